@@ -0,0 +1,244 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/RichardKnop/chess-engine/auth"
+	"github.com/RichardKnop/chess-engine/server"
+	"github.com/satori/go.uuid"
+)
+
+// maxRequestSize limits JSON bodies of session and account requests
+const maxRequestSize = 4096
+
+// session is returned by every endpoint issuing a token, profile is only set
+// for players with an account
+type session struct {
+	PlayerID string          `json:"player_id"`
+	Token    string          `json:"token"`
+	Profile  *server.Profile `json:"profile,omitempty"`
+}
+
+// credentials are sent to register and log in
+type credentials struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	// Session of an anonymous player registering, their games are kept
+	Token string `json:"token"`
+}
+
+// sessionHandler issues a session token. A valid token sent in the request
+// body is refreshed keeping the player ID, otherwise a new anonymous player
+// is created.
+func sessionHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req session
+	decodeJSON(r, &req)
+
+	claims, account, err := authenticate(req.Token)
+	if err != nil {
+		writeSession(w, uuid.NewV4().String(), nil)
+		return
+	}
+	writeSession(w, claims.PlayerID, account)
+}
+
+// registerHandler creates an account and logs the player in
+func registerHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req credentials
+	if err := decodeJSON(r, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// An anonymous player keeps their player ID so games they played so far
+	// belong to the account, a player ID can only be registered once
+	playerID := uuid.NewV4().String()
+	if claims, err := signer.Verify(req.Token, time.Now()); err == nil && claims.Version == 0 {
+		if _, err := store.LoadAccount(claims.PlayerID); err == nil {
+			http.Error(w, server.ErrAccountExists.Error(), http.StatusConflict)
+			return
+		}
+		playerID = claims.PlayerID
+	}
+
+	account, err := server.NewAccount(playerID, req.Username, req.Password)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := store.CreateAccount(account); err == server.ErrUsernameTaken || err == server.ErrAccountExists {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeSession(w, account.ID, account)
+}
+
+// loginHandler logs a player in with a username and a password
+func loginHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req credentials
+	if err := decodeJSON(r, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	account, err := store.FindAccount(strings.ToLower(strings.TrimSpace(req.Username)))
+	if _, ok := err.(*server.AccountNotFoundError); ok {
+		http.Error(w, server.ErrInvalidCredentials.Error(), http.StatusUnauthorized)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !account.Authenticate(req.Password) {
+		http.Error(w, server.ErrInvalidCredentials.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	writeSession(w, account.ID, account)
+}
+
+// logoutHandler ends all sessions of an account and starts a new anonymous
+// session
+func logoutHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	_, account, err := authenticate(bearerToken(r))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	if account != nil {
+		account.TokenVersion++
+		if err := store.SaveAccount(account); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	writeSession(w, uuid.NewV4().String(), nil)
+}
+
+// profileHandler returns the profile of the logged in player on GET and
+// updates it on PUT
+func profileHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	_, account, err := authenticate(bearerToken(r))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	if account == nil {
+		http.Error(w, "Anonymous players have no profile", http.StatusNotFound)
+		return
+	}
+
+	if r.Method == http.MethodPut {
+		profile := new(server.Profile)
+		if err := decodeJSON(r, profile); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := account.UpdateProfile(profile); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := store.SaveAccount(account); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	writeJSON(w, account.Profile())
+}
+
+// authenticate verifies a token, the account is nil for anonymous players.
+// Tokens of accounts are only valid until the player logs out, anonymous
+// tokens stop being valid once the player registers.
+func authenticate(token string) (*auth.Claims, *server.Account, error) {
+	claims, err := signer.Verify(token, time.Now())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	account, err := store.LoadAccount(claims.PlayerID)
+	if _, ok := err.(*server.AccountNotFoundError); ok && claims.Version == 0 {
+		return claims, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if claims.Version == 0 {
+		return nil, nil, auth.ErrInvalidToken
+	}
+	if account.TokenVersion != claims.Version {
+		return nil, nil, auth.ErrInvalidToken
+	}
+
+	return claims, account, nil
+}
+
+// writeSession issues a token for a player
+func writeSession(w http.ResponseWriter, playerID string, account *server.Account) {
+	resp := &session{PlayerID: playerID}
+
+	var version int
+	if account != nil {
+		version = account.TokenVersion
+		resp.Profile = account.Profile()
+	}
+
+	token, err := signer.Issue(playerID, version, time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	resp.Token = token
+
+	writeJSON(w, resp)
+}
+
+// bearerToken returns the token from the Authorization header
+func bearerToken(r *http.Request) string {
+	return strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+}
+
+func decodeJSON(r *http.Request, v interface{}) error {
+	return json.NewDecoder(io.LimitReader(r.Body, maxRequestSize)).Decode(v)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
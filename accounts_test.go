@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/RichardKnop/chess-engine/server"
+)
+
+func TestRegisterAndLogin(t *testing.T) {
+	setUp(t)
+
+	// An anonymous player keeps their player ID when registering
+	anonymous := decodeSession(t, serve(sessionHandler, http.MethodPost, "/session", "", "{}"))
+	registered := decodeSession(t, serve(registerHandler, http.MethodPost, "/register", "",
+		`{"username":"Alice","password":"correct horse","token":"`+anonymous.Token+`"}`))
+	if registered.PlayerID != anonymous.PlayerID || registered.Profile == nil || registered.Profile.Username != "alice" {
+		t.Fatalf("registered session %+v", registered)
+	}
+
+	testCases := []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{"invalid JSON", `{"username":`, http.StatusBadRequest},
+		{"invalid username", `{"username":"a","password":"correct horse"}`, http.StatusBadRequest},
+		{"short password", `{"username":"bob","password":"short"}`, http.StatusBadRequest},
+		{"username taken", `{"username":"alice","password":"correct horse"}`, http.StatusConflict},
+		{"player registered again", `{"username":"bob","password":"correct horse","token":"` + anonymous.Token + `"}`, http.StatusConflict},
+	}
+	for _, tc := range testCases {
+		if w := serve(registerHandler, http.MethodPost, "/register", "", tc.body); w.Code != tc.status {
+			t.Errorf("%s: status %d, want %d", tc.name, w.Code, tc.status)
+		}
+	}
+
+	// The anonymous token stops being valid once the player registers
+	if _, _, err := authenticate(anonymous.Token); err == nil {
+		t.Error("anonymous token still valid after registering")
+	}
+
+	loggedIn := decodeSession(t, serve(loginHandler, http.MethodPost, "/login", "", `{"username":" Alice ","password":"correct horse"}`))
+	if loggedIn.PlayerID != registered.PlayerID {
+		t.Errorf("logged in as %s, want %s", loggedIn.PlayerID, registered.PlayerID)
+	}
+	for _, body := range []string{
+		`{"username":"alice","password":"wrong password"}`,
+		`{"username":"nobody","password":"correct horse"}`,
+	} {
+		w := serve(loginHandler, http.MethodPost, "/login", "", body)
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("login with %s: status %d", body, w.Code)
+		}
+	}
+
+	// Logging out ends every session of the account
+	decodeSession(t, serve(logoutHandler, http.MethodPost, "/logout", loggedIn.Token, ""))
+	for _, token := range []string{registered.Token, loggedIn.Token} {
+		if _, _, err := authenticate(token); err == nil {
+			t.Error("token still valid after logging out")
+		}
+	}
+	if w := serve(logoutHandler, http.MethodPost, "/logout", loggedIn.Token, ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("logging out twice: status %d", w.Code)
+	}
+}
+
+func TestProfileHandler(t *testing.T) {
+	setUp(t)
+
+	anonymous := decodeSession(t, serve(sessionHandler, http.MethodPost, "/session", "", "{}"))
+	alice := decodeSession(t, serve(registerHandler, http.MethodPost, "/register", "", `{"username":"alice","password":"correct horse"}`))
+
+	testCases := []struct {
+		name   string
+		method string
+		token  string
+		body   string
+		status int
+	}{
+		{"no token", http.MethodGet, "", "", http.StatusUnauthorized},
+		{"invalid token", http.MethodGet, "garbage", "", http.StatusUnauthorized},
+		{"anonymous player", http.MethodGet, anonymous.Token, "", http.StatusNotFound},
+		{"method", http.MethodPost, alice.Token, "", http.StatusMethodNotAllowed},
+		{"empty display name", http.MethodPut, alice.Token, `{"display_name":" ","piece_set":"alpha"}`, http.StatusBadRequest},
+		{"country", http.MethodPut, alice.Token, `{"display_name":"Alice","country":"xyz","piece_set":"alpha"}`, http.StatusBadRequest},
+		{"piece set", http.MethodPut, alice.Token, `{"display_name":"Alice","piece_set":"unknown"}`, http.StatusBadRequest},
+		{"update", http.MethodPut, alice.Token, `{"display_name":"Alice Liddell","country":"GB","piece_set":"alpha"}`, http.StatusOK},
+	}
+	for _, tc := range testCases {
+		if w := serve(profileHandler, tc.method, "/profile", tc.token, tc.body); w.Code != tc.status {
+			t.Errorf("%s: status %d, want %d", tc.name, w.Code, tc.status)
+		}
+	}
+
+	w := serve(profileHandler, http.MethodGet, "/profile", alice.Token, "")
+	profile := new(server.Profile)
+	if err := json.NewDecoder(w.Body).Decode(profile); err != nil {
+		t.Fatal(err)
+	}
+	if profile.ID != alice.PlayerID || profile.DisplayName != "Alice Liddell" || profile.Country != "GB" || profile.PieceSet != "alpha" {
+		t.Errorf("profile after update %+v", profile)
+	}
+}
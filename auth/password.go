@@ -0,0 +1,40 @@
+package auth
+
+import (
+	"errors"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// passwordCost is the bcrypt work factor, hashes keep their cost so it can
+// be raised over time
+var passwordCost = 12
+
+// MaxPasswordLength is the number of bytes bcrypt uses, longer passwords are
+// rejected rather than silently truncated
+const MaxPasswordLength = 72
+
+// ErrInvalidPasswordHash is returned for hashes in an unknown format
+var ErrInvalidPasswordHash = errors.New("Invalid password hash")
+
+// HashPassword returns a salted bcrypt hash of a password
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), passwordCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// CheckPassword returns true if the password matches the hash
+func CheckPassword(hash, password string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	switch err {
+	case nil:
+		return true, nil
+	case bcrypt.ErrMismatchedHashAndPassword:
+		return false, nil
+	default:
+		return false, ErrInvalidPasswordHash
+	}
+}
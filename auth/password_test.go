@@ -0,0 +1,38 @@
+package auth
+
+import (
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestPassword(t *testing.T) {
+	defer func(n int) { passwordCost = n }(passwordCost)
+	passwordCost = bcrypt.MinCost
+
+	hash, err := HashPassword("correct horse battery staple")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	other, err := HashPassword("correct horse battery staple")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hash == other {
+		t.Error("hashes of the same password are not salted")
+	}
+
+	if ok, err := CheckPassword(hash, "correct horse battery staple"); !ok || err != nil {
+		t.Errorf("correct password returned %v, %v", ok, err)
+	}
+	if ok, err := CheckPassword(hash, "Correct horse battery staple"); ok || err != nil {
+		t.Errorf("wrong password returned %v, %v", ok, err)
+	}
+
+	for _, invalid := range []string{"", "md5$abc", "$2a$04$short", "pbkdf2-sha256$1000$c2FsdA$a2V5"} {
+		if _, err := CheckPassword(invalid, "password"); err != ErrInvalidPasswordHash {
+			t.Errorf("hash %q returned %v", invalid, err)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+// Package auth issues and verifies signed session tokens identifying players.
+// Tokens are JSON Web Tokens signed with HMAC-SHA256.
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidToken is returned for malformed tokens and tokens with a bad
+	// signature
+	ErrInvalidToken = errors.New("Invalid session token")
+	// ErrTokenExpired is returned for tokens past their expiry time
+	ErrTokenExpired = errors.New("Session token has expired")
+)
+
+// header of every token, only HS256 is ever accepted
+var header = encode([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+// Claims are the contents of a token
+type Claims struct {
+	PlayerID  string `json:"sub"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+	// Version of account sessions, tokens of older versions were logged out
+	Version int `json:"ver,omitempty"`
+}
+
+// Signer issues and verifies tokens with a secret key
+type Signer struct {
+	key []byte
+	ttl time.Duration
+}
+
+// NewSigner creates a new instance of Signer, issued tokens are valid for
+// the ttl duration
+func NewSigner(key []byte, ttl time.Duration) *Signer {
+	return &Signer{key: key, ttl: ttl}
+}
+
+// GenerateKey returns a random key, tokens signed with it cannot be verified
+// once the key is lost
+func GenerateKey() ([]byte, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// Issue returns a token identifying a player, version is zero for anonymous
+// players
+func (s *Signer) Issue(playerID string, version int, now time.Time) (string, error) {
+	payload, err := json.Marshal(&Claims{
+		PlayerID:  playerID,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(s.ttl).Unix(),
+		Version:   version,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	signed := header + "." + encode(payload)
+	return signed + "." + encode(s.sign(signed)), nil
+}
+
+// Verify checks signature and expiry of a token and returns its claims
+func (s *Signer) Verify(token string, now time.Time) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[0] != header {
+		return nil, ErrInvalidToken
+	}
+
+	signature, err := decode(parts[2])
+	if err != nil || !hmac.Equal(signature, s.sign(parts[0]+"."+parts[1])) {
+		return nil, ErrInvalidToken
+	}
+
+	payload, err := decode(parts[1])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	claims := new(Claims)
+	if err := json.Unmarshal(payload, claims); err != nil || claims.PlayerID == "" {
+		return nil, ErrInvalidToken
+	}
+
+	if now.Unix() >= claims.ExpiresAt {
+		return nil, ErrTokenExpired
+	}
+
+	return claims, nil
+}
+
+func (s *Signer) sign(data string) []byte {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func encode(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decode(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(s)
+}
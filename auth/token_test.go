@@ -0,0 +1,49 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestToken(t *testing.T) {
+	now := time.Now()
+	signer := NewSigner([]byte("secret"), time.Hour)
+
+	token, err := signer.Issue("alice", 1, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err := signer.Verify(token, now.Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.PlayerID != "alice" || claims.Version != 1 {
+		t.Errorf("token identifies %s version %d, want alice version 1", claims.PlayerID, claims.Version)
+	}
+
+	if _, err := signer.Verify(token, now.Add(2*time.Hour)); err != ErrTokenExpired {
+		t.Errorf("expired token returned %v", err)
+	}
+
+	// Payload of a token signed for bob with another key
+	forged, _ := NewSigner([]byte("guess"), time.Hour).Issue("bob", 0, now)
+	parts := strings.Split(token, ".")
+	tampered := parts[0] + "." + strings.Split(forged, ".")[1] + "." + parts[2]
+
+	invalid := []string{
+		"",
+		"a.b",
+		forged,
+		tampered,
+		token + "x",
+		// The none algorithm is never accepted
+		"eyJhbGciOiJub25lIiwidHlwIjoiSldUIn0." + parts[1] + ".",
+	}
+	for _, s := range invalid {
+		if _, err := signer.Verify(s, now); err != ErrInvalidToken {
+			t.Errorf("token %q returned %v", s, err)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package chess
+
+// Attack tables are computed once when the package is loaded. Sliding pieces
+// use magic bitboards: relevant blockers of a square are multiplied by a magic
+// number which perfectly hashes every blocker subset into a table index.
+// See https://www.chessprogramming.org/Magic_Bitboards
+
+var (
+	knightAttacks [64]Bitboard
+	kingAttacks   [64]Bitboard
+	pawnAttacks   [2][64]Bitboard
+
+	rookMagics   [64]magic
+	bishopMagics [64]magic
+)
+
+// magic holds a perfect hash of blocker sets to attacks for one square
+type magic struct {
+	mask    Bitboard
+	number  uint64
+	shift   uint
+	attacks []Bitboard
+}
+
+// index hashes blockers into the attack table
+func (m *magic) index(occupied Bitboard) uint64 {
+	return (uint64(occupied&m.mask) * m.number) >> m.shift
+}
+
+func init() {
+	for sq := Square(0); sq < 64; sq++ {
+		knightAttacks[sq] = stepAttacks(sq, knightOffsets[:])
+		kingAttacks[sq] = stepAttacks(sq, kingOffsets[:])
+		pawnAttacks[White][sq] = stepAttacks(sq, [][2]int{{-1, 1}, {1, 1}})
+		pawnAttacks[Black][sq] = stepAttacks(sq, [][2]int{{-1, -1}, {1, -1}})
+	}
+
+	for sq := Square(0); sq < 64; sq++ {
+		initMagic(&rookMagics[sq], sq, rookDirs[:], newPRNG(magicSeeds[sq.Rank()]))
+		initMagic(&bishopMagics[sq], sq, bishopDirs[:], newPRNG(magicSeeds[sq.Rank()]))
+	}
+}
+
+// magicSeeds are known to find magic numbers quickly for squares of each rank
+var magicSeeds = [8]uint64{728, 10316, 55013, 32803, 12281, 15100, 16645, 255}
+
+// rookAttacks returns squares attacked by a rook given board occupancy
+func rookAttacks(sq Square, occupied Bitboard) Bitboard {
+	m := &rookMagics[sq]
+	return m.attacks[m.index(occupied)]
+}
+
+// bishopAttacks returns squares attacked by a bishop given board occupancy
+func bishopAttacks(sq Square, occupied Bitboard) Bitboard {
+	m := &bishopMagics[sq]
+	return m.attacks[m.index(occupied)]
+}
+
+// queenAttacks returns squares attacked by a queen given board occupancy
+func queenAttacks(sq Square, occupied Bitboard) Bitboard {
+	return rookAttacks(sq, occupied) | bishopAttacks(sq, occupied)
+}
+
+// stepAttacks builds attacks of a non-sliding piece from offsets
+func stepAttacks(sq Square, offsets [][2]int) Bitboard {
+	var b Bitboard
+	for _, o := range offsets {
+		if to, ok := sq.offset(o[0], o[1]); ok {
+			b |= SquareBB(to)
+		}
+	}
+	return b
+}
+
+// slidingAttacks walks rays from a square until they hit a blocker. It is
+// slow and only used to fill the magic tables.
+func slidingAttacks(sq Square, occupied Bitboard, dirs [][2]int) Bitboard {
+	var b Bitboard
+	for _, d := range dirs {
+		to := sq
+		for {
+			var ok bool
+			if to, ok = to.offset(d[0], d[1]); !ok {
+				break
+			}
+			b |= SquareBB(to)
+			if occupied.Has(to) {
+				break
+			}
+		}
+	}
+	return b
+}
+
+// relevantMask returns squares whose occupancy affects slider attacks, edge
+// squares at the end of each ray never matter
+func relevantMask(sq Square, dirs [][2]int) Bitboard {
+	var b Bitboard
+	for _, d := range dirs {
+		to := sq
+		for {
+			next, ok := to.offset(d[0], d[1])
+			if !ok {
+				break
+			}
+			if _, ok := next.offset(d[0], d[1]); !ok {
+				break
+			}
+			b |= SquareBB(next)
+			to = next
+		}
+	}
+	return b
+}
+
+// initMagic searches for a magic number without destructive collisions
+func initMagic(m *magic, sq Square, dirs [][2]int, rng *prng) {
+	m.mask = relevantMask(sq, dirs)
+	bitCount := m.mask.Count()
+	m.shift = uint(64 - bitCount)
+
+	size := 1 << uint(bitCount)
+	occupancies := make([]Bitboard, 0, size)
+	references := make([]Bitboard, 0, size)
+
+	// Enumerate all subsets of the mask (Carry-Rippler trick)
+	var subset Bitboard
+	for {
+		occupancies = append(occupancies, subset)
+		references = append(references, slidingAttacks(sq, subset, dirs))
+		subset = (subset - m.mask) & m.mask
+		if subset == 0 {
+			break
+		}
+	}
+
+	m.attacks = make([]Bitboard, size)
+	used := make([]bool, size)
+
+search:
+	for {
+		m.number = rng.sparse()
+		if Bitboard((uint64(m.mask)*m.number)>>56).Count() < 6 {
+			continue
+		}
+
+		for i := range used {
+			used[i] = false
+		}
+		for i, occupied := range occupancies {
+			idx := m.index(occupied)
+			if used[idx] && m.attacks[idx] != references[i] {
+				continue search
+			}
+			used[idx] = true
+			m.attacks[idx] = references[i]
+		}
+		return
+	}
+}
+
+// prng is a xorshift64* generator used for deterministic table initialization
+type prng struct {
+	state uint64
+}
+
+func newPRNG(seed uint64) *prng {
+	return &prng{state: seed}
+}
+
+func (r *prng) next() uint64 {
+	r.state ^= r.state >> 12
+	r.state ^= r.state << 25
+	r.state ^= r.state >> 27
+	return r.state * 2685821657736338717
+}
+
+// sparse returns a number with few bits set, which makes good magic candidates
+func (r *prng) sparse() uint64 {
+	return r.next() & r.next() & r.next()
+}
+
+// KnightAttacks returns squares attacked by a knight
+func KnightAttacks(sq Square) Bitboard {
+	return knightAttacks[sq]
+}
+
+// KingAttacks returns squares attacked by a king
+func KingAttacks(sq Square) Bitboard {
+	return kingAttacks[sq]
+}
+
+// PawnAttacks returns squares attacked by a pawn of a color
+func PawnAttacks(c Color, sq Square) Bitboard {
+	return pawnAttacks[c][sq]
+}
+
+// SliderAttacks returns squares attacked by a bishop, rook or queen given
+// board occupancy
+func SliderAttacks(t PieceType, sq Square, occupied Bitboard) Bitboard {
+	switch t {
+	case Bishop:
+		return bishopAttacks(sq, occupied)
+	case Rook:
+		return rookAttacks(sq, occupied)
+	case Queen:
+		return queenAttacks(sq, occupied)
+	}
+	return 0
+}
@@ -0,0 +1,52 @@
+package chess
+
+import (
+	"math/bits"
+)
+
+// Bitboard is a set of squares, bit 0 is a1 and bit 63 is h8
+type Bitboard uint64
+
+const (
+	rank1 Bitboard = 0xff
+	rank3 Bitboard = rank1 << 16
+	rank6 Bitboard = rank1 << 40
+	rank8 Bitboard = rank1 << 56
+)
+
+// SquareBB returns a bitboard with a single square set
+func SquareBB(sq Square) Bitboard {
+	return 1 << uint(sq)
+}
+
+// Has returns true if the square is in the set
+func (b Bitboard) Has(sq Square) bool {
+	return b&SquareBB(sq) != 0
+}
+
+// Count returns number of squares in the set
+func (b Bitboard) Count() int {
+	return bits.OnesCount64(uint64(b))
+}
+
+// First returns the lowest square in the set, the set must not be empty
+func (b Bitboard) First() Square {
+	return Square(bits.TrailingZeros64(uint64(b)))
+}
+
+// PopFirst removes the lowest square from the set and returns it
+func (b *Bitboard) PopFirst() Square {
+	sq := b.First()
+	*b &= *b - 1
+	return sq
+}
+
+// north moves all squares one rank up
+func (b Bitboard) north() Bitboard {
+	return b << 8
+}
+
+// south moves all squares one rank down
+func (b Bitboard) south() Bitboard {
+	return b >> 8
+}
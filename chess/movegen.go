@@ -0,0 +1,231 @@
+package chess
+
+var (
+	knightOffsets = [8][2]int{{1, 2}, {2, 1}, {2, -1}, {1, -2}, {-1, -2}, {-2, -1}, {-2, 1}, {-1, 2}}
+	kingOffsets   = [8][2]int{{1, 0}, {1, 1}, {0, 1}, {-1, 1}, {-1, 0}, {-1, -1}, {0, -1}, {1, -1}}
+	bishopDirs    = [4][2]int{{1, 1}, {1, -1}, {-1, 1}, {-1, -1}}
+	rookDirs      = [4][2]int{{1, 0}, {-1, 0}, {0, 1}, {0, -1}}
+
+	promotionTypes = [4]PieceType{Queen, Rook, Bishop, Knight}
+)
+
+// MaxMoves is an upper bound of pseudo-legal moves in any reachable position
+const MaxMoves = 256
+
+// MoveList is a fixed size list of moves which avoids allocations during search
+type MoveList struct {
+	moves [MaxMoves]Move
+	count int
+}
+
+// Add appends a move to the list
+func (l *MoveList) Add(m Move) {
+	l.moves[l.count] = m
+	l.count++
+}
+
+// Len returns number of moves in the list
+func (l *MoveList) Len() int {
+	return l.count
+}
+
+// At returns a move at an index
+func (l *MoveList) At(i int) Move {
+	return l.moves[i]
+}
+
+// Swap exchanges two moves, which is used for move ordering
+func (l *MoveList) Swap(i, j int) {
+	l.moves[i], l.moves[j] = l.moves[j], l.moves[i]
+}
+
+// Clear empties the list
+func (l *MoveList) Clear() {
+	l.count = 0
+}
+
+// LegalMoves returns all legal moves for the side to move
+func (p *Position) LegalMoves() []Move {
+	var list MoveList
+	p.GenerateMoves(&list)
+
+	q := *p
+	legal := make([]Move, 0, list.count)
+	for i := 0; i < list.count; i++ {
+		m := list.moves[i]
+		u := q.DoMove(m)
+		if !q.OpponentInCheck() {
+			legal = append(legal, m)
+		}
+		q.UndoMove(m, u)
+	}
+	return legal
+}
+
+// IsLegal returns true if a move is legal in the position
+func (p *Position) IsLegal(m Move) bool {
+	for _, legal := range p.LegalMoves() {
+		if legal == m {
+			return true
+		}
+	}
+	return false
+}
+
+// InCheck returns true if the side to move is in check
+func (p *Position) InCheck() bool {
+	return p.IsAttacked(p.KingSquare(p.turn), p.turn.Other())
+}
+
+// OpponentInCheck returns true if the side which is not on the move is in
+// check, after DoMove it means the move was not legal
+func (p *Position) OpponentInCheck() bool {
+	return p.IsAttacked(p.KingSquare(p.turn.Other()), p.turn)
+}
+
+// IsAttacked returns true if a square is attacked by any piece of a color
+func (p *Position) IsAttacked(sq Square, by Color) bool {
+	return p.AttackersTo(sq, p.occupied[White]|p.occupied[Black])&p.occupied[by] != 0
+}
+
+// AttackersTo returns pieces of both colors attacking a square given occupancy
+func (p *Position) AttackersTo(sq Square, occupied Bitboard) Bitboard {
+	w, b := &p.pieces[White], &p.pieces[Black]
+	return pawnAttacks[Black][sq]&w[Pawn] |
+		pawnAttacks[White][sq]&b[Pawn] |
+		knightAttacks[sq]&(w[Knight]|b[Knight]) |
+		kingAttacks[sq]&(w[King]|b[King]) |
+		bishopAttacks(sq, occupied)&(w[Bishop]|b[Bishop]|w[Queen]|b[Queen]) |
+		rookAttacks(sq, occupied)&(w[Rook]|b[Rook]|w[Queen]|b[Queen])
+}
+
+// GenerateMoves adds all pseudo-legal moves to a list, moves which leave the
+// king in check have to be filtered out by the caller
+func (p *Position) GenerateMoves(list *MoveList) {
+	p.generate(list, ^p.occupied[p.turn], true)
+}
+
+// GenerateCaptures adds pseudo-legal captures and queen promotions to a list,
+// which is what quiescence search needs
+func (p *Position) GenerateCaptures(list *MoveList) {
+	p.generate(list, p.occupied[p.turn.Other()], false)
+}
+
+func (p *Position) generate(list *MoveList, targets Bitboard, quiet bool) {
+	us, them := p.turn, p.turn.Other()
+	occupied := p.occupied[White] | p.occupied[Black]
+
+	p.generatePawnMoves(list, quiet)
+
+	for t := Knight; t <= King; t++ {
+		for pieces := p.pieces[us][t]; pieces != 0; {
+			from := pieces.PopFirst()
+			var attacks Bitboard
+			switch t {
+			case Knight:
+				attacks = knightAttacks[from]
+			case Bishop:
+				attacks = bishopAttacks(from, occupied)
+			case Rook:
+				attacks = rookAttacks(from, occupied)
+			case Queen:
+				attacks = queenAttacks(from, occupied)
+			case King:
+				attacks = kingAttacks[from]
+			}
+			for attacks &= targets; attacks != 0; {
+				list.Add(Move{From: from, To: attacks.PopFirst()})
+			}
+		}
+	}
+
+	if quiet && p.castling != NoCastling {
+		p.generateCastling(list, them, occupied)
+	}
+}
+
+func (p *Position) generatePawnMoves(list *MoveList, quiet bool) {
+	us, them := p.turn, p.turn.Other()
+	pawns := p.pieces[us][Pawn]
+	empty := ^(p.occupied[White] | p.occupied[Black])
+	enemies := p.occupied[them]
+	if p.epSquare != NoSquare {
+		enemies |= SquareBB(p.epSquare)
+	}
+
+	var single, double Bitboard
+	var forward Square
+	var lastRank Bitboard
+	if us == White {
+		single = pawns.north() & empty
+		double = (single & rank3).north() & empty
+		forward, lastRank = 8, rank8
+	} else {
+		single = pawns.south() & empty
+		double = (single & rank6).south() & empty
+		forward, lastRank = -8, rank1
+	}
+
+	// Promotions count as captures for quiescence search, but only to a queen
+	for b := single & lastRank; b != 0; {
+		to := b.PopFirst()
+		addPromotions(list, to-forward, to, quiet)
+	}
+	if quiet {
+		for b := single &^ lastRank; b != 0; {
+			to := b.PopFirst()
+			list.Add(Move{From: to - forward, To: to})
+		}
+		for b := double; b != 0; {
+			to := b.PopFirst()
+			list.Add(Move{From: to - 2*forward, To: to})
+		}
+	}
+
+	for b := pawns; b != 0; {
+		from := b.PopFirst()
+		for attacks := pawnAttacks[us][from] & enemies; attacks != 0; {
+			to := attacks.PopFirst()
+			if lastRank.Has(to) {
+				addPromotions(list, from, to, quiet)
+			} else {
+				list.Add(Move{From: from, To: to})
+			}
+		}
+	}
+}
+
+// addPromotions adds a promotion to every piece type or only to a queen
+func addPromotions(list *MoveList, from, to Square, underpromotions bool) {
+	if !underpromotions {
+		list.Add(Move{From: from, To: to, Promotion: Queen})
+		return
+	}
+	for _, t := range promotionTypes {
+		list.Add(Move{From: from, To: to, Promotion: t})
+	}
+}
+
+func (p *Position) generateCastling(list *MoveList, them Color, occupied Bitboard) {
+	from := kingHome[p.turn]
+	if !p.pieces[p.turn][King].Has(from) || p.IsAttacked(from, them) {
+		return
+	}
+
+	kingSide, queenSide := WhiteKingSide, WhiteQueenSide
+	if p.turn == Black {
+		kingSide, queenSide = BlackKingSide, BlackQueenSide
+	}
+	rank := from.Rank()
+
+	if p.castling&kingSide != 0 &&
+		occupied&(SquareBB(NewSquare(5, rank))|SquareBB(NewSquare(6, rank))) == 0 &&
+		!p.IsAttacked(NewSquare(5, rank), them) {
+		list.Add(Move{From: from, To: NewSquare(6, rank)})
+	}
+	if p.castling&queenSide != 0 &&
+		occupied&(SquareBB(NewSquare(1, rank))|SquareBB(NewSquare(2, rank))|SquareBB(NewSquare(3, rank))) == 0 &&
+		!p.IsAttacked(NewSquare(3, rank), them) {
+		list.Add(Move{From: from, To: NewSquare(2, rank)})
+	}
+}
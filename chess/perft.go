@@ -0,0 +1,51 @@
+package chess
+
+// Perft counts leaf nodes of the legal move tree to a given depth. It is used
+// to verify move generation against known reference numbers.
+// See https://www.chessprogramming.org/Perft
+func Perft(p *Position, depth int) uint64 {
+	q := *p
+	return perft(&q, depth)
+}
+
+// Divide runs perft for every legal move separately, which helps to find the
+// exact move where generation goes wrong when compared with another engine
+func Divide(p *Position, depth int) map[Move]uint64 {
+	result := make(map[Move]uint64)
+	if depth < 1 {
+		return result
+	}
+
+	q := *p
+	for _, m := range q.LegalMoves() {
+		u := q.DoMove(m)
+		result[m] = perft(&q, depth-1)
+		q.UndoMove(m, u)
+	}
+	return result
+}
+
+// perft plays moves in place, so it does not allocate
+func perft(p *Position, depth int) uint64 {
+	if depth == 0 {
+		return 1
+	}
+
+	var list MoveList
+	p.GenerateMoves(&list)
+
+	var nodes uint64
+	for i := 0; i < list.Len(); i++ {
+		m := list.At(i)
+		u := p.DoMove(m)
+		if !p.OpponentInCheck() {
+			if depth == 1 {
+				nodes++
+			} else {
+				nodes += perft(p, depth-1)
+			}
+		}
+		p.UndoMove(m, u)
+	}
+	return nodes
+}
@@ -0,0 +1,105 @@
+package chess_test
+
+import (
+	"testing"
+
+	"github.com/RichardKnop/chess-engine/chess"
+	"github.com/RichardKnop/chess-engine/fen"
+)
+
+// Reference numbers from https://www.chessprogramming.org/Perft_Results
+var perftTestCases = []struct {
+	name  string
+	fen   string
+	nodes []uint64
+}{
+	{
+		name:  "initial",
+		fen:   fen.Initial,
+		nodes: []uint64{20, 400, 8902, 197281, 4865609},
+	},
+	{
+		name:  "kiwipete",
+		fen:   "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+		nodes: []uint64{48, 2039, 97862, 4085603},
+	},
+	{
+		name:  "en passant and pins",
+		fen:   "8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1",
+		nodes: []uint64{14, 191, 2812, 43238, 674624},
+	},
+	{
+		name:  "promotions and castling",
+		fen:   "r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1",
+		nodes: []uint64{6, 264, 9467, 422333},
+	},
+	{
+		name:  "underpromotion",
+		fen:   "rnbq1k1r/pp1Pbppp/2p5/8/2B5/8/PPP1NnPP/RNBQK2R w KQ - 1 8",
+		nodes: []uint64{44, 1486, 62379, 2103487},
+	},
+	{
+		name:  "middlegame",
+		fen:   "r4rk1/1pp1qppp/p1np1n2/2b1p1B1/2B1P1b1/P1NP1N2/1PP1QPPP/R4RK1 w - - 0 10",
+		nodes: []uint64{46, 2079, 89890, 3894594},
+	},
+}
+
+// shortPerftNodes limits node counts checked when running with -short
+const shortPerftNodes = 100000
+
+func TestPerft(t *testing.T) {
+	for _, tc := range perftTestCases {
+		p, err := fen.Parse(tc.fen)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+
+		for i, expected := range tc.nodes {
+			if testing.Short() && expected > shortPerftNodes {
+				break
+			}
+			depth := i + 1
+			if nodes := chess.Perft(p, depth); nodes != expected {
+				t.Errorf("%s: perft(%d) = %d, expected %d", tc.name, depth, nodes, expected)
+			}
+		}
+	}
+}
+
+func TestDivide(t *testing.T) {
+	for _, tc := range perftTestCases {
+		p, err := fen.Parse(tc.fen)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+
+		divide := chess.Divide(p, 2)
+		if len(divide) != int(tc.nodes[0]) {
+			t.Errorf("%s: divide returned %d moves, expected %d", tc.name, len(divide), tc.nodes[0])
+		}
+
+		var total uint64
+		for _, nodes := range divide {
+			total += nodes
+		}
+		if total != tc.nodes[1] {
+			t.Errorf("%s: divide(2) sums to %d, expected %d", tc.name, total, tc.nodes[1])
+		}
+	}
+}
+
+func BenchmarkPerft(b *testing.B) {
+	for _, tc := range perftTestCases {
+		p, err := fen.Parse(tc.fen)
+		if err != nil {
+			b.Fatalf("%s: %v", tc.name, err)
+		}
+
+		b.Run(tc.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				chess.Perft(p, 3)
+			}
+		})
+	}
+}
@@ -0,0 +1,326 @@
+package chess
+
+import (
+	"bytes"
+	"errors"
+)
+
+var (
+	// ErrIllegalMove is returned when a move is not legal in a position
+	ErrIllegalMove = errors.New("Illegal move")
+)
+
+// Position represents a complete state of the board. Pieces are kept both in
+// bitboards for move generation and in a mailbox for fast square lookups.
+type Position struct {
+	pieces   [2][7]Bitboard
+	occupied [2]Bitboard
+	board    [64]Piece
+
+	turn           Color
+	castling       CastlingRights
+	epSquare       Square
+	halfmoveClock  int
+	fullmoveNumber int
+	hash           uint64
+}
+
+// Undo holds state which cannot be recovered from a move when taking it back
+type Undo struct {
+	captured      Piece
+	castling      CastlingRights
+	epSquare      Square
+	halfmoveClock int
+	hash          uint64
+}
+
+// NewPosition returns the standard starting position
+func NewPosition() *Position {
+	s := &Setup{
+		Turn:           White,
+		Castling:       AllCastling,
+		EnPassant:      NoSquare,
+		FullmoveNumber: 1,
+	}
+	backRank := [8]PieceType{Rook, Knight, Bishop, Queen, King, Bishop, Knight, Rook}
+	for file, t := range backRank {
+		s.Board[NewSquare(file, 0)] = NewPiece(White, t)
+		s.Board[NewSquare(file, 1)] = NewPiece(White, Pawn)
+		s.Board[NewSquare(file, 6)] = NewPiece(Black, Pawn)
+		s.Board[NewSquare(file, 7)] = NewPiece(Black, t)
+	}
+	p, err := NewPositionFromSetup(s)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// Placement returns the piece placement field of a FEN string
+func (p *Position) Placement() string {
+	var buf bytes.Buffer
+	for rank := 7; rank >= 0; rank-- {
+		empty := 0
+		for file := 0; file < 8; file++ {
+			piece := p.board[NewSquare(file, rank)]
+			if piece == NoPiece {
+				empty++
+				continue
+			}
+			if empty > 0 {
+				buf.WriteByte(byte('0' + empty))
+				empty = 0
+			}
+			buf.WriteByte(piece.Char())
+		}
+		if empty > 0 {
+			buf.WriteByte(byte('0' + empty))
+		}
+		if rank > 0 {
+			buf.WriteByte('/')
+		}
+	}
+	return buf.String()
+}
+
+// Turn returns the side to move
+func (p *Position) Turn() Color {
+	return p.turn
+}
+
+// PieceAt returns a piece standing on a square
+func (p *Position) PieceAt(sq Square) Piece {
+	return p.board[sq]
+}
+
+// Pieces returns a set of squares occupied by pieces of a color and type
+func (p *Position) Pieces(c Color, t PieceType) Bitboard {
+	return p.pieces[c][t]
+}
+
+// Occupied returns a set of squares occupied by pieces of a color
+func (p *Position) Occupied(c Color) Bitboard {
+	return p.occupied[c]
+}
+
+// Castling returns castling rights still available
+func (p *Position) Castling() CastlingRights {
+	return p.castling
+}
+
+// EnPassant returns en passant target square or NoSquare
+func (p *Position) EnPassant() Square {
+	return p.epSquare
+}
+
+// HalfmoveClock returns number of halfmoves since the last capture or pawn move
+func (p *Position) HalfmoveClock() int {
+	return p.halfmoveClock
+}
+
+// FullmoveNumber returns number of the current full move, starting at 1
+func (p *Position) FullmoveNumber() int {
+	return p.fullmoveNumber
+}
+
+// Hash returns Zobrist key of the position
+func (p *Position) Hash() uint64 {
+	return p.hash
+}
+
+// KingSquare returns square of the king of a color
+func (p *Position) KingSquare(c Color) Square {
+	return p.pieces[c][King].First()
+}
+
+// MakeMove validates a move and returns a new position with the move played,
+// the original position is left untouched
+func (p *Position) MakeMove(m Move) (*Position, error) {
+	if !p.IsLegal(m) {
+		return nil, ErrIllegalMove
+	}
+	next := *p
+	next.DoMove(m)
+	return &next, nil
+}
+
+// DoMove plays a pseudo-legal move in place and returns information needed
+// to take it back with UndoMove. It does not allocate.
+func (p *Position) DoMove(m Move) Undo {
+	u := Undo{
+		castling:      p.castling,
+		epSquare:      p.epSquare,
+		halfmoveClock: p.halfmoveClock,
+		hash:          p.hash,
+	}
+	us := p.turn
+	piece := p.board[m.From]
+
+	if p.epSquare != NoSquare {
+		if p.epCapturable(us) {
+			p.hash ^= zobristEnPassant[p.epSquare.File()]
+		}
+		p.epSquare = NoSquare
+	}
+
+	if u.captured = p.board[m.To]; u.captured != NoPiece {
+		p.removePiece(m.To)
+	} else if piece.Type() == Pawn && m.To == u.epSquare {
+		capSq := epCaptureSquare(m.To, us)
+		u.captured = p.board[capSq]
+		p.removePiece(capSq)
+	}
+	p.movePiece(m.From, m.To)
+
+	switch piece.Type() {
+	case Pawn:
+		if m.Promotion != NoPieceType {
+			p.removePiece(m.To)
+			p.putPiece(m.To, NewPiece(us, m.Promotion))
+		} else if m.To-m.From == 16 || m.From-m.To == 16 {
+			p.epSquare = (m.From + m.To) / 2
+			if p.epCapturable(us.Other()) {
+				p.hash ^= zobristEnPassant[p.epSquare.File()]
+			}
+		}
+	case King:
+		if rookFrom, rookTo, ok := castlingRook(m); ok {
+			p.movePiece(rookFrom, rookTo)
+		}
+	}
+
+	p.hash ^= zobristCastling[p.castling]
+	p.castling &^= castlingMask[m.From] | castlingMask[m.To]
+	p.hash ^= zobristCastling[p.castling]
+
+	if piece.Type() == Pawn || u.captured != NoPiece {
+		p.halfmoveClock = 0
+	} else {
+		p.halfmoveClock++
+	}
+	if us == Black {
+		p.fullmoveNumber++
+	}
+	p.turn = us.Other()
+	p.hash ^= zobristTurn
+
+	return u
+}
+
+// UndoMove takes back a move previously played with DoMove
+func (p *Position) UndoMove(m Move, u Undo) {
+	p.turn = p.turn.Other()
+	us := p.turn
+	if us == Black {
+		p.fullmoveNumber--
+	}
+
+	if m.Promotion != NoPieceType {
+		p.removePiece(m.To)
+		p.putPiece(m.To, NewPiece(us, Pawn))
+	}
+	p.movePiece(m.To, m.From)
+
+	piece := p.board[m.From]
+	if piece.Type() == King {
+		if rookFrom, rookTo, ok := castlingRook(m); ok {
+			p.movePiece(rookTo, rookFrom)
+		}
+	}
+
+	if u.captured != NoPiece {
+		if piece.Type() == Pawn && m.To == u.epSquare {
+			p.putPiece(epCaptureSquare(m.To, us), u.captured)
+		} else {
+			p.putPiece(m.To, u.captured)
+		}
+	}
+
+	p.castling = u.castling
+	p.epSquare = u.epSquare
+	p.halfmoveClock = u.halfmoveClock
+	p.hash = u.hash
+}
+
+// putPiece places a piece on an empty square
+func (p *Position) putPiece(sq Square, piece Piece) {
+	b := SquareBB(sq)
+	p.pieces[piece.Color()][piece.Type()] |= b
+	p.occupied[piece.Color()] |= b
+	p.board[sq] = piece
+	p.hash ^= zobristPieces[piece][sq]
+}
+
+// removePiece clears an occupied square
+func (p *Position) removePiece(sq Square) {
+	piece := p.board[sq]
+	b := SquareBB(sq)
+	p.pieces[piece.Color()][piece.Type()] &^= b
+	p.occupied[piece.Color()] &^= b
+	p.board[sq] = NoPiece
+	p.hash ^= zobristPieces[piece][sq]
+}
+
+// movePiece moves a piece to an empty square
+func (p *Position) movePiece(from, to Square) {
+	piece := p.board[from]
+	b := SquareBB(from) | SquareBB(to)
+	p.pieces[piece.Color()][piece.Type()] ^= b
+	p.occupied[piece.Color()] ^= b
+	p.board[from] = NoPiece
+	p.board[to] = piece
+	p.hash ^= zobristPieces[piece][from] ^ zobristPieces[piece][to]
+}
+
+// epCaptureSquare returns square of a pawn captured en passant
+func epCaptureSquare(to Square, us Color) Square {
+	if us == White {
+		return to - 8
+	}
+	return to + 8
+}
+
+// epCapturable returns true if a pawn of a color can legally capture on the
+// en passant square. Only then the square is part of the hash, otherwise
+// positions differing just by an en passant square nobody can use would not
+// count as repetitions.
+func (p *Position) epCapturable(by Color) bool {
+	them := by.Other()
+	captured := SquareBB(epCaptureSquare(p.epSquare, by))
+	king := p.KingSquare(by)
+
+	for b := pawnAttacks[them][p.epSquare] & p.pieces[by][Pawn]; b != 0; {
+		from := b.PopFirst()
+		occupied := (p.occupied[White]|p.occupied[Black])&^(SquareBB(from)|captured) | SquareBB(p.epSquare)
+		if p.AttackersTo(king, occupied)&p.occupied[them]&^captured == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// castlingRook returns rook squares if a king move is castling
+func castlingRook(m Move) (Square, Square, bool) {
+	rank := m.From.Rank()
+	switch m.To.File() - m.From.File() {
+	case 2:
+		return NewSquare(7, rank), NewSquare(5, rank), true
+	case -2:
+		return NewSquare(0, rank), NewSquare(3, rank), true
+	}
+	return NoSquare, NoSquare, false
+}
+
+// kingHome are original squares of both kings
+var kingHome = [2]Square{NewSquare(4, 0), NewSquare(4, 7)}
+
+// castlingMask lists castling rights lost when a piece moves from or to a square
+var castlingMask = func() (mask [64]CastlingRights) {
+	mask[NewSquare(4, 0)] = WhiteKingSide | WhiteQueenSide
+	mask[NewSquare(7, 0)] = WhiteKingSide
+	mask[NewSquare(0, 0)] = WhiteQueenSide
+	mask[NewSquare(4, 7)] = BlackKingSide | BlackQueenSide
+	mask[NewSquare(7, 7)] = BlackKingSide
+	mask[NewSquare(0, 7)] = BlackQueenSide
+	return
+}()
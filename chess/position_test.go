@@ -0,0 +1,170 @@
+package chess_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/RichardKnop/chess-engine/chess"
+	"github.com/RichardKnop/chess-engine/fen"
+)
+
+// walk plays every legal move to a depth and checks that incremental state
+// matches a position built from scratch and that UndoMove restores it
+func walk(t *testing.T, p *chess.Position, depth int) {
+	if depth == 0 {
+		return
+	}
+
+	for _, m := range p.LegalMoves() {
+		before := *p
+		u := p.DoMove(m)
+
+		expected, err := fen.Parse(fen.Encode(p))
+		if err != nil {
+			t.Fatalf("%s after %s: %v", fen.Encode(&before), m, err)
+		}
+		if p.Hash() != expected.Hash() {
+			t.Fatalf("%s after %s: incremental hash does not match", fen.Encode(&before), m)
+		}
+
+		walk(t, p, depth-1)
+
+		p.UndoMove(m, u)
+		if *p != before {
+			t.Fatalf("%s: undo of %s did not restore the position", fen.Encode(&before), m)
+		}
+	}
+}
+
+func TestDoUndoMove(t *testing.T) {
+	for _, tc := range perftTestCases {
+		p, err := fen.Parse(tc.fen)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		walk(t, p, 2)
+	}
+}
+
+func TestHashTransposition(t *testing.T) {
+	p := chess.NewPosition()
+	q := chess.NewPosition()
+
+	for _, s := range []string{"g1f3", "g8f6", "b1c3", "b8c6"} {
+		m, _ := chess.ParseMove(s)
+		p.DoMove(m)
+	}
+	for _, s := range []string{"b1c3", "b8c6", "g1f3", "g8f6"} {
+		m, _ := chess.ParseMove(s)
+		q.DoMove(m)
+	}
+
+	if p.Hash() != q.Hash() {
+		t.Error("Transposed move orders should produce the same hash")
+	}
+}
+
+func TestPerftDoesNotAllocate(t *testing.T) {
+	p := chess.NewPosition()
+	allocs := testing.AllocsPerRun(10, func() {
+		chess.Perft(p, 3)
+	})
+	if allocs > 1 {
+		t.Errorf("Perft allocated %v times per run", allocs)
+	}
+}
+
+func TestMakeMove(t *testing.T) {
+	testCases := []struct {
+		name  string
+		fen   string
+		move  string
+		legal bool
+	}{
+		{"own piece", fen.Initial, "e2e4", true},
+		{"opponent's piece", fen.Initial, "e7e5", false},
+		{"empty square", fen.Initial, "e4e5", false},
+		{"pinned piece", "4k3/4r3/8/8/8/8/4B3/4K3 w - - 0 1", "e2d3", false},
+		{"pinned piece along the pin", "4k3/4r3/8/8/8/8/4R3/4K3 w - - 0 1", "e2e7", true},
+		{"castling", "r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "e1g1", true},
+		{"castling through check", "4kr2/8/8/8/8/8/8/R3K2R w KQ - 0 1", "e1g1", false},
+		{"castling other side", "4kr2/8/8/8/8/8/8/R3K2R w KQ - 0 1", "e1c1", true},
+		{"castling out of check", "4r1k1/8/8/8/8/8/8/R3K2R w KQ - 0 1", "e1g1", false},
+		{"castling without rights", "4k3/8/8/8/8/8/8/R3K2R w Q - 0 1", "e1g1", false},
+		{"en passant", "4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 2", "e5d6", true},
+		{"en passant too late", "4k3/8/8/3pP3/8/8/8/4K3 w - - 0 2", "e5d6", false},
+		{"en passant exposing the king", "8/8/8/K2pP2r/8/8/8/4k3 w - d6 0 2", "e5d6", false},
+		{"promotion", "4k3/P7/8/8/8/8/8/4K3 w - - 0 1", "a7a8q", true},
+		{"underpromotion", "4k3/P7/8/8/8/8/8/4K3 w - - 0 1", "a7a8n", true},
+		{"promotion without a piece", "4k3/P7/8/8/8/8/8/4K3 w - - 0 1", "a7a8", false},
+		{"promotion to a king", "4k3/P7/8/8/8/8/8/4K3 w - - 0 1", "a7a8k", false},
+		{"promotion to a pawn", "4k3/P7/8/8/8/8/8/4K3 w - - 0 1", "a7a8p", false},
+		{"promotion of a king", "4k3/P7/8/8/8/8/8/4K3 w - - 0 1", "e1e2q", false},
+		{"after checkmate", "rnb1kbnr/pppp1ppp/8/4p3/6Pq/5P2/PPPPP2P/RNBQKBNR w KQkq - 1 3", "e2e4", false},
+		{"after stalemate", "7k/5Q2/6K1/8/8/8/8/8 b - - 0 1", "h8g8", false},
+	}
+	for _, tc := range testCases {
+		p, err := fen.Parse(tc.fen)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		m, err := chess.ParseMove(tc.move)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+
+		next, err := p.MakeMove(m)
+		if tc.legal && err != nil {
+			t.Errorf("%s: %s returned %v", tc.name, tc.move, err)
+		}
+		if !tc.legal && err != chess.ErrIllegalMove {
+			t.Errorf("%s: %s returned %v, want %v", tc.name, tc.move, err, chess.ErrIllegalMove)
+		}
+		if tc.legal && err == nil && next.Turn() == p.Turn() {
+			t.Errorf("%s: %s did not pass the turn", tc.name, tc.move)
+		}
+		if s := fen.Encode(p); s != tc.fen {
+			t.Errorf("%s: %s changed the original position to %s", tc.name, tc.move, s)
+		}
+	}
+}
+
+func TestHashEnPassant(t *testing.T) {
+	testCases := []struct {
+		name       string
+		fen        string
+		capturable bool
+	}{
+		{"no pawn next to it", "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1", false},
+		{"pawn next to it", "4k3/8/8/8/3pP3/8/8/4K3 b - e3 0 1", true},
+		{"pinned pawn", "8/8/8/8/k2pP2R/8/8/4K3 b - e3 0 1", false},
+	}
+	for _, tc := range testCases {
+		p, err := fen.Parse(tc.fen)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		q, err := fen.Parse(strings.Replace(tc.fen, " e3 ", " - ", 1))
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if differ := p.Hash() != q.Hash(); differ != tc.capturable {
+			t.Errorf("%s: en passant square changes the hash: %v", tc.name, differ)
+		}
+	}
+
+	// Incremental hash after double pawn pushes matches the full one, after
+	// f7f5 white can capture en passant
+	p := chess.NewPosition()
+	for _, s := range []string{"e2e4", "d7d5", "e4e5", "f7f5"} {
+		m, _ := chess.ParseMove(s)
+		p.DoMove(m)
+		expected, err := fen.Parse(fen.Encode(p))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.Hash() != expected.Hash() {
+			t.Errorf("incremental hash after %s does not match %s", s, fen.Encode(p))
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package chess
+
+import (
+	"errors"
+)
+
+var (
+	// ErrKingCount is returned when a side does not have exactly one king
+	ErrKingCount = errors.New("Each side must have exactly one king")
+	// ErrPawnOnBackRank is returned when a pawn stands on the first or last rank
+	ErrPawnOnBackRank = errors.New("Pawns cannot stand on the first or last rank")
+	// ErrOpponentInCheck is returned when the side not to move is in check
+	ErrOpponentInCheck = errors.New("Side not to move is in check")
+	// ErrInvalidCastling is returned when castling rights do not match the board
+	ErrInvalidCastling = errors.New("Castling rights do not match king and rook placement")
+	// ErrInvalidEnPassant is returned when en passant square does not match the board
+	ErrInvalidEnPassant = errors.New("En passant square does not follow a double pawn push")
+	// ErrInvalidClock is returned when halfmove clock or fullmove number is out of range
+	ErrInvalidClock = errors.New("Invalid halfmove clock or fullmove number")
+)
+
+// Setup is a plain description of a position used to construct one
+type Setup struct {
+	Board          [64]Piece
+	Turn           Color
+	Castling       CastlingRights
+	EnPassant      Square
+	HalfmoveClock  int
+	FullmoveNumber int
+}
+
+// NewPositionFromSetup validates a setup and creates a position from it
+func NewPositionFromSetup(s *Setup) (*Position, error) {
+	p := &Position{
+		turn:           s.Turn,
+		castling:       s.Castling,
+		epSquare:       s.EnPassant,
+		halfmoveClock:  s.HalfmoveClock,
+		fullmoveNumber: s.FullmoveNumber,
+	}
+	for sq := Square(0); sq < 64; sq++ {
+		if s.Board[sq] != NoPiece {
+			p.putPiece(sq, s.Board[sq])
+		}
+	}
+
+	if err := p.validate(); err != nil {
+		return nil, err
+	}
+	p.hash = p.computeHash()
+
+	return p, nil
+}
+
+// validate checks the position is reachable enough for move generation to work
+func (p *Position) validate() error {
+	if p.pieces[White][King].Count() != 1 || p.pieces[Black][King].Count() != 1 {
+		return ErrKingCount
+	}
+	if (p.pieces[White][Pawn]|p.pieces[Black][Pawn])&(rank1|rank8) != 0 {
+		return ErrPawnOnBackRank
+	}
+
+	if p.OpponentInCheck() {
+		return ErrOpponentInCheck
+	}
+
+	for _, c := range [2]Color{White, Black} {
+		kingSide, queenSide, rank := WhiteKingSide, WhiteQueenSide, 0
+		if c == Black {
+			kingSide, queenSide, rank = BlackKingSide, BlackQueenSide, 7
+		}
+		if p.castling&(kingSide|queenSide) != 0 && p.board[kingHome[c]] != NewPiece(c, King) {
+			return ErrInvalidCastling
+		}
+		if p.castling&kingSide != 0 && p.board[NewSquare(7, rank)] != NewPiece(c, Rook) {
+			return ErrInvalidCastling
+		}
+		if p.castling&queenSide != 0 && p.board[NewSquare(0, rank)] != NewPiece(c, Rook) {
+			return ErrInvalidCastling
+		}
+	}
+
+	if p.epSquare != NoSquare {
+		// The pawn which has just moved two squares stands in front of the
+		// en passant square and both squares it passed through are empty
+		rank, dir := 5, -1
+		if p.turn == Black {
+			rank, dir = 2, 1
+		}
+		pawn := NewSquare(p.epSquare.File(), rank+dir)
+		origin := NewSquare(p.epSquare.File(), rank-dir)
+		if p.epSquare.Rank() != rank ||
+			p.board[pawn] != NewPiece(p.turn.Other(), Pawn) ||
+			p.board[p.epSquare] != NoPiece ||
+			p.board[origin] != NoPiece {
+			return ErrInvalidEnPassant
+		}
+	}
+
+	if p.halfmoveClock < 0 || p.fullmoveNumber < 1 {
+		return ErrInvalidClock
+	}
+
+	return nil
+}
@@ -0,0 +1,65 @@
+package chess
+
+// darkSquares are a1, c1, ... h8
+const darkSquares Bitboard = 0xaa55aa55aa55aa55
+
+// IsCheckmate returns true if the side to move is in check and has no legal moves
+func (p *Position) IsCheckmate() bool {
+	return p.InCheck() && !p.hasLegalMove()
+}
+
+// IsStalemate returns true if the side to move is not in check and has no legal moves
+func (p *Position) IsStalemate() bool {
+	return !p.InCheck() && !p.hasLegalMove()
+}
+
+// IsFiftyMoveDraw returns true if no pawn was moved and nothing was captured
+// during the last fifty moves of each side
+func (p *Position) IsFiftyMoveDraw() bool {
+	return p.halfmoveClock >= 100
+}
+
+// IsInsufficientMaterial returns true if neither side can possibly checkmate:
+// only kings remain, one side has a single minor piece, or all remaining
+// bishops stand on squares of the same color
+func (p *Position) IsInsufficientMaterial() bool {
+	for c := White; c <= Black; c++ {
+		if p.pieces[c][Pawn]|p.pieces[c][Rook]|p.pieces[c][Queen] != 0 {
+			return false
+		}
+	}
+
+	knights := p.pieces[White][Knight] | p.pieces[Black][Knight]
+	bishops := p.pieces[White][Bishop] | p.pieces[Black][Bishop]
+	if (knights | bishops).Count() <= 1 {
+		return true
+	}
+	return knights == 0 && (bishops&darkSquares == 0 || bishops&^darkSquares == 0)
+}
+
+// hasLegalMove returns true if the side to move has at least one legal move
+func (p *Position) hasLegalMove() bool {
+	var list MoveList
+	p.GenerateMoves(&list)
+
+	q := *p
+	for i := 0; i < list.count; i++ {
+		m := list.moves[i]
+		u := q.DoMove(m)
+		legal := !q.OpponentInCheck()
+		q.UndoMove(m, u)
+		if legal {
+			return true
+		}
+	}
+	return false
+}
+
+// HasMatingMaterial returns false if a color has only its king, or its king
+// and a single minor piece, which can never force checkmate
+func (p *Position) HasMatingMaterial(c Color) bool {
+	if p.pieces[c][Pawn]|p.pieces[c][Rook]|p.pieces[c][Queen] != 0 {
+		return true
+	}
+	return (p.pieces[c][Knight] | p.pieces[c][Bishop]).Count() > 1
+}
@@ -0,0 +1,77 @@
+package chess_test
+
+import (
+	"testing"
+
+	"github.com/RichardKnop/chess-engine/chess"
+	"github.com/RichardKnop/chess-engine/fen"
+)
+
+func TestStatus(t *testing.T) {
+	testCases := []struct {
+		name                 string
+		fen                  string
+		checkmate            bool
+		stalemate            bool
+		fiftyMoveDraw        bool
+		insufficientMaterial bool
+	}{
+		{name: "initial position", fen: fen.Initial},
+		{name: "fool's mate", fen: "rnb1kbnr/pppp1ppp/8/4p3/6Pq/5P2/PPPPP2P/RNBQKBNR w KQkq - 1 3", checkmate: true},
+		{name: "stalemate", fen: "7k/5Q2/6K1/8/8/8/8/8 b - - 0 1", stalemate: true},
+		{name: "fifty moves", fen: "4k3/8/8/8/8/8/8/R3K3 w - - 100 80", fiftyMoveDraw: true},
+		{name: "checkmate on the hundredth half move", fen: "R5k1/5ppp/8/8/8/8/8/6K1 b - - 100 80", checkmate: true, fiftyMoveDraw: true},
+		{name: "bare kings", fen: "4k3/8/8/8/8/8/8/4K3 w - - 0 1", insufficientMaterial: true},
+		{name: "king and knight", fen: "4k3/8/8/8/8/8/8/4KN2 w - - 0 1", insufficientMaterial: true},
+		{name: "king and bishop", fen: "4k3/8/8/8/8/8/8/4KB2 w - - 0 1", insufficientMaterial: true},
+		{name: "bishops on same color", fen: "4kb2/8/8/8/8/8/8/2B1K3 w - - 0 1", insufficientMaterial: true},
+		{name: "bishops on opposite colors", fen: "4k1b1/8/8/8/8/8/8/2B1K3 w - - 0 1"},
+		{name: "two knights", fen: "4k3/8/8/8/8/8/8/1N2KN2 w - - 0 1"},
+		{name: "king and pawn", fen: "4k3/8/8/8/8/8/4P3/4K3 w - - 0 1"},
+	}
+
+	for _, tc := range testCases {
+		p, err := fen.Parse(tc.fen)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if got := p.IsCheckmate(); got != tc.checkmate {
+			t.Errorf("%s: IsCheckmate() = %v", tc.name, got)
+		}
+		if got := p.IsStalemate(); got != tc.stalemate {
+			t.Errorf("%s: IsStalemate() = %v", tc.name, got)
+		}
+		if got := p.IsFiftyMoveDraw(); got != tc.fiftyMoveDraw {
+			t.Errorf("%s: IsFiftyMoveDraw() = %v", tc.name, got)
+		}
+		if got := p.IsInsufficientMaterial(); got != tc.insufficientMaterial {
+			t.Errorf("%s: IsInsufficientMaterial() = %v", tc.name, got)
+		}
+	}
+}
+
+func TestHasMatingMaterial(t *testing.T) {
+	testCases := []struct {
+		fen   string
+		white bool
+		black bool
+	}{
+		{fen: fen.Initial, white: true, black: true},
+		{fen: "4k3/8/8/8/8/8/8/4KN2 w - - 0 1", white: false, black: false},
+		{fen: "4k3/8/8/8/8/8/8/3BKN2 w - - 0 1", white: true, black: false},
+		{fen: "4k3/p7/8/8/8/8/8/4K3 w - - 0 1", white: false, black: true},
+	}
+
+	for _, tc := range testCases {
+		p, err := fen.Parse(tc.fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := p.HasMatingMaterial(chess.White); got != tc.white {
+			t.Errorf("%s: HasMatingMaterial(White) = %v", tc.fen, got)
+		}
+		if got := p.HasMatingMaterial(chess.Black); got != tc.black {
+			t.Errorf("%s: HasMatingMaterial(Black) = %v", tc.fen, got)
+		}
+	}
+}
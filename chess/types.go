@@ -0,0 +1,233 @@
+package chess
+
+import (
+	"fmt"
+)
+
+// Color represents a side (white or black)
+type Color int8
+
+const (
+	// White moves first
+	White Color = iota
+	// Black moves second
+	Black
+)
+
+// Other returns the opposite color
+func (c Color) Other() Color {
+	return c ^ 1
+}
+
+// String returns a lowercase name of the color
+func (c Color) String() string {
+	if c == White {
+		return "white"
+	}
+	return "black"
+}
+
+// PieceType represents a kind of piece regardless of its color
+type PieceType int8
+
+const (
+	// NoPieceType is used for empty squares and moves without promotion
+	NoPieceType PieceType = iota
+	// Pawn ...
+	Pawn
+	// Knight ...
+	Knight
+	// Bishop ...
+	Bishop
+	// Rook ...
+	Rook
+	// Queen ...
+	Queen
+	// King ...
+	King
+)
+
+// pieceTypeChars maps piece types to lowercase FEN characters
+var pieceTypeChars = [...]byte{' ', 'p', 'n', 'b', 'r', 'q', 'k'}
+
+// Char returns a lowercase letter of the piece type
+func (t PieceType) Char() byte {
+	return pieceTypeChars[t]
+}
+
+// ParsePieceType parses a single letter (either case) into a piece type
+func ParsePieceType(c byte) (PieceType, error) {
+	if c >= 'A' && c <= 'Z' {
+		c += 'a' - 'A'
+	}
+	for t := Pawn; t <= King; t++ {
+		if pieceTypeChars[t] == c {
+			return t, nil
+		}
+	}
+	return NoPieceType, fmt.Errorf("Invalid piece type: %c", c)
+}
+
+// Piece is a colored piece, the zero value is an empty square
+type Piece int8
+
+// NoPiece represents an empty square
+const NoPiece Piece = 0
+
+// NewPiece returns a piece of the given color and type
+func NewPiece(c Color, t PieceType) Piece {
+	return Piece(int8(c)<<3 | int8(t))
+}
+
+// Color returns color of the piece
+func (p Piece) Color() Color {
+	return Color(p >> 3)
+}
+
+// Type returns type of the piece
+func (p Piece) Type() PieceType {
+	return PieceType(p & 7)
+}
+
+// Char returns a FEN character of the piece (uppercase for white)
+func (p Piece) Char() byte {
+	c := p.Type().Char()
+	if p.Color() == White {
+		c -= 'a' - 'A'
+	}
+	return c
+}
+
+// Code returns a two letter piece code such as wP or bK
+func (p Piece) Code() string {
+	if p == NoPiece {
+		return ""
+	}
+	t := p.Type().Char() - ('a' - 'A')
+	if p.Color() == White {
+		return "w" + string(t)
+	}
+	return "b" + string(t)
+}
+
+// ParsePiece parses a FEN character into a piece
+func ParsePiece(c byte) (Piece, error) {
+	t, err := ParsePieceType(c)
+	if err != nil {
+		return NoPiece, err
+	}
+	if c >= 'a' && c <= 'z' {
+		return NewPiece(Black, t), nil
+	}
+	return NewPiece(White, t), nil
+}
+
+// Square is an index of a square on the board, a1 is 0 and h8 is 63
+type Square int8
+
+// NoSquare is used when there is no square, e.g. no en passant target
+const NoSquare Square = -1
+
+// NewSquare returns a square from zero based file and rank
+func NewSquare(file, rank int) Square {
+	return Square(rank*8 + file)
+}
+
+// ParseSquare parses algebraic notation such as e4 into a square
+func ParseSquare(s string) (Square, error) {
+	if len(s) != 2 || s[0] < 'a' || s[0] > 'h' || s[1] < '1' || s[1] > '8' {
+		return NoSquare, fmt.Errorf("Invalid square: %s", s)
+	}
+	return NewSquare(int(s[0]-'a'), int(s[1]-'1')), nil
+}
+
+// File returns zero based file of the square
+func (s Square) File() int {
+	return int(s) & 7
+}
+
+// Rank returns zero based rank of the square
+func (s Square) Rank() int {
+	return int(s) >> 3
+}
+
+// String returns algebraic notation of the square
+func (s Square) String() string {
+	if s == NoSquare {
+		return "-"
+	}
+	return string([]byte{byte('a' + s.File()), byte('1' + s.Rank())})
+}
+
+// offset returns a square shifted by given number of files and ranks and
+// false if the resulting square would be off the board
+func (s Square) offset(df, dr int) (Square, bool) {
+	f, r := s.File()+df, s.Rank()+dr
+	if f < 0 || f > 7 || r < 0 || r > 7 {
+		return NoSquare, false
+	}
+	return NewSquare(f, r), true
+}
+
+// CastlingRights is a bit set of castling options still available
+type CastlingRights uint8
+
+const (
+	// WhiteKingSide means white can still castle short
+	WhiteKingSide CastlingRights = 1 << iota
+	// WhiteQueenSide means white can still castle long
+	WhiteQueenSide
+	// BlackKingSide means black can still castle short
+	BlackKingSide
+	// BlackQueenSide means black can still castle long
+	BlackQueenSide
+
+	// NoCastling means neither side can castle
+	NoCastling CastlingRights = 0
+	// AllCastling means both sides can castle both ways
+	AllCastling = WhiteKingSide | WhiteQueenSide | BlackKingSide | BlackQueenSide
+)
+
+// Move represents a single move from one square to another
+type Move struct {
+	From      Square
+	To        Square
+	Promotion PieceType
+}
+
+// NoMove is the zero value of Move and never a legal move
+var NoMove = Move{}
+
+// String returns the move in long algebraic notation, e.g. e2e4 or e7e8q
+func (m Move) String() string {
+	if m == NoMove {
+		return "0000"
+	}
+	s := m.From.String() + m.To.String()
+	if m.Promotion != NoPieceType {
+		s += string(m.Promotion.Char())
+	}
+	return s
+}
+
+// ParseMove parses a move in long algebraic notation, e.g. e2e4 or e7e8q
+func ParseMove(s string) (Move, error) {
+	if len(s) != 4 && len(s) != 5 {
+		return Move{}, fmt.Errorf("Invalid move: %s", s)
+	}
+	from, err := ParseSquare(s[0:2])
+	if err != nil {
+		return Move{}, err
+	}
+	to, err := ParseSquare(s[2:4])
+	if err != nil {
+		return Move{}, err
+	}
+	m := Move{From: from, To: to}
+	if len(s) == 5 {
+		if m.Promotion, err = ParsePieceType(s[4]); err != nil {
+			return Move{}, err
+		}
+	}
+	return m, nil
+}
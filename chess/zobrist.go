@@ -0,0 +1,46 @@
+package chess
+
+// Zobrist keys identify positions by XORing a random number for every piece
+// on its square, the side to move, castling rights and en passant file when
+// a pawn can capture en passant.
+// See https://www.chessprogramming.org/Zobrist_Hashing
+var (
+	zobristPieces    [16][64]uint64
+	zobristCastling  [16]uint64
+	zobristEnPassant [8]uint64
+	zobristTurn      uint64
+)
+
+func init() {
+	rng := newPRNG(1070372)
+	for p := range zobristPieces {
+		for sq := range zobristPieces[p] {
+			zobristPieces[p][sq] = rng.next()
+		}
+	}
+	for i := range zobristCastling {
+		zobristCastling[i] = rng.next()
+	}
+	for i := range zobristEnPassant {
+		zobristEnPassant[i] = rng.next()
+	}
+	zobristTurn = rng.next()
+}
+
+// computeHash calculates Zobrist key of a position from scratch
+func (p *Position) computeHash() uint64 {
+	var h uint64
+	for sq := Square(0); sq < 64; sq++ {
+		if piece := p.board[sq]; piece != NoPiece {
+			h ^= zobristPieces[piece][sq]
+		}
+	}
+	h ^= zobristCastling[p.castling]
+	if p.epSquare != NoSquare && p.epCapturable(p.turn) {
+		h ^= zobristEnPassant[p.epSquare.File()]
+	}
+	if p.turn == Black {
+		h ^= zobristTurn
+	}
+	return h
+}
@@ -1,23 +1,59 @@
 var conn,
     board,
     newGameBtn = document.getElementById('new-game-btn'),
+    leaveQueueBtn = document.getElementById('leave-queue-btn'),
+    ratingRange = document.getElementById('rating-range'),
+    rated = document.getElementById('rated'),
+    createSeekBtn = document.getElementById('create-seek-btn'),
+    seekList = document.getElementById('seeks'),
+    challengeBtn = document.getElementById('challenge-btn'),
+    privateGameBtn = document.getElementById('private-game-btn'),
+    challengePlayerID = document.getElementById('challenge-player-id'),
+    challengeList = document.getElementById('challenges'),
+    playComputerBtn = document.getElementById('play-computer-btn'),
+    importGameBtn = document.getElementById('import-game-btn'),
+    watchGameBtn = document.getElementById('watch-game-btn'),
+    watchGameID = document.getElementById('watch-game-id'),
+    spectators = document.getElementById('spectators'),
+    chatChannel = document.getElementById('chat-channel'),
+    chatInput = document.getElementById('chat-input'),
+    chatBtn = document.getElementById('chat-btn'),
+    pgnInput = document.getElementById('pgn-input'),
+    computerLevel = document.getElementById('computer-level'),
+    timeControl = document.getElementById('time-control'),
+    whiteClock = document.getElementById('white-clock'),
+    blackClock = document.getElementById('black-clock'),
+    clock = null,
     log = document.getElementById('console'),
     player = {
-        ID: generateUUID(),
+        ID: null,
+        token: null,
+        profile: null,
+    },
+    // Images of piece sets a player can choose in their profile
+    pieceThemes = {
+        'wikipedia': 'img/chesspieces/wikipedia/{piece}.png',
+        'alpha': 'img/alpha/{piece}.png',
+        'uscf': 'img/chesspieces/uscf/{piece}.png',
     },
     game = {
         ID: getQueryStringParam('game_id'),
+        watching: getQueryStringParam('watch') === '1',
+        // Private games can only be opened with the invite code
+        inviteCode: getQueryStringParam('invite'),
         started: false,
+        over: false,
         myTurn: false,
     },
     cfg = {
         draggable: true,
         onDrop: function(source, target, piece, newPos, oldPos, orientation) {
-            if (!game.started || !game.myTurn || (newPos === oldPos)) {
+            if (!game.started || game.over || game.watching || !game.myTurn || (newPos === oldPos)) {
                 // http://chessboardjs.com/docs#config:onDrop
                 return 'snapback';
             }
             game.myTurn = !game.myTurn;
+            game.lastPosition = ChessBoard.objToFen(oldPos);
             conn.send(JSON.stringify({
                 type: 'make_move',
                 data: {
@@ -26,8 +62,6 @@ var conn,
                     'source': source,
                     'target': target,
                     'piece': piece,
-                    'old_position': ChessBoard.objToFen(oldPos),
-                    'new_position': ChessBoard.objToFen(newPos),
                 },
             }));
         },
@@ -37,12 +71,14 @@ function initWebsocket() {
     console.log("Player ID: ", player.ID);
 
     var wsHost = 'localhost:8080',
-        socket = new ReconnectingWebSocket('ws://' + wsHost + '/ws');
+        socket = new ReconnectingWebSocket('ws://' + wsHost + '/ws?token=' + encodeURIComponent(player.token));
 
     socket.onopen = function() {
         console.log('Connection established');
 
-        if (game.ID) {
+        if (game.ID && game.watching) {
+            watchGame(game.ID);
+        } else if (game.ID) {
             var orientation = getOrientation();
             setOrientation(orientation);
 
@@ -52,6 +88,7 @@ function initWebsocket() {
                     'game_id': game.ID,
                     'player_id': player.ID,
                     'orientation': cfg.orientation,
+                    'invite_code': game.inviteCode || '',
                 },
             }));
         }
@@ -82,11 +119,76 @@ function initWebsocket() {
                     game.ID = msg.data['game_id'];
 
                     // Append game ID to URL
-                    setQueryStringParams({ 'game_id': game.ID, 'orientation': cfg.orientation });
+                    var params = { 'game_id': game.ID };
+                    if (game.watching) {
+                        board.position(msg.data['position']);
+                        params['watch'] = '1';
+                    } else {
+                        params['orientation'] = cfg.orientation;
+                    }
+                    if (game.inviteCode) {
+                        params['invite'] = game.inviteCode;
+                    }
+                    setQueryStringParams(params);
 
                     // Set game.myTurn
                     game.myTurn = msg.data['player_id'] == player.ID;
 
+                    spectators.innerHTML = msg.data['spectators'] ? msg.data['spectators'] + ' watching' : '';
+
+                    updateClock(msg.data['clock']);
+
+                    break;
+                case 'queue_joined':
+                    appendLog('Looking for an opponent...');
+                    break;
+                case 'queue_left':
+                    appendLog('Stopped looking for an opponent.');
+                    break;
+                case 'match_found':
+                    // Seeks and challenges start a game without startGame
+                    game = {
+                        ID: msg.data['game_id'],
+                        started: false,
+                        over: false,
+                        myTurn: false,
+                    };
+                    setOrientation(msg.data['orientation']);
+                    board = ChessBoard('board', cfg);
+                    board.position(msg.data['position']);
+                    appendLog('Opponent found, you play ' + msg.data['orientation'] + '.');
+                    break;
+                case 'private_game_created':
+                    game.ID = msg.data['game_id'];
+                    game.inviteCode = msg.data['invite_code'];
+                    setOrientation(msg.data['orientation']);
+                    board = ChessBoard('board', cfg);
+                    board.position(msg.data['position']);
+                    appendLog('Private game created, invite code ' + game.inviteCode + '. Send this link to your opponent: ' +
+                        window.location.protocol + '//' + window.location.host + window.location.pathname +
+                        '?game_id=' + game.ID + '&invite=' + game.inviteCode +
+                        '&orientation=' + (msg.data['orientation'] === 'white' ? 'black' : 'white'));
+                    break;
+                case 'seeks':
+                    renderSeeks(msg.data['seeks'] || []);
+                    break;
+                case 'challenge_received':
+                    addChallenge(msg.data['challenge']);
+                    break;
+                case 'challenge_sent':
+                    appendLog('Challenge sent to ' + escapeHTML(msg.data['challenge']['player_id'].substr(0, 8)) + '.');
+                    break;
+                case 'challenge_declined':
+                    appendLog('Challenge declined.');
+                    break;
+                case 'challenge_cancelled':
+                    // Challenges are cancelled when either player goes offline
+                    if (msg.data['challenge']['player_id'] == player.ID) {
+                        removeChallenge(msg.data['challenge']['id']);
+                        appendLog(escapeHTML(describeChallenger(msg.data['challenge'])) + ' cancelled the challenge.');
+                    } else {
+                        appendLog('Challenge cancelled, ' + escapeHTML(msg.data['challenge']['player_id'].substr(0, 8)) + ' went offline.');
+                    }
                     break;
                 case 'game_started':
                     if (!game.started) {
@@ -95,13 +197,74 @@ function initWebsocket() {
                         // Set board position
                         board.position(msg.data['position']);
 
-                        appendLog('Game started.');
+                        appendLog(msg.data['rated'] ? 'Rated game started.' : 'Game started.');
                     }
+                    updateClock(msg.data['clock']);
                     break;
                 case 'move_made':
-                    if (board.fen() !== msg.data['position']) {
-                        board.position(msg.data['position']);
-                        game.myTurn = !game.myTurn;
+                    // Server derives the position so castling, en passant
+                    // and promotions are reflected on the board
+                    board.position(msg.data['position']);
+                    game.myTurn = msg.data['player_id'] !== player.ID;
+                    updateClock(msg.data['clock']);
+                    break;
+                case 'game_over':
+                    board.position(msg.data['position']);
+                    game.myTurn = false;
+                    game.over = true;
+                    updateClock(msg.data['clock']);
+                    appendLog('Game over: ' + msg.data['result'] + ' (' + msg.data['reason'].replace(/_/g, ' ') + ').');
+                    break;
+                case 'draw_offered':
+                    appendLog(describePlayer(msg.data['player_id']) + ' offered a draw.');
+                    break;
+                case 'draw_declined':
+                    appendLog(describePlayer(msg.data['player_id']) + ' declined the draw offer.');
+                    break;
+                case 'player_disconnected':
+                    appendLog(describePlayer(msg.data['player_id']) + ' disconnected, waiting for them to come back.');
+                    break;
+                case 'player_reconnected':
+                    appendLog(describePlayer(msg.data['player_id']) + ' reconnected.');
+                    break;
+                case 'takeback_requested':
+                    appendLog(describePlayer(msg.data['player_id']) + ' asked to take back a move.');
+                    break;
+                case 'takeback_accepted':
+                    board.position(msg.data['position']);
+                    game.myTurn = msg.data['player_id'] === player.ID;
+                    updateClock(msg.data['clock']);
+                    appendLog('Move taken back.');
+                    break;
+                case 'replay_position':
+                    // Imported games are only replayed, moves are not allowed
+                    game.over = true;
+                    board.position(msg.data['position']);
+                    appendLog('Ply ' + (msg.data['ply'] || 0) + '.');
+                    break;
+                case 'chat_message':
+                case 'chat_history':
+                    for (var j = 0; j < msg.data['chat'].length; j++) {
+                        appendChat(msg.data['chat'][j]);
+                    }
+                    break;
+                case 'pgn':
+                    appendLog(escapeHTML(msg.data['pgn']));
+                    // Private games are only exported to their players and
+                    // players with the invite code
+                    var query = '?token=' + encodeURIComponent(player.token);
+                    if (game.inviteCode) {
+                        query += '&invite=' + encodeURIComponent(game.inviteCode);
+                    }
+                    appendLog(escapeHTML('Download: ' + window.location.protocol + '//' + window.location.host + '/games/' + msg.data['game_id'] + '.pgn' + query));
+                    break;
+                case 'error':
+                    appendLog(escapeHTML(msg.data['error']));
+
+                    // Undo the rejected move
+                    if (board && game.lastPosition) {
+                        board.position(game.lastPosition);
+                        game.myTurn = true;
                     }
                     break;
             }
@@ -121,11 +284,12 @@ function initWebsocket() {
     return socket;
 }
 
-newGameBtn.addEventListener('click', function(evt) {
+function startGame(type, data) {
     // Reset game data
     game = {
         ID: null,
         started: false,
+        over: false,
         myTurn: false,
     }
 
@@ -137,29 +301,367 @@ newGameBtn.addEventListener('click', function(evt) {
 
     board = ChessBoard('board', cfg);
 
+    updateClock(null);
+
+    // Private games send the invited player instead
+    if (!('player_id' in data)) {
+        data['player_id'] = player.ID;
+    }
+    // Colors are assigned by the server, only a preference is sent
+    var preference = type === 'join_queue' || type === 'create_private_game';
+    data['orientation'] = preference ? getOrientationPreference() : cfg.orientation;
+    data['time_control'] = timeControl.value;
+    conn.send(JSON.stringify({
+        type: type,
+        data: data,
+    }));
+}
+
+newGameBtn.addEventListener('click', function(evt) {
+    startGame('join_queue', {
+        'rating_range': parseInt(ratingRange.value, 10),
+    });
+    return false;
+});
+
+leaveQueueBtn.addEventListener('click', function(evt) {
     conn.send(JSON.stringify({
-        type: 'find_game',
+        type: 'leave_queue',
+        data: {},
+    }));
+    return false;
+});
+
+createSeekBtn.addEventListener('click', function(evt) {
+    conn.send(JSON.stringify({
+        type: 'create_seek',
+        data: {
+            'orientation': getOrientationPreference(),
+            'time_control': timeControl.value,
+            'rated': rated.checked,
+            'rating_range': parseInt(ratingRange.value, 10),
+        },
+    }));
+    return false;
+});
+
+// renderSeeks shows open seeks, own seeks can be cancelled and others accepted
+function renderSeeks(seeks) {
+    var html = '';
+    for (var i = 0; i < seeks.length; i++) {
+        var seek = seeks[i],
+            own = seek['player_id'] === player.ID;
+        html += '<div>' + escapeHTML(seek['name'] || seek['player_id'].substr(0, 8)) +
+            ' (' + seek['rating'] + ') ' + escapeHTML(seek['time_control'] || 'untimed') +
+            (seek['rated'] ? ' rated' : ' casual') +
+            ' <button class="seek-action" data-type="' + (own ? 'cancel_seek' : 'accept_seek') +
+            '" data-id="' + seek['id'] + '">' + (own ? 'Cancel' : 'Accept') + '</button></div>';
+    }
+    seekList.innerHTML = html;
+}
+
+seekList.addEventListener('click', function(evt) {
+    var btn = evt.target;
+    if (btn.className !== 'seek-action') {
+        return false;
+    }
+    conn.send(JSON.stringify({
+        type: btn.getAttribute('data-type'),
+        data: {
+            'seek_id': btn.getAttribute('data-id'),
+        },
+    }));
+    return false;
+});
+
+privateGameBtn.addEventListener('click', function(evt) {
+    // The player ID field limits the free seat to that player
+    startGame('create_private_game', {
+        'player_id': challengePlayerID.value,
+        'rated': rated.checked,
+    });
+    return false;
+});
+
+challengeBtn.addEventListener('click', function(evt) {
+    if (!challengePlayerID.value) {
+        return false;
+    }
+    conn.send(JSON.stringify({
+        type: 'challenge_player',
+        data: {
+            'player_id': challengePlayerID.value,
+            'orientation': getOrientationPreference(),
+            'time_control': timeControl.value,
+            'rated': rated.checked,
+        },
+    }));
+    return false;
+});
+
+// addChallenge shows a challenge received from another player
+function addChallenge(challenge) {
+    var div = document.createElement('div');
+    div.id = 'challenge-' + challenge['id'];
+    div.innerHTML = escapeHTML(describeChallenger(challenge)) + ' challenges you: ' +
+        escapeHTML(challenge['time_control'] || 'untimed') + (challenge['rated'] ? ' rated' : ' casual') +
+        ' <button class="challenge-action" data-type="accept_challenge" data-id="' + challenge['id'] + '">Accept</button>' +
+        ' <button class="challenge-action" data-type="decline_challenge" data-id="' + challenge['id'] + '">Decline</button>';
+    challengeList.appendChild(div);
+}
+
+function removeChallenge(challengeID) {
+    var div = document.getElementById('challenge-' + challengeID);
+    if (div) {
+        challengeList.removeChild(div);
+    }
+}
+
+function describeChallenger(challenge) {
+    return challenge['challenger_name'] || challenge['challenger_id'].substr(0, 8);
+}
+
+challengeList.addEventListener('click', function(evt) {
+    var btn = evt.target;
+    if (btn.className !== 'challenge-action') {
+        return false;
+    }
+    removeChallenge(btn.getAttribute('data-id'));
+    conn.send(JSON.stringify({
+        type: btn.getAttribute('data-type'),
         data: {
+            'challenge_id': btn.getAttribute('data-id'),
+        },
+    }));
+    return false;
+});
+
+playComputerBtn.addEventListener('click', function(evt) {
+    startGame('play_computer', {
+        'level': parseInt(computerLevel.value, 10),
+    });
+    return false;
+});
+
+watchGameBtn.addEventListener('click', function(evt) {
+    if (watchGameID.value) {
+        watchGame(watchGameID.value);
+    }
+    return false;
+});
+
+// watchGame follows a game as a spectator, the board cannot be moved
+function watchGame(gameID) {
+    game = {
+        ID: gameID,
+        watching: true,
+        inviteCode: game.inviteCode,
+        started: true,
+        over: false,
+        myTurn: false,
+    };
+
+    board = ChessBoard('board', cfg);
+
+    updateClock(null);
+
+    conn.send(JSON.stringify({
+        type: 'watch_game',
+        data: {
+            'game_id': gameID,
+            'player_id': player.ID,
+            'invite_code': game.inviteCode || '',
+        },
+    }));
+}
+
+chatBtn.addEventListener('click', function(evt) {
+    if (!chatInput.value) {
+        return false;
+    }
+    conn.send(JSON.stringify({
+        type: 'chat_message',
+        data: {
+            'game_id': game.ID || '',
             'player_id': player.ID,
-            'orientation': cfg.orientation,
+            'channel': chatChannel.value,
+            'text': chatInput.value,
         },
     }));
+    chatInput.value = '';
+    return false;
+});
+
+// appendChat shows a chat message, the text is escaped as it comes from
+// other players
+function appendChat(chat) {
+    var name = chat['player_id'] === player.ID ? 'You' : chat['player_id'].substr(0, 8);
+    appendLog('[' + chat['channel'] + '] ' + escapeHTML(name) + ': ' + escapeHTML(chat['text']));
+}
 
+function escapeHTML(s) {
+    return s.replace(/&/g, '&amp;').replace(/</g, '&lt;').replace(/>/g, '&gt;');
+}
+
+importGameBtn.addEventListener('click', function(evt) {
+    startGame('import_game', {
+        'pgn': pgnInput.value,
+    });
     return false;
 });
 
-function generateUUID() { // Public Domain/MIT
-    var d = new Date().getTime();
-    if (typeof performance !== 'undefined' && typeof performance.now === 'function') {
-        d += performance.now(); //use high-precision timer if available
+// updateClock stores the clock sent by the server, the running side is
+// counted down locally until the next update arrives
+function updateClock(state) {
+    clock = state ? {
+        white: state['white'],
+        black: state['black'],
+        turn: state['turn'],
+        running: state['running'],
+        received: Date.now(),
+    } : null;
+    renderClock();
+}
+
+function renderClock() {
+    if (!clock) {
+        whiteClock.innerHTML = '-';
+        blackClock.innerHTML = '-';
+        return;
     }
-    return 'xxxxxxxx-xxxx-4xxx-yxxx-xxxxxxxxxxxx'.replace(/[xy]/g, function(c) {
-        var r = (d + Math.random() * 16) % 16 | 0;
-        d = Math.floor(d / 16);
-        return (c === 'x' ? r : (r & 0x3 | 0x8)).toString(16);
+
+    var elapsed = clock.running ? Date.now() - clock.received : 0;
+    whiteClock.innerHTML = formatTime(clock.white - (clock.turn === 'white' ? elapsed : 0));
+    blackClock.innerHTML = formatTime(clock.black - (clock.turn === 'black' ? elapsed : 0));
+}
+
+function formatTime(ms) {
+    if (ms < 0) {
+        ms = 0;
+    }
+    var seconds = Math.floor(ms / 1000),
+        minutes = Math.floor(seconds / 60);
+    seconds = seconds % 60;
+    return minutes + ':' + (seconds < 10 ? '0' : '') + seconds;
+}
+
+setInterval(renderClock, 200);
+
+var actionBtns = document.getElementsByClassName('game-action');
+for (var i = 0; i < actionBtns.length; i++) {
+    actionBtns[i].addEventListener('click', function(evt) {
+        if (!game.ID) {
+            return false;
+        }
+        conn.send(JSON.stringify({
+            type: evt.target.getAttribute('data-type'),
+            data: {
+                'game_id': game.ID,
+                'player_id': player.ID,
+                'invite_code': game.inviteCode || '',
+            },
+        }));
+        return false;
+    });
+}
+
+function describePlayer(playerID) {
+    return playerID === player.ID ? 'You' : 'Your opponent';
+}
+
+// startSession gets a session token identifying the player from the server,
+// the token is kept for the browser tab so a page refresh reconnects to the
+// reserved seat
+function startSession(callback) {
+    sessionRequest('/session', { 'token': window.sessionStorage.getItem('token') || '' }, callback);
+}
+
+// sessionRequest calls an endpoint issuing a session token, the websocket
+// reconnects with the new identity
+function sessionRequest(url, data, callback) {
+    $.ajax({
+        type: 'POST',
+        url: url,
+        contentType: 'application/json',
+        data: JSON.stringify(data),
+        dataType: 'json',
+        headers: player.token ? { 'Authorization': 'Bearer ' + player.token } : {},
+        success: function(session) {
+            player.ID = session['player_id'];
+            player.token = session['token'];
+            window.sessionStorage.setItem('token', player.token);
+            setProfile(session['profile'] || null);
+            callback();
+        },
+        error: function(xhr) {
+            appendLog(escapeHTML(xhr.responseText || 'Could not start a session.'));
+        },
     });
 }
 
+function setProfile(profile) {
+    player.profile = profile;
+    document.getElementById('account-name').innerHTML = profile ? escapeHTML(profile['display_name']) : 'Anonymous';
+    if (profile) {
+        document.getElementById('display-name').value = profile['display_name'];
+        document.getElementById('country').value = profile['country'] || '';
+        document.getElementById('piece-set').value = profile['piece_set'];
+    }
+    cfg.pieceTheme = pieceThemes[profile ? profile['piece_set'] : 'wikipedia'];
+    if (board) {
+        board = ChessBoard('board', $.extend({}, cfg, { position: board.position(), orientation: cfg.orientation }));
+    }
+}
+
+// reconnect opens a new websocket so the server sees the new identity
+function reconnect() {
+    conn.close();
+    conn = initWebsocket();
+}
+
+document.getElementById('login-btn').addEventListener('click', function(evt) {
+    sessionRequest('/login', {
+        'username': document.getElementById('username').value,
+        'password': document.getElementById('password').value,
+    }, reconnect);
+    return false;
+});
+
+document.getElementById('register-btn').addEventListener('click', function(evt) {
+    // Games played anonymously so far are kept
+    sessionRequest('/register', {
+        'username': document.getElementById('username').value,
+        'password': document.getElementById('password').value,
+        'token': player.token,
+    }, reconnect);
+    return false;
+});
+
+document.getElementById('logout-btn').addEventListener('click', function(evt) {
+    sessionRequest('/logout', {}, reconnect);
+    return false;
+});
+
+document.getElementById('profile-btn').addEventListener('click', function(evt) {
+    $.ajax({
+        type: 'PUT',
+        url: '/profile',
+        contentType: 'application/json',
+        data: JSON.stringify({
+            'display_name': document.getElementById('display-name').value,
+            'country': document.getElementById('country').value.toUpperCase(),
+            'piece_set': document.getElementById('piece-set').value,
+        }),
+        dataType: 'json',
+        headers: { 'Authorization': 'Bearer ' + player.token },
+        success: setProfile,
+        error: function(xhr) {
+            appendLog(escapeHTML(xhr.responseText));
+        },
+    });
+    return false;
+});
+
 function appendLog(msg) {
     console.log(msg);
     log.innerHTML += '' + msg + '\n';
@@ -175,7 +677,7 @@ function getOrientation() {
     // Second, look at radio buttons
     var radios = document.getElementsByName('orientation')
     for (var i = 0, length = radios.length; i < length; i++) {
-        if (radios[i].checked) {
+        if (radios[i].checked && radios[i].value) {
             return radios[i].value;
         }
     }
@@ -187,6 +689,18 @@ function getOrientation() {
     return 'white';
 }
 
+// getOrientationPreference returns the color chosen by the player, empty if
+// they do not mind playing either
+function getOrientationPreference() {
+    var radios = document.getElementsByName('orientation')
+    for (var i = 0, length = radios.length; i < length; i++) {
+        if (radios[i].checked) {
+            return radios[i].value;
+        }
+    }
+    return '';
+}
+
 function setOrientation(orientation) {
     cfg.orientation = orientation;
 
@@ -224,5 +738,7 @@ window.onload = function() {
         return;
     }
 
-    conn = initWebsocket();
+    startSession(function() {
+        conn = initWebsocket();
+    });
 };
\ No newline at end of file
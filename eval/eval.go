@@ -0,0 +1,202 @@
+// Package eval implements a static evaluation of chess positions. Every term
+// is scored separately for the middlegame and the endgame and the two are
+// blended (tapered) by the amount of material left on the board.
+package eval
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/RichardKnop/chess-engine/chess"
+)
+
+// Score is a pair of middlegame and endgame values in centipawns
+type Score struct {
+	MG int
+	EG int
+}
+
+func (s *Score) add(o Score) {
+	s.MG += o.MG
+	s.EG += o.EG
+}
+
+func (s *Score) addTimes(o Score, n int) {
+	s.MG += o.MG * n
+	s.EG += o.EG * n
+}
+
+// Term is a single component of the evaluation
+type Term int
+
+const (
+	// Material is the sum of piece values
+	Material Term = iota
+	// PieceSquares rewards pieces standing on good squares
+	PieceSquares
+	// Mobility rewards pieces with many squares to go to
+	Mobility
+	// PawnStructure penalizes doubled and isolated pawns and rewards passed pawns
+	PawnStructure
+	// KingSafety rewards pawn shields and penalizes attacks on the king zone
+	KingSafety
+	// BishopPair rewards having both bishops
+	BishopPair
+
+	// NumTerms is the number of evaluation terms
+	NumTerms
+)
+
+var termNames = [NumTerms]string{"Material", "Piece squares", "Mobility", "Pawn structure", "King safety", "Bishop pair"}
+
+// String returns a human readable name of the term
+func (t Term) String() string {
+	return termNames[t]
+}
+
+// Breakdown holds every term of an evaluation for both colors
+type Breakdown struct {
+	// Phase goes from 24 with all pieces on the board down to 0
+	Phase int
+	Terms [NumTerms][2]Score
+	// Score is the total from white's point of view
+	Score int
+}
+
+// Term returns a tapered value of a term from white's point of view
+func (b *Breakdown) Term(t Term) int {
+	var s Score
+	s.add(b.Terms[t][chess.White])
+	s.addTimes(b.Terms[t][chess.Black], -1)
+	return taper(s, b.Phase)
+}
+
+// String formats the breakdown as a table
+func (b *Breakdown) String() string {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%-15s | %11s | %11s | %6s\n", "Term", "White MG EG", "Black MG EG", "Total")
+	for t := Term(0); t < NumTerms; t++ {
+		w, bl := b.Terms[t][chess.White], b.Terms[t][chess.Black]
+		fmt.Fprintf(&buf, "%-15s | %5d %5d | %5d %5d | %6d\n", t, w.MG, w.EG, bl.MG, bl.EG, b.Term(t))
+	}
+	fmt.Fprintf(&buf, "Phase %d/%d, total %d (white's point of view)\n", b.Phase, maxPhase, b.Score)
+	return buf.String()
+}
+
+// Evaluate returns a score in centipawns from the point of view of the side
+// to move
+func Evaluate(p *chess.Position) int {
+	var b Breakdown
+	evaluate(p, &b)
+	if p.Turn() == chess.Black {
+		return -b.Score
+	}
+	return b.Score
+}
+
+// Explain returns all terms of the evaluation, which is useful to debug why
+// a position scores the way it does
+func Explain(p *chess.Position) *Breakdown {
+	b := new(Breakdown)
+	evaluate(p, b)
+	return b
+}
+
+func evaluate(p *chess.Position, b *Breakdown) {
+	occupied := p.Occupied(chess.White) | p.Occupied(chess.Black)
+
+	for t := chess.Knight; t <= chess.Queen; t++ {
+		b.Phase += phaseWeights[t] * (p.Pieces(chess.White, t) | p.Pieces(chess.Black, t)).Count()
+	}
+	if b.Phase > maxPhase {
+		// Early promotions can push the phase above the maximum
+		b.Phase = maxPhase
+	}
+
+	for _, c := range [2]chess.Color{chess.White, chess.Black} {
+		them := c.Other()
+		enemyPawnAttacks := pawnAttackSet(them, p.Pieces(them, chess.Pawn))
+		enemyKingZone := kingZone(p.KingSquare(them))
+		kingDanger := 0
+
+		for t := chess.Pawn; t <= chess.King; t++ {
+			for pieces := p.Pieces(c, t); pieces != 0; {
+				sq := pieces.PopFirst()
+				b.Terms[Material][c].add(pieceValues[t])
+				b.Terms[PieceSquares][c].add(pieceSquares[t][relativeSquare(c, sq)])
+
+				if t == chess.Pawn || t == chess.King {
+					continue
+				}
+
+				var attacks chess.Bitboard
+				if t == chess.Knight {
+					attacks = chess.KnightAttacks(sq)
+				} else {
+					attacks = chess.SliderAttacks(t, sq, occupied)
+				}
+
+				squares := (attacks &^ p.Occupied(c) &^ enemyPawnAttacks).Count()
+				b.Terms[Mobility][c].addTimes(mobilityWeights[t], squares-mobilityBaseline[t])
+				kingDanger += kingAttackWeights[t] * (attacks & enemyKingZone).Count()
+			}
+		}
+
+		b.Terms[PawnStructure][c].add(pawnStructure(p, c))
+		b.Terms[KingSafety][c].add(kingShelter(p, c))
+		// Attacks on the enemy king count against the enemy
+		danger := kingDanger * kingDanger / 4
+		if danger > maxKingDanger {
+			danger = maxKingDanger
+		}
+		if p.Pieces(c, chess.Queen) != 0 {
+			b.Terms[KingSafety][them].MG -= danger
+		}
+
+		if p.Pieces(c, chess.Bishop).Count() >= 2 {
+			b.Terms[BishopPair][c].add(bishopPair)
+		}
+	}
+
+	for t := Term(0); t < NumTerms; t++ {
+		b.Score += b.Term(t)
+	}
+}
+
+// pawnStructure scores doubled, isolated and passed pawns of a color
+func pawnStructure(p *chess.Position, c chess.Color) Score {
+	var s Score
+	pawns := p.Pieces(c, chess.Pawn)
+	enemyPawns := p.Pieces(c.Other(), chess.Pawn)
+
+	for file := 0; file < 8; file++ {
+		count := (pawns & fileMasks[file]).Count()
+		if count > 1 {
+			s.addTimes(doubledPawn, count-1)
+		}
+		if count > 0 && pawns&adjacentFiles[file] == 0 {
+			s.addTimes(isolatedPawn, count)
+		}
+	}
+
+	for b := pawns; b != 0; {
+		sq := b.PopFirst()
+		if enemyPawns&passedMasks[c][sq] == 0 {
+			s.add(passedPawn[relativeSquare(c, sq).Rank()])
+		}
+	}
+
+	return s
+}
+
+// kingShelter rewards own pawns in front of a castled king
+func kingShelter(p *chess.Position, c chess.Color) Score {
+	var s Score
+	king := p.KingSquare(c)
+	if relativeSquare(c, king).Rank() > 1 {
+		return s
+	}
+	shield := p.Pieces(c, chess.Pawn) & shieldMasks[c][king]
+	s.addTimes(pawnShield, shield.Count())
+	return s
+}
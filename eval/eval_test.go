@@ -0,0 +1,126 @@
+package eval_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/RichardKnop/chess-engine/chess"
+	"github.com/RichardKnop/chess-engine/eval"
+	"github.com/RichardKnop/chess-engine/fen"
+)
+
+var evalTestPositions = []string{
+	fen.Initial,
+	"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+	"r4rk1/1pp1qppp/p1np1n2/2b1p1B1/2B1P1b1/P1NP1N2/1PP1QPPP/R4RK1 w - - 0 10",
+	"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1",
+	"4k3/8/8/3q4/8/8/8/3RK3 b - - 0 1",
+}
+
+// mirror flips the board vertically and swaps colors
+func mirror(s string) string {
+	fields := strings.Fields(s)
+	ranks := strings.Split(fields[0], "/")
+	for i, j := 0, len(ranks)-1; i < j; i, j = i+1, j-1 {
+		ranks[i], ranks[j] = ranks[j], ranks[i]
+	}
+	fields[0] = swapCase(strings.Join(ranks, "/"))
+
+	if fields[1] == "w" {
+		fields[1] = "b"
+	} else {
+		fields[1] = "w"
+	}
+	if fields[2] != "-" {
+		fields[2] = swapCase(fields[2])
+	}
+	if fields[3] != "-" {
+		rank := '3'
+		if fields[3][1] == '3' {
+			rank = '6'
+		}
+		fields[3] = fields[3][:1] + string(rank)
+	}
+	return strings.Join(fields, " ")
+}
+
+func swapCase(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z':
+			return r - 'A' + 'a'
+		}
+		return r
+	}, s)
+}
+
+func TestEvaluateSymmetry(t *testing.T) {
+	for _, s := range evalTestPositions {
+		p, err := fen.Parse(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		mirrored, err := fen.Parse(mirror(s))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if a, b := eval.Evaluate(p), eval.Evaluate(mirrored); a != b {
+			t.Errorf("%s: evaluated %d, mirrored position evaluated %d", s, a, b)
+		}
+	}
+}
+
+func TestEvaluateInitialPosition(t *testing.T) {
+	p, err := fen.Parse(fen.Initial)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := eval.Explain(p)
+	if b.Phase != 24 {
+		t.Errorf("Phase = %d, want 24", b.Phase)
+	}
+	if b.Score != 0 {
+		t.Errorf("Score = %d, want 0\n%s", b.Score, b)
+	}
+}
+
+func TestExplain(t *testing.T) {
+	for _, s := range evalTestPositions {
+		p, err := fen.Parse(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		b := eval.Explain(p)
+		sum := 0
+		for term := eval.Term(0); term < eval.NumTerms; term++ {
+			sum += b.Term(term)
+		}
+		if sum != b.Score {
+			t.Errorf("%s: terms sum to %d, score is %d", s, sum, b.Score)
+		}
+
+		score := eval.Evaluate(p)
+		if p.Turn() == chess.Black {
+			score = -score
+		}
+		if score != b.Score {
+			t.Errorf("%s: Evaluate returned %d from white's point of view, Explain %d", s, score, b.Score)
+		}
+	}
+}
+
+func BenchmarkEvaluate(b *testing.B) {
+	p, err := fen.Parse(evalTestPositions[1])
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		eval.Evaluate(p)
+	}
+}
@@ -0,0 +1,78 @@
+package eval
+
+import (
+	"github.com/RichardKnop/chess-engine/chess"
+)
+
+var (
+	fileMasks     [8]chess.Bitboard
+	adjacentFiles [8]chess.Bitboard
+	// passedMasks are squares in front of a pawn on its own and adjacent
+	// files, a pawn is passed when no enemy pawn stands there
+	passedMasks [2][64]chess.Bitboard
+	// shieldMasks are two ranks in front of a king on its own and adjacent files
+	shieldMasks [2][64]chess.Bitboard
+)
+
+func init() {
+	for sq := chess.Square(0); sq < 64; sq++ {
+		fileMasks[sq.File()] |= chess.SquareBB(sq)
+	}
+	for file := 0; file < 8; file++ {
+		if file > 0 {
+			adjacentFiles[file] |= fileMasks[file-1]
+		}
+		if file < 7 {
+			adjacentFiles[file] |= fileMasks[file+1]
+		}
+	}
+
+	for sq := chess.Square(0); sq < 64; sq++ {
+		files := fileMasks[sq.File()] | adjacentFiles[sq.File()]
+		for other := chess.Square(0); other < 64; other++ {
+			if !files.Has(other) {
+				continue
+			}
+			if other.Rank() > sq.Rank() {
+				passedMasks[chess.White][sq] |= chess.SquareBB(other)
+				if other.Rank()-sq.Rank() <= 2 {
+					shieldMasks[chess.White][sq] |= chess.SquareBB(other)
+				}
+			}
+			if other.Rank() < sq.Rank() {
+				passedMasks[chess.Black][sq] |= chess.SquareBB(other)
+				if sq.Rank()-other.Rank() <= 2 {
+					shieldMasks[chess.Black][sq] |= chess.SquareBB(other)
+				}
+			}
+		}
+	}
+}
+
+// relativeSquare flips a square vertically for black so tables written from
+// white's point of view apply to both colors
+func relativeSquare(c chess.Color, sq chess.Square) chess.Square {
+	if c == chess.Black {
+		return sq ^ 56
+	}
+	return sq
+}
+
+// pawnAttackSet returns all squares attacked by pawns
+func pawnAttackSet(c chess.Color, pawns chess.Bitboard) chess.Bitboard {
+	var attacks chess.Bitboard
+	for pawns != 0 {
+		attacks |= chess.PawnAttacks(c, pawns.PopFirst())
+	}
+	return attacks
+}
+
+// kingZone is the king square and all squares around it
+func kingZone(sq chess.Square) chess.Bitboard {
+	return chess.KingAttacks(sq) | chess.SquareBB(sq)
+}
+
+// taper blends middlegame and endgame values by the game phase
+func taper(s Score, phase int) int {
+	return (s.MG*phase + s.EG*(maxPhase-phase)) / maxPhase
+}
@@ -0,0 +1,156 @@
+package eval
+
+import (
+	"github.com/RichardKnop/chess-engine/chess"
+)
+
+// Material values from PeSTO
+// See https://www.chessprogramming.org/PeSTO%27s_Evaluation_Function
+var pieceValues = [7]Score{
+	chess.Pawn:   {82, 94},
+	chess.Knight: {337, 281},
+	chess.Bishop: {365, 297},
+	chess.Rook:   {477, 512},
+	chess.Queen:  {1025, 936},
+}
+
+// phaseWeights tell how much each piece contributes to the game phase, with
+// all pieces on the board the phase is maxPhase (pure middlegame)
+var phaseWeights = [7]int{chess.Knight: 1, chess.Bishop: 1, chess.Rook: 2, chess.Queen: 4}
+
+const maxPhase = 24
+
+// Piece-square tables are written from white's point of view as the board is
+// displayed, so the first row is the eighth rank. They are based on the
+// Simplified Evaluation Function by Tomasz Michniewski.
+// See https://www.chessprogramming.org/Simplified_Evaluation_Function
+var (
+	pawnMG = [64]int{
+		0, 0, 0, 0, 0, 0, 0, 0,
+		50, 50, 50, 50, 50, 50, 50, 50,
+		10, 10, 20, 30, 30, 20, 10, 10,
+		5, 5, 10, 25, 25, 10, 5, 5,
+		0, 0, 0, 20, 20, 0, 0, 0,
+		5, -5, -10, 0, 0, -10, -5, 5,
+		5, 10, 10, -20, -20, 10, 10, 5,
+		0, 0, 0, 0, 0, 0, 0, 0,
+	}
+	pawnEG = [64]int{
+		0, 0, 0, 0, 0, 0, 0, 0,
+		80, 80, 80, 80, 80, 80, 80, 80,
+		50, 50, 50, 50, 50, 50, 50, 50,
+		30, 30, 30, 30, 30, 30, 30, 30,
+		20, 20, 20, 20, 20, 20, 20, 20,
+		10, 10, 10, 10, 10, 10, 10, 10,
+		10, 10, 10, 10, 10, 10, 10, 10,
+		0, 0, 0, 0, 0, 0, 0, 0,
+	}
+	knightTable = [64]int{
+		-50, -40, -30, -30, -30, -30, -40, -50,
+		-40, -20, 0, 0, 0, 0, -20, -40,
+		-30, 0, 10, 15, 15, 10, 0, -30,
+		-30, 5, 15, 20, 20, 15, 5, -30,
+		-30, 0, 15, 20, 20, 15, 0, -30,
+		-30, 5, 10, 15, 15, 10, 5, -30,
+		-40, -20, 0, 5, 5, 0, -20, -40,
+		-50, -40, -30, -30, -30, -30, -40, -50,
+	}
+	bishopTable = [64]int{
+		-20, -10, -10, -10, -10, -10, -10, -20,
+		-10, 0, 0, 0, 0, 0, 0, -10,
+		-10, 0, 5, 10, 10, 5, 0, -10,
+		-10, 5, 5, 10, 10, 5, 5, -10,
+		-10, 0, 10, 10, 10, 10, 0, -10,
+		-10, 10, 10, 10, 10, 10, 10, -10,
+		-10, 5, 0, 0, 0, 0, 5, -10,
+		-20, -10, -10, -10, -10, -10, -10, -20,
+	}
+	rookMG = [64]int{
+		0, 0, 0, 0, 0, 0, 0, 0,
+		5, 10, 10, 10, 10, 10, 10, 5,
+		-5, 0, 0, 0, 0, 0, 0, -5,
+		-5, 0, 0, 0, 0, 0, 0, -5,
+		-5, 0, 0, 0, 0, 0, 0, -5,
+		-5, 0, 0, 0, 0, 0, 0, -5,
+		-5, 0, 0, 0, 0, 0, 0, -5,
+		0, 0, 0, 5, 5, 0, 0, 0,
+	}
+	rookEG     = [64]int{}
+	queenTable = [64]int{
+		-20, -10, -10, -5, -5, -10, -10, -20,
+		-10, 0, 0, 0, 0, 0, 0, -10,
+		-10, 0, 5, 5, 5, 5, 0, -10,
+		-5, 0, 5, 5, 5, 5, 0, -5,
+		0, 0, 5, 5, 5, 5, 0, -5,
+		-10, 5, 5, 5, 5, 5, 0, -10,
+		-10, 0, 5, 0, 0, 0, 0, -10,
+		-20, -10, -10, -5, -5, -10, -10, -20,
+	}
+	kingMG = [64]int{
+		-30, -40, -40, -50, -50, -40, -40, -30,
+		-30, -40, -40, -50, -50, -40, -40, -30,
+		-30, -40, -40, -50, -50, -40, -40, -30,
+		-30, -40, -40, -50, -50, -40, -40, -30,
+		-20, -30, -30, -40, -40, -30, -30, -20,
+		-10, -20, -20, -20, -20, -20, -20, -10,
+		20, 20, 0, 0, 0, 0, 20, 20,
+		20, 30, 10, 0, 0, 10, 30, 20,
+	}
+	kingEG = [64]int{
+		-50, -40, -30, -20, -20, -30, -40, -50,
+		-30, -20, -10, 0, 0, -10, -20, -30,
+		-30, -10, 20, 30, 30, 20, -10, -30,
+		-30, -10, 30, 40, 40, 30, -10, -30,
+		-30, -10, 30, 40, 40, 30, -10, -30,
+		-30, -10, 20, 30, 30, 20, -10, -30,
+		-30, -30, 0, 0, 0, 0, -30, -30,
+		-50, -30, -30, -30, -30, -30, -30, -50,
+	}
+)
+
+// pieceSquares combines middlegame and endgame tables indexed by piece type
+// and square from white's point of view (a1 is 0)
+var pieceSquares [7][64]Score
+
+func init() {
+	tables := [7][2]*[64]int{
+		chess.Pawn:   {&pawnMG, &pawnEG},
+		chess.Knight: {&knightTable, &knightTable},
+		chess.Bishop: {&bishopTable, &bishopTable},
+		chess.Rook:   {&rookMG, &rookEG},
+		chess.Queen:  {&queenTable, &queenTable},
+		chess.King:   {&kingMG, &kingEG},
+	}
+	for t := chess.Pawn; t <= chess.King; t++ {
+		for sq := chess.Square(0); sq < 64; sq++ {
+			// Tables list the eighth rank first
+			i := (7-sq.Rank())*8 + sq.File()
+			pieceSquares[t][sq] = Score{tables[t][0][i], tables[t][1][i]}
+		}
+	}
+}
+
+// Mobility bonus per reachable square and number of squares considered normal
+var (
+	mobilityWeights  = [7]Score{chess.Knight: {4, 4}, chess.Bishop: {5, 5}, chess.Rook: {2, 4}, chess.Queen: {1, 2}}
+	mobilityBaseline = [7]int{chess.Knight: 4, chess.Bishop: 7, chess.Rook: 7, chess.Queen: 14}
+)
+
+// Pawn structure
+var (
+	doubledPawn  = Score{-10, -20}
+	isolatedPawn = Score{-10, -15}
+	// Passed pawn bonus by rank relative to the pawn's color
+	passedPawn = [8]Score{{0, 0}, {5, 10}, {10, 20}, {15, 35}, {25, 60}, {40, 90}, {60, 130}, {0, 0}}
+)
+
+// King safety
+var (
+	pawnShield = Score{10, 0}
+	// Attack units per square of the king zone attacked by a piece type
+	kingAttackWeights = [7]int{chess.Knight: 2, chess.Bishop: 2, chess.Rook: 3, chess.Queen: 5}
+	maxKingDanger     = 500
+)
+
+// Bishop pair
+var bishopPair = Score{30, 50}
@@ -0,0 +1,57 @@
+package fen
+
+import (
+	"fmt"
+)
+
+// FieldCountError represents a custom error
+type FieldCountError struct {
+	count int
+}
+
+// Error implements the error interface
+func (e FieldCountError) Error() string {
+	return fmt.Sprintf("FEN must have 4 or 6 fields, got %d", e.count)
+}
+
+// NewFieldCountError creates a new instance of FieldCountError
+func NewFieldCountError(count int) *FieldCountError {
+	return &FieldCountError{count: count}
+}
+
+// InvalidFieldError represents a custom error
+type InvalidFieldError struct {
+	field string
+	value string
+}
+
+// Error implements the error interface
+func (e InvalidFieldError) Error() string {
+	return fmt.Sprintf("Invalid FEN %s: %s", e.field, e.value)
+}
+
+// NewInvalidFieldError creates a new instance of InvalidFieldError
+func NewInvalidFieldError(field, value string) *InvalidFieldError {
+	return &InvalidFieldError{field: field, value: value}
+}
+
+// InvalidPositionError represents a custom error
+type InvalidPositionError struct {
+	fen string
+	err error
+}
+
+// Error implements the error interface
+func (e InvalidPositionError) Error() string {
+	return fmt.Sprintf("Invalid FEN position %s: %v", e.fen, e.err)
+}
+
+// Unwrap returns the underlying validation error
+func (e InvalidPositionError) Unwrap() error {
+	return e.err
+}
+
+// NewInvalidPositionError creates a new instance of InvalidPositionError
+func NewInvalidPositionError(fen string, err error) *InvalidPositionError {
+	return &InvalidPositionError{fen: fen, err: err}
+}
@@ -0,0 +1,168 @@
+// Package fen parses and serializes positions in Forsyth-Edwards Notation
+// See https://en.wikipedia.org/wiki/Forsyth%E2%80%93Edwards_Notation
+package fen
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+
+	"github.com/RichardKnop/chess-engine/chess"
+)
+
+// Initial is FEN of the standard starting position
+const Initial = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+
+// Parse parses a FEN string into a position. The halfmove clock and fullmove
+// number fields are optional and default to 0 and 1.
+func Parse(s string) (*chess.Position, error) {
+	fields := strings.Fields(s)
+	if len(fields) != 4 && len(fields) != 6 {
+		return nil, NewFieldCountError(len(fields))
+	}
+
+	setup := &chess.Setup{FullmoveNumber: 1}
+
+	if err := parsePlacement(fields[0], &setup.Board); err != nil {
+		return nil, err
+	}
+
+	switch fields[1] {
+	case "w":
+		setup.Turn = chess.White
+	case "b":
+		setup.Turn = chess.Black
+	default:
+		return nil, NewInvalidFieldError("active color", fields[1])
+	}
+
+	castling, err := parseCastling(fields[2])
+	if err != nil {
+		return nil, err
+	}
+	setup.Castling = castling
+
+	setup.EnPassant = chess.NoSquare
+	if fields[3] != "-" {
+		if setup.EnPassant, err = chess.ParseSquare(fields[3]); err != nil {
+			return nil, NewInvalidFieldError("en passant square", fields[3])
+		}
+	}
+
+	if len(fields) == 6 {
+		if setup.HalfmoveClock, err = strconv.Atoi(fields[4]); err != nil {
+			return nil, NewInvalidFieldError("halfmove clock", fields[4])
+		}
+		if setup.FullmoveNumber, err = strconv.Atoi(fields[5]); err != nil {
+			return nil, NewInvalidFieldError("fullmove number", fields[5])
+		}
+	}
+
+	p, err := chess.NewPositionFromSetup(setup)
+	if err != nil {
+		return nil, NewInvalidPositionError(s, err)
+	}
+
+	return p, nil
+}
+
+// Encode serializes a position into a FEN string
+func Encode(p *chess.Position) string {
+	var buf bytes.Buffer
+
+	buf.WriteString(p.Placement())
+
+	if p.Turn() == chess.White {
+		buf.WriteString(" w ")
+	} else {
+		buf.WriteString(" b ")
+	}
+
+	buf.WriteString(encodeCastling(p.Castling()))
+	buf.WriteByte(' ')
+	buf.WriteString(p.EnPassant().String())
+	buf.WriteByte(' ')
+	buf.WriteString(strconv.Itoa(p.HalfmoveClock()))
+	buf.WriteByte(' ')
+	buf.WriteString(strconv.Itoa(p.FullmoveNumber()))
+
+	return buf.String()
+}
+
+// parsePlacement parses the piece placement field into a board
+func parsePlacement(placement string, board *[64]chess.Piece) error {
+	ranks := strings.Split(placement, "/")
+	if len(ranks) != 8 {
+		return NewInvalidFieldError("piece placement", placement)
+	}
+
+	for i, row := range ranks {
+		rank, file := 7-i, 0
+		for j := 0; j < len(row); j++ {
+			c := row[j]
+			if c >= '1' && c <= '8' {
+				file += int(c - '0')
+				continue
+			}
+			piece, err := chess.ParsePiece(c)
+			if err != nil || file > 7 {
+				return NewInvalidFieldError("piece placement", placement)
+			}
+			board[chess.NewSquare(file, rank)] = piece
+			file++
+		}
+		if file != 8 {
+			return NewInvalidFieldError("piece placement", placement)
+		}
+	}
+
+	return nil
+}
+
+var castlingChars = []struct {
+	c      byte
+	rights chess.CastlingRights
+}{
+	{'K', chess.WhiteKingSide},
+	{'Q', chess.WhiteQueenSide},
+	{'k', chess.BlackKingSide},
+	{'q', chess.BlackQueenSide},
+}
+
+// parseCastling parses the castling availability field
+func parseCastling(s string) (chess.CastlingRights, error) {
+	if s == "-" {
+		return chess.NoCastling, nil
+	}
+
+	rights := chess.NoCastling
+	for i := 0; i < len(s); i++ {
+		found := false
+		for _, cc := range castlingChars {
+			if cc.c == s[i] && rights&cc.rights == 0 {
+				rights |= cc.rights
+				found = true
+			}
+		}
+		if !found {
+			return chess.NoCastling, NewInvalidFieldError("castling availability", s)
+		}
+	}
+
+	return rights, nil
+}
+
+// encodeCastling serializes castling rights
+func encodeCastling(rights chess.CastlingRights) string {
+	if rights == chess.NoCastling {
+		return "-"
+	}
+
+	var buf bytes.Buffer
+	for _, cc := range castlingChars {
+		if rights&cc.rights != 0 {
+			buf.WriteByte(cc.c)
+		}
+	}
+	return buf.String()
+}
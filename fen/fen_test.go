@@ -0,0 +1,85 @@
+package fen_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/RichardKnop/chess-engine/chess"
+	"github.com/RichardKnop/chess-engine/fen"
+)
+
+func TestRoundTrip(t *testing.T) {
+	testCases := []struct {
+		name string
+		fen  string
+	}{
+		{"initial", fen.Initial},
+		{"en passant", "rnbqkbnr/ppp1p1pp/8/3pPp2/8/8/PPPP1PPP/RNBQKBNR w KQkq f6 0 3"},
+		{"castling rights", "r3k2r/8/8/8/8/8/8/R3K2R b Kq - 5 20"},
+	}
+	for _, tc := range testCases {
+		p, err := fen.Parse(tc.fen)
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if s := fen.Encode(p); s != tc.fen {
+			t.Errorf("%s: encoded %s, want %s", tc.name, s, tc.fen)
+		}
+	}
+}
+
+func TestParseOptionalFields(t *testing.T) {
+	p, err := fen.Parse("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq -")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := fen.Encode(p); s != fen.Initial {
+		t.Errorf("encoded %s, want %s", s, fen.Initial)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	testCases := []struct {
+		name  string
+		fen   string
+		check func(err error) bool
+	}{
+		{"too few fields", "8/8/8/8/8/8/8/8 w", isFieldCountError},
+		{"five fields", fen.Initial[:len(fen.Initial)-2], isFieldCountError},
+		{"seven rows", "rnbqkbnr/pppppppp/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", isInvalidFieldError},
+		{"too many files", "rnbqkbnr/pppppppp/9/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", isInvalidFieldError},
+		{"unknown piece", "rnbqkbnr/pppppppp/8/8/4X3/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", isInvalidFieldError},
+		{"active color", "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR x KQkq - 0 1", isInvalidFieldError},
+		{"repeated castling", "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KKq - 0 1", isInvalidFieldError},
+		{"en passant square", "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq z9 0 1", isInvalidFieldError},
+		{"halfmove clock", "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - x 1", isInvalidFieldError},
+		{"fullmove number", "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 x", isInvalidFieldError},
+		{"two white kings", "4k3/8/8/8/8/8/8/3KK3 w - - 0 1", isInvalidPositionError(chess.ErrKingCount)},
+		{"pawn on back rank", "P3k3/8/8/8/8/8/8/4K3 w - - 0 1", isInvalidPositionError(chess.ErrPawnOnBackRank)},
+		{"side not to move in check", "4k3/8/8/8/4R3/8/8/4K3 w - - 0 1", isInvalidPositionError(chess.ErrOpponentInCheck)},
+	}
+	for _, tc := range testCases {
+		_, err := fen.Parse(tc.fen)
+		if !tc.check(err) {
+			t.Errorf("%s: parsing %q returned %v", tc.name, tc.fen, err)
+		}
+	}
+}
+
+func isFieldCountError(err error) bool {
+	_, ok := err.(*fen.FieldCountError)
+	return ok
+}
+
+func isInvalidFieldError(err error) bool {
+	_, ok := err.(*fen.InvalidFieldError)
+	return ok
+}
+
+func isInvalidPositionError(cause error) func(err error) bool {
+	return func(err error) bool {
+		_, ok := err.(*fen.InvalidPositionError)
+		return ok && errors.Is(err, cause)
+	}
+}
@@ -1,14 +1,29 @@
 package main
 
 import (
+	"flag"
+	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"os"
+	"strings"
+	"time"
 
+	"github.com/RichardKnop/chess-engine/auth"
 	"github.com/RichardKnop/chess-engine/server"
+	"github.com/RichardKnop/chess-engine/uci"
 	"github.com/gorilla/websocket"
 )
 
-var engine *server.Engine
+// sessionTTL is how long a session token identifies a player
+const sessionTTL = 30 * 24 * time.Hour
+
+var (
+	engine *server.Engine
+	store  server.Store
+	signer *auth.Signer
+)
 
 var upgrader = websocket.Upgrader{
 	ReadBufferSize:  1024,
@@ -16,14 +31,58 @@ var upgrader = websocket.Upgrader{
 }
 
 func main() {
-	engine = server.NewEngine()
+	// Speak UCI over stdin/stdout instead of running the websocket server
+	if len(os.Args) > 1 && os.Args[1] == "uci" {
+		if err := uci.New(os.Stdin, os.Stdout).Run(); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	dataDir := flag.String("data", "data", "Directory where games and accounts are stored, they are kept in memory only if empty")
+	secret := flag.String("secret", "", "Key signing session tokens, a random key is used if empty")
+	flag.Parse()
+
+	key := []byte(*secret)
+	if len(key) == 0 {
+		var err error
+		if key, err = auth.GenerateKey(); err != nil {
+			log.Fatal(err)
+		}
+		log.Print("Using a random session key, players get new identities after a restart")
+	}
+	signer = auth.NewSigner(key, sessionTTL)
+
+	store = server.NewMemoryStore()
+	if *dataDir != "" {
+		fileStore, err := server.NewFileStore(*dataDir)
+		if err != nil {
+			log.Fatal(err)
+		}
+		store = fileStore
+	}
+
+	engine = server.NewEngine(store)
 
 	// Start the engine
 	go engine.Run()
 
+	// Session tokens identifying players and player accounts
+	http.HandleFunc("/session", sessionHandler)
+	http.HandleFunc("/register", registerHandler)
+	http.HandleFunc("/login", loginHandler)
+	http.HandleFunc("/logout", logoutHandler)
+	http.HandleFunc("/profile", profileHandler)
+
 	// Web sockets handler
 	http.HandleFunc("/ws", wsHandler)
 
+	// Games exported in PGN
+	http.HandleFunc("/games/", pgnHandler)
+
+	// Ratings and rating history of players
+	http.HandleFunc("/ratings/", ratingsHandler)
+
 	// Serving static files from public directory
 	http.Handle("/", http.StripPrefix("/", http.FileServer(http.Dir("./client"))))
 
@@ -33,6 +92,18 @@ func main() {
 }
 
 func wsHandler(w http.ResponseWriter, r *http.Request) {
+	// Browsers cannot set headers of websocket requests so the session
+	// token is passed in the query string
+	claims, account, err := authenticate(r.URL.Query().Get("token"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	var name string
+	if account != nil {
+		name = account.DisplayName
+	}
+
 	// Open a websocket connection
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
@@ -40,16 +111,13 @@ func wsHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Register the client connection with the engine
-	client := engine.NewClient(conn)
+	client := engine.NewClient(conn, claims.PlayerID, name)
 
+	// The client is disconnected from games once reading fails, reading is
+	// not retried as the connection cannot recover
 	go func() {
-		var loop = true
-		for loop {
-			if err := client.ReadPump(); err != nil {
-				log.Print("Read pump error: ", err)
-			} else {
-				loop = false
-			}
+		if err := client.ReadPump(); err != nil {
+			log.Print("Read pump error: ", err)
 		}
 	}()
 
@@ -59,3 +127,63 @@ func wsHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}()
 }
+
+// pgnHandler serves games as /games/{id}.pgn, private games also need the
+// session token of a player or the invite code which are passed in the query
+// string so the export can be downloaded with a plain link
+func pgnHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var playerID string
+	if token := r.URL.Query().Get("token"); token != "" {
+		claims, _, err := authenticate(token)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		playerID = claims.PlayerID
+	}
+
+	name := strings.TrimPrefix(r.URL.Path, "/games/")
+	if !strings.HasSuffix(name, ".pgn") {
+		http.NotFound(w, r)
+		return
+	}
+
+	data, err := engine.PGN(strings.TrimSuffix(name, ".pgn"), playerID, r.URL.Query().Get("invite"))
+	if _, ok := err.(*server.GameNotFoundError); ok {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-chess-pgn")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	io.WriteString(w, data)
+}
+
+// ratingsHandler serves ratings of a player as /ratings/{player_id}
+func ratingsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ratings, err := store.LoadRatings(strings.TrimPrefix(r.URL.Path, "/ratings/"))
+	if _, ok := err.(*server.PlayerNotFoundError); ok {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, ratings)
+}
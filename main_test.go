@@ -0,0 +1,134 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RichardKnop/chess-engine/auth"
+	"github.com/RichardKnop/chess-engine/server"
+)
+
+// setUp replaces the engine, store and signer used by the handlers with
+// fresh ones keeping everything in memory
+func setUp(t *testing.T) {
+	key, err := auth.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer = auth.NewSigner(key, sessionTTL)
+	store = server.NewMemoryStore()
+	engine = server.NewEngine(store)
+}
+
+// serve passes a request to a handler and returns the recorded response
+func serve(handler http.HandlerFunc, method, target, token, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	handler(w, r)
+	return w
+}
+
+// decodeSession decodes a session from a response, the test fails unless
+// the request succeeded
+func decodeSession(t *testing.T, w *httptest.ResponseRecorder) *session {
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body.String())
+	}
+	s := new(session)
+	if err := json.NewDecoder(w.Body).Decode(s); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestSessionHandler(t *testing.T) {
+	setUp(t)
+
+	anonymous := decodeSession(t, serve(sessionHandler, http.MethodPost, "/session", "", "{}"))
+	if anonymous.PlayerID == "" || anonymous.Token == "" || anonymous.Profile != nil {
+		t.Fatalf("anonymous session %+v", anonymous)
+	}
+
+	// A valid token keeps the player ID
+	refreshed := decodeSession(t, serve(sessionHandler, http.MethodPost, "/session", "", `{"token":"`+anonymous.Token+`"}`))
+	if refreshed.PlayerID != anonymous.PlayerID {
+		t.Errorf("refreshed session of %s belongs to %s", anonymous.PlayerID, refreshed.PlayerID)
+	}
+
+	// Invalid and expired tokens start a new anonymous session
+	expired, err := signer.Issue(anonymous.PlayerID, 0, time.Now().Add(-2*sessionTTL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, token := range []string{"garbage", expired} {
+		s := decodeSession(t, serve(sessionHandler, http.MethodPost, "/session", "", `{"token":"`+token+`"}`))
+		if s.PlayerID == anonymous.PlayerID {
+			t.Errorf("token %q kept the player ID", token)
+		}
+	}
+
+	if w := serve(sessionHandler, http.MethodGet, "/session", "", ""); w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET returned status %d", w.Code)
+	}
+}
+
+func TestPGNHandler(t *testing.T) {
+	setUp(t)
+
+	for _, r := range []*server.GameRecord{
+		{ID: "public", InitialPosition: server.InitialPosition, WhiteID: "alice", WhiteName: "Alice", BlackID: "bob", BlackName: "Bob"},
+		{ID: "private", InitialPosition: server.InitialPosition, WhiteID: "alice", WhiteName: "Alice", Private: true, InviteCode: "secret"},
+	} {
+		r.CreatedAt, r.UpdatedAt = time.Now(), time.Now()
+		if err := store.Save(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	alice, err := signer.Issue("alice", 0, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	eve, err := signer.Issue("eve", 0, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name   string
+		target string
+		status int
+	}{
+		{"public game", "/games/public.pgn", http.StatusOK},
+		{"missing game", "/games/missing.pgn", http.StatusNotFound},
+		{"not a PGN file", "/games/public", http.StatusNotFound},
+		{"invalid token", "/games/public.pgn?token=garbage", http.StatusUnauthorized},
+		{"private game", "/games/private.pgn", http.StatusNotFound},
+		{"private game of another player", "/games/private.pgn?token=" + eve, http.StatusNotFound},
+		{"wrong invite code", "/games/private.pgn?invite=guess", http.StatusNotFound},
+		{"invite code", "/games/private.pgn?invite=secret", http.StatusOK},
+		{"player of the private game", "/games/private.pgn?token=" + alice, http.StatusOK},
+	}
+	for _, tc := range testCases {
+		w := serve(pgnHandler, http.MethodGet, tc.target, "", "")
+		if w.Code != tc.status {
+			t.Errorf("%s: status %d, want %d", tc.name, w.Code, tc.status)
+			continue
+		}
+		if tc.status != http.StatusOK {
+			continue
+		}
+		if ct := w.Header().Get("Content-Type"); ct != "application/x-chess-pgn" {
+			t.Errorf("%s: content type %s", tc.name, ct)
+		}
+		if !strings.Contains(w.Body.String(), `[White "Alice"]`) {
+			t.Errorf("%s: downloaded %s", tc.name, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package pgn
+
+import (
+	"fmt"
+)
+
+// SyntaxError represents a custom error
+type SyntaxError struct {
+	line  int
+	token string
+}
+
+// Error implements the error interface
+func (e SyntaxError) Error() string {
+	return fmt.Sprintf("PGN syntax error on line %d near %q", e.line, e.token)
+}
+
+// NewSyntaxError creates a new instance of SyntaxError
+func NewSyntaxError(line int, token string) *SyntaxError {
+	return &SyntaxError{line: line, token: token}
+}
+
+// InvalidMoveError represents a custom error
+type InvalidMoveError struct {
+	san    string
+	reason string
+}
+
+// Error implements the error interface
+func (e InvalidMoveError) Error() string {
+	return fmt.Sprintf("Invalid move %s: %s", e.san, e.reason)
+}
+
+// NewInvalidMoveError creates a new instance of InvalidMoveError
+func NewInvalidMoveError(san, reason string) *InvalidMoveError {
+	return &InvalidMoveError{san: san, reason: reason}
+}
@@ -0,0 +1,277 @@
+package pgn
+
+import (
+	"strings"
+
+	"github.com/RichardKnop/chess-engine/chess"
+	"github.com/RichardKnop/chess-engine/fen"
+)
+
+type tokenType int
+
+const (
+	tokenEOF tokenType = iota
+	tokenTag
+	tokenComment
+	tokenSymbol
+	tokenPeriod
+	tokenNAG
+	tokenVariationStart
+	tokenVariationEnd
+)
+
+type token struct {
+	typ   tokenType
+	value string
+	// Tags have a name and a value
+	name string
+	line int
+}
+
+// Parse reads all games from PGN text. Moves of the main line are validated,
+// recursive variations, NAGs and comments outside the main line are skipped.
+func Parse(s string) ([]*Game, error) {
+	var (
+		games     []*Game
+		game      *Game
+		position  *chess.Position
+		variation int
+	)
+
+	start := func() {
+		game = &Game{Tags: make(map[string]string), Result: ResultUnknown}
+		position = chess.NewPosition()
+	}
+	finish := func() {
+		games = append(games, game)
+		game, position, variation = nil, nil, 0
+	}
+
+	l := &lexer{s: s, line: 1}
+	for {
+		t, err := l.next()
+		if err != nil {
+			return nil, err
+		}
+
+		if t.typ == tokenEOF {
+			if variation > 0 {
+				return nil, NewSyntaxError(t.line, "unterminated variation")
+			}
+			// Be lenient about a missing game termination marker
+			if game != nil {
+				finish()
+			}
+			return games, nil
+		}
+
+		if game == nil {
+			start()
+		}
+
+		switch t.typ {
+		case tokenTag:
+			if len(game.Moves) > 0 {
+				return nil, NewSyntaxError(t.line, "["+t.name)
+			}
+			switch t.name {
+			case "FEN":
+				if position, err = fen.Parse(t.value); err != nil {
+					return nil, err
+				}
+				game.Position = position
+			case "Result":
+				game.Result = t.value
+			case "SetUp":
+			default:
+				game.Tags[t.name] = t.value
+			}
+		case tokenComment:
+			if variation == 0 && len(game.Moves) > 0 {
+				last := len(game.Comments) - 1
+				game.Comments[last] = strings.TrimSpace(game.Comments[last] + " " + t.value)
+			}
+		case tokenVariationStart:
+			variation++
+		case tokenVariationEnd:
+			if variation == 0 {
+				return nil, NewSyntaxError(t.line, ")")
+			}
+			variation--
+		case tokenPeriod, tokenNAG:
+		case tokenSymbol:
+			if variation > 0 || isMoveNumber(t.value) {
+				continue
+			}
+			if isResult(t.value) {
+				game.Result = t.value
+				finish()
+				continue
+			}
+
+			m, err := ParseSAN(position, t.value)
+			if err != nil {
+				return nil, err
+			}
+			if position, err = position.MakeMove(m); err != nil {
+				return nil, err
+			}
+			game.Moves = append(game.Moves, m)
+			game.Comments = append(game.Comments, "")
+		}
+	}
+}
+
+func isMoveNumber(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func isResult(s string) bool {
+	return s == "1-0" || s == "0-1" || s == "1/2-1/2" || s == ResultUnknown
+}
+
+// lexer splits PGN text into tokens
+type lexer struct {
+	s    string
+	pos  int
+	line int
+}
+
+func (l *lexer) next() (token, error) {
+	l.skipSpace()
+	if l.pos >= len(l.s) {
+		return token{typ: tokenEOF, line: l.line}, nil
+	}
+
+	t := token{line: l.line}
+	c := l.s[l.pos]
+	switch {
+	case c == '[':
+		return l.tag()
+	case c == '{':
+		end := strings.IndexByte(l.s[l.pos:], '}')
+		if end < 0 {
+			return t, NewSyntaxError(l.line, "unterminated comment")
+		}
+		t.typ, t.value = tokenComment, strings.TrimSpace(l.s[l.pos+1:l.pos+end])
+		l.advance(end + 1)
+	case c == ';':
+		// Rest of line comment
+		end := strings.IndexByte(l.s[l.pos:], '\n')
+		if end < 0 {
+			end = len(l.s) - l.pos
+		}
+		t.typ, t.value = tokenComment, strings.TrimSpace(l.s[l.pos+1:l.pos+end])
+		l.advance(end)
+	case c == '(':
+		t.typ = tokenVariationStart
+		l.advance(1)
+	case c == ')':
+		t.typ = tokenVariationEnd
+		l.advance(1)
+	case c == '.':
+		t.typ = tokenPeriod
+		l.advance(1)
+	case c == '$':
+		l.advance(1)
+		t.typ, t.value = tokenNAG, l.symbol()
+		if !isMoveNumber(t.value) || t.value == "" {
+			return t, NewSyntaxError(t.line, "$"+t.value)
+		}
+	case c == '!' || c == '?':
+		// Suffix annotations written apart from the move
+		start := l.pos
+		for l.pos < len(l.s) && (l.s[l.pos] == '!' || l.s[l.pos] == '?') {
+			l.pos++
+		}
+		t.typ, t.value = tokenNAG, l.s[start:l.pos]
+	case isSymbolStart(c):
+		t.typ, t.value = tokenSymbol, l.symbol()
+	default:
+		return t, NewSyntaxError(l.line, string(c))
+	}
+	return t, nil
+}
+
+// tag reads a tag pair such as [Event "F/S Return Match"]
+func (l *lexer) tag() (token, error) {
+	t := token{typ: tokenTag, line: l.line}
+	l.advance(1)
+
+	l.skipSpace()
+	if t.name = l.symbol(); t.name == "" {
+		return t, NewSyntaxError(t.line, "[")
+	}
+
+	l.skipSpace()
+	if l.pos >= len(l.s) || l.s[l.pos] != '"' {
+		return t, NewSyntaxError(t.line, "["+t.name)
+	}
+	l.advance(1)
+
+	var value []byte
+	for {
+		if l.pos >= len(l.s) || l.s[l.pos] == '\n' {
+			return t, NewSyntaxError(t.line, "["+t.name)
+		}
+		c := l.s[l.pos]
+		l.advance(1)
+		if c == '"' {
+			break
+		}
+		if c == '\\' && l.pos < len(l.s) {
+			c = l.s[l.pos]
+			l.advance(1)
+		}
+		value = append(value, c)
+	}
+	t.value = string(value)
+
+	l.skipSpace()
+	if l.pos >= len(l.s) || l.s[l.pos] != ']' {
+		return t, NewSyntaxError(t.line, "["+t.name)
+	}
+	l.advance(1)
+
+	return t, nil
+}
+
+// symbol reads letters, digits and characters allowed in moves and results
+func (l *lexer) symbol() string {
+	start := l.pos
+	for l.pos < len(l.s) && (isSymbolStart(l.s[l.pos]) || strings.IndexByte("_+#=:-/!?", l.s[l.pos]) >= 0) {
+		l.pos++
+	}
+	return l.s[start:l.pos]
+}
+
+// skipSpace skips white space and escaped lines starting with a percent sign
+func (l *lexer) skipSpace() {
+	for l.pos < len(l.s) {
+		switch c := l.s[l.pos]; {
+		case c == '%' && (l.pos == 0 || l.s[l.pos-1] == '\n'):
+			for l.pos < len(l.s) && l.s[l.pos] != '\n' {
+				l.pos++
+			}
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			l.advance(1)
+		default:
+			return
+		}
+	}
+}
+
+// advance moves forward counting lines
+func (l *lexer) advance(n int) {
+	l.line += strings.Count(l.s[l.pos:l.pos+n], "\n")
+	l.pos += n
+}
+
+func isSymbolStart(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c == '*'
+}
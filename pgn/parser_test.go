@@ -0,0 +1,117 @@
+package pgn_test
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/RichardKnop/chess-engine/chess"
+	"github.com/RichardKnop/chess-engine/fen"
+	"github.com/RichardKnop/chess-engine/pgn"
+)
+
+// replay plays moves of a parsed game and returns the final position
+func replay(t *testing.T, g *pgn.Game) *chess.Position {
+	p := g.Position
+	if p == nil {
+		p = chess.NewPosition()
+	}
+	for _, m := range g.Moves {
+		var err error
+		if p, err = p.MakeMove(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return p
+}
+
+func TestParseClassics(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/classics.pgn")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	games, err := pgn.Parse(string(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(games) != 2 {
+		t.Fatalf("parsed %d games, want 2", len(games))
+	}
+
+	testCases := []struct {
+		white string
+		black string
+		plies int
+	}{
+		{"Paul Morphy", "Duke Karl / Count Isouard", 33},
+		{"Adolf Anderssen", "Lionel Kieseritzky", 45},
+	}
+	for i, tc := range testCases {
+		g := games[i]
+		if g.Tags["White"] != tc.white || g.Tags["Black"] != tc.black || g.Result != "1-0" {
+			t.Errorf("game %d: tags %v, result %s", i, g.Tags, g.Result)
+		}
+		if len(g.Moves) != tc.plies {
+			t.Errorf("game %d: %d moves, want %d", i, len(g.Moves), tc.plies)
+		}
+		if p := replay(t, g); !p.IsCheckmate() {
+			t.Errorf("game %d: final position %s is not checkmate", i, fen.Encode(p))
+		}
+
+		// Writing the game and reading it back gives the same moves
+		s, err := pgn.Encode(g)
+		if err != nil {
+			t.Fatal(err)
+		}
+		again, err := pgn.Parse(s)
+		if err != nil {
+			t.Fatalf("game %d: %v\n%s", i, err, s)
+		}
+		if len(again) != 1 || len(again[0].Moves) != len(g.Moves) || again[0].Comments[8] != g.Comments[8] {
+			t.Errorf("game %d did not survive a round trip:\n%s", i, s)
+		}
+	}
+
+	if games[0].Comments[16] != "Black is in what's like a zugzwang position\nhere." {
+		t.Errorf("unexpected comment %q", games[0].Comments[16])
+	}
+}
+
+func TestParse(t *testing.T) {
+	s := `% escaped line
+[Event "Quoted \"name\""]
+[SetUp "1"]
+[FEN "4k3/1P6/8/8/8/8/8/4K2R w K - 0 1"]
+
+1. b8=Q+ ; check
+Kd7 2. O-O!? $1 {castled} Kc6 (2... Ke6 3. Qe8+) 3. Qb4 *`
+
+	games, err := pgn.Parse(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := games[0]
+	if g.Tags["Event"] != `Quoted "name"` || g.Result != "*" || len(g.Moves) != 5 {
+		t.Fatalf("parsed %+v", g)
+	}
+	if g.Comments[0] != "check" || g.Comments[2] != "castled" {
+		t.Errorf("comments %q", g.Comments)
+	}
+	if p := replay(t, g); fen.Encode(p) != "8/8/2k5/8/1Q6/8/8/5RK1 b - - 4 3" {
+		t.Errorf("final position %s", fen.Encode(p))
+	}
+
+	invalid := []string{
+		`[Event "Unterminated]`,
+		`1. e4 e5 2. Ke3 *`,
+		`1. e4 (1. d4 *`,
+		`1. e4 { comment *`,
+		`1. Nd2 *`,
+		`[FEN "4k3/8/8/8/8/8/8/R4RK1 w - - 0 1"] 1. Rd1 *`,
+	}
+	for _, s := range invalid {
+		if _, err := pgn.Parse(s); err == nil {
+			t.Errorf("%s: expected an error", s)
+		}
+	}
+}
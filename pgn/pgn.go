@@ -0,0 +1,187 @@
+// Package pgn reads and writes games in Portable Game Notation
+// See http://www.saremba.de/chessgml/standards/pgn/pgn-complete.htm
+package pgn
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/RichardKnop/chess-engine/chess"
+	"github.com/RichardKnop/chess-engine/fen"
+)
+
+const (
+	// ResultUnknown terminates games which are still in progress
+	ResultUnknown = "*"
+
+	// maxLineLength is where movetext is wrapped as the standard recommends
+	maxLineLength = 79
+)
+
+// sevenTagRoster are tags every game has, they are written first in this order
+var sevenTagRoster = []string{"Event", "Site", "Date", "Round", "White", "Black", "Result"}
+
+// Game is a game as written to PGN
+type Game struct {
+	// Tags other than Result, SetUp and FEN which are derived from the game
+	Tags map[string]string
+	// Initial position, nil for the standard starting position
+	Position *chess.Position
+	Moves    []chess.Move
+	// Optional comment after each move, for example clock times
+	Comments []string
+	// Result is one of 1-0, 0-1, 1/2-1/2 or * for games in progress
+	Result string
+}
+
+// NewGame creates a game starting from the standard position with the
+// Seven Tag Roster filled with unknown values
+func NewGame() *Game {
+	return &Game{
+		Tags: map[string]string{
+			"Event": "?",
+			"Site":  "?",
+			"Date":  "????.??.??",
+			"Round": "-",
+			"White": "?",
+			"Black": "?",
+		},
+		Result: ResultUnknown,
+	}
+}
+
+// Encode returns the game in PGN
+func Encode(g *Game) (string, error) {
+	var buf bytes.Buffer
+	if err := Write(&buf, g); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// Write writes the game in PGN, moves are validated and converted to SAN
+func Write(w io.Writer, g *Game) error {
+	result := g.Result
+	if result == "" {
+		result = ResultUnknown
+	}
+
+	position := g.Position
+	if position == nil {
+		position = chess.NewPosition()
+	}
+
+	tags := make(map[string]string, len(g.Tags)+3)
+	for name, value := range g.Tags {
+		tags[name] = value
+	}
+	tags["Result"] = result
+	if start := fen.Encode(position); start != fen.Initial {
+		tags["SetUp"] = "1"
+		tags["FEN"] = start
+	}
+
+	var buf bytes.Buffer
+	writeTags(&buf, tags)
+	buf.WriteByte('\n')
+
+	tokens, err := movetext(position, g.Moves, g.Comments)
+	if err != nil {
+		return err
+	}
+	tokens = append(tokens, result)
+	writeWrapped(&buf, tokens)
+
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// ClockComment formats remaining time of the player who moved as a comment
+// understood by most PGN viewers, for example "[%clk 0:04:55]"
+func ClockComment(remaining time.Duration) string {
+	seconds := int64(remaining / time.Second)
+	return fmt.Sprintf("[%%clk %d:%02d:%02d]", seconds/3600, seconds/60%60, seconds%60)
+}
+
+// writeTags writes the Seven Tag Roster followed by other tags sorted by name
+func writeTags(buf *bytes.Buffer, tags map[string]string) {
+	var names []string
+	for name := range tags {
+		if !isRosterTag(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range append(sevenTagRoster, names...) {
+		value := tags[name]
+		if value == "" {
+			value = "?"
+		}
+		value = strings.Replace(value, `\`, `\\`, -1)
+		value = strings.Replace(value, `"`, `\"`, -1)
+		fmt.Fprintf(buf, "[%s \"%s\"]\n", name, value)
+	}
+}
+
+// movetext returns move numbers, moves in SAN and comments
+func movetext(p *chess.Position, moves []chess.Move, comments []string) ([]string, error) {
+	var tokens []string
+	for i, m := range moves {
+		number := p.FullmoveNumber()
+		if p.Turn() == chess.White {
+			tokens = append(tokens, fmt.Sprintf("%d.", number))
+		} else if i == 0 || (i-1 < len(comments) && comments[i-1] != "") {
+			// Black's move needs its number when it does not follow white's
+			tokens = append(tokens, fmt.Sprintf("%d...", number))
+		}
+
+		san, err := SAN(p, m)
+		if err != nil {
+			return nil, fmt.Errorf("Move %d %s: %v", i+1, m, err)
+		}
+		tokens = append(tokens, san)
+
+		if i < len(comments) && comments[i] != "" {
+			// Braces would end the comment early
+			comment := strings.Replace(comments[i], "}", ")", -1)
+			tokens = append(tokens, "{"+comment+"}")
+		}
+
+		if p, err = p.MakeMove(m); err != nil {
+			return nil, err
+		}
+	}
+	return tokens, nil
+}
+
+// writeWrapped joins tokens with spaces keeping lines short
+func writeWrapped(buf *bytes.Buffer, tokens []string) {
+	lineLength := 0
+	for _, token := range tokens {
+		if lineLength > 0 && lineLength+1+len(token) > maxLineLength {
+			buf.WriteByte('\n')
+			lineLength = 0
+		}
+		if lineLength > 0 {
+			buf.WriteByte(' ')
+			lineLength++
+		}
+		buf.WriteString(token)
+		lineLength += len(token)
+	}
+	buf.WriteByte('\n')
+}
+
+func isRosterTag(name string) bool {
+	for _, tag := range sevenTagRoster {
+		if tag == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,113 @@
+package pgn_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/RichardKnop/chess-engine/chess"
+	"github.com/RichardKnop/chess-engine/fen"
+	"github.com/RichardKnop/chess-engine/pgn"
+)
+
+func TestSAN(t *testing.T) {
+	testCases := []struct {
+		fen  string
+		move string
+		san  string
+	}{
+		{fen.Initial, "e2e4", "e4"},
+		{fen.Initial, "g1f3", "Nf3"},
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "e1g1", "O-O"},
+		{"r3k2r/8/8/8/8/8/8/R3K2R b KQkq - 0 1", "e8c8", "O-O-O"},
+		{"rnbqkbnr/ppp1p1pp/8/3pPp2/8/8/PPPP1PPP/RNBQKBNR w KQkq f6 0 3", "e5f6", "exf6"},
+		{"4k3/1P6/8/8/8/8/8/4K3 w - - 0 1", "b7b8q", "b8=Q+"},
+		{"4k3/1P6/8/8/8/8/8/4K3 w - - 0 1", "b7b8n", "b8=N"},
+		{"4k3/8/8/8/8/8/8/R4RK1 w - - 0 1", "a1d1", "Rad1"},
+		{"4k3/8/8/8/R7/8/8/R3K3 w - - 0 1", "a1a2", "R1a2"},
+		{"k7/8/8/8/8/2Q1Q3/8/4Q2K w - - 0 1", "e3d2", "Qe3d2"},
+		{"k7/8/8/8/8/2Q1Q3/8/2Q4K w - - 0 1", "e3d2", "Qed2"},
+		{"6k1/5ppp/8/8/8/8/5PPP/3R2K1 w - - 0 1", "d1d8", "Rd8#"},
+		{"4k3/8/8/3p4/4N3/8/8/4K3 w - - 0 1", "e4d6", "Nd6+"},
+		{"4k3/8/8/3p4/4N3/8/8/4K3 w - - 0 1", "e4c5", "Nc5"},
+		{"4k3/8/5n2/3p4/4N3/8/8/4K3 w - - 0 1", "e4f6", "Nxf6+"},
+	}
+
+	for _, tc := range testCases {
+		p, err := fen.Parse(tc.fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, err := chess.ParseMove(tc.move)
+		if err != nil {
+			t.Fatal(err)
+		}
+		san, err := pgn.SAN(p, m)
+		if err != nil {
+			t.Errorf("%s %s: %v", tc.fen, tc.move, err)
+			continue
+		}
+		if san != tc.san {
+			t.Errorf("%s %s: got %s, want %s", tc.fen, tc.move, san, tc.san)
+		}
+	}
+
+	if _, err := pgn.SAN(chess.NewPosition(), chess.Move{From: chess.Square(12), To: chess.Square(36)}); err == nil {
+		t.Error("illegal move converted to SAN")
+	}
+}
+
+func TestEncode(t *testing.T) {
+	g := pgn.NewGame()
+	g.Tags["White"] = "alice"
+	g.Tags["TimeControl"] = "300+3"
+	for _, s := range []string{"f2f3", "e7e5", "g2g4", "d8h4"} {
+		m, _ := chess.ParseMove(s)
+		g.Moves = append(g.Moves, m)
+	}
+	g.Comments = []string{"", "", "", pgn.ClockComment(295 * time.Second)}
+	g.Result = "0-1"
+
+	expected := `[Event "?"]
+[Site "?"]
+[Date "????.??.??"]
+[Round "-"]
+[White "alice"]
+[Black "?"]
+[Result "0-1"]
+[TimeControl "300+3"]
+
+1. f3 e5 2. g4 Qh4# {[%clk 0:04:55]} 0-1
+`
+	s, err := pgn.Encode(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != expected {
+		t.Errorf("got\n%s\nwant\n%s", s, expected)
+	}
+
+	g.Position, _ = fen.Parse("4k3/8/8/8/8/8/8/4K2R b K - 0 40")
+	g.Moves = []chess.Move{{From: chess.Square(60), To: chess.Square(52)}, {From: chess.Square(4), To: chess.Square(6)}}
+	g.Comments = []string{"{x}", ""}
+	g.Result = ""
+	s, err = pgn.Encode(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = `[Event "?"]
+[Site "?"]
+[Date "????.??.??"]
+[Round "-"]
+[White "alice"]
+[Black "?"]
+[Result "*"]
+[FEN "4k3/8/8/8/8/8/8/4K2R b K - 0 40"]
+[SetUp "1"]
+[TimeControl "300+3"]
+
+40... Ke7 {{x)} 41. O-O *
+`
+	if s != expected {
+		t.Errorf("got\n%s\nwant\n%s", s, expected)
+	}
+}
@@ -0,0 +1,200 @@
+package pgn
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/RichardKnop/chess-engine/chess"
+)
+
+// SAN returns a legal move in Standard Algebraic Notation, for example
+// "Nbd7", "exd6", "e8=Q+" or "O-O-O#"
+func SAN(p *chess.Position, m chess.Move) (string, error) {
+	legal := p.LegalMoves()
+	if !contains(legal, m) {
+		return "", chess.ErrIllegalMove
+	}
+
+	var buf bytes.Buffer
+	piece := p.PieceAt(m.From)
+
+	switch {
+	case piece.Type() == chess.King && abs(m.To.File()-m.From.File()) == 2:
+		if m.To.File() > m.From.File() {
+			buf.WriteString("O-O")
+		} else {
+			buf.WriteString("O-O-O")
+		}
+	case piece.Type() == chess.Pawn:
+		if m.From.File() != m.To.File() {
+			// Pawns only change files when capturing
+			buf.WriteByte(m.From.String()[0])
+			buf.WriteByte('x')
+		}
+		buf.WriteString(m.To.String())
+		if m.Promotion != chess.NoPieceType {
+			buf.WriteByte('=')
+			buf.WriteByte(upper(m.Promotion.Char()))
+		}
+	default:
+		buf.WriteByte(upper(piece.Type().Char()))
+		buf.WriteString(disambiguation(p, legal, m))
+		if p.PieceAt(m.To) != chess.NoPiece {
+			buf.WriteByte('x')
+		}
+		buf.WriteString(m.To.String())
+	}
+
+	next, err := p.MakeMove(m)
+	if err != nil {
+		return "", err
+	}
+	if next.IsCheckmate() {
+		buf.WriteByte('#')
+	} else if next.InCheck() {
+		buf.WriteByte('+')
+	}
+
+	return buf.String(), nil
+}
+
+// disambiguation returns the file, rank or both of the origin square when
+// another piece of the same type can move to the same square
+func disambiguation(p *chess.Position, legal []chess.Move, m chess.Move) string {
+	piece := p.PieceAt(m.From)
+	ambiguous, sameFile, sameRank := false, false, false
+
+	for _, other := range legal {
+		if other.To != m.To || other.From == m.From || p.PieceAt(other.From) != piece {
+			continue
+		}
+		ambiguous = true
+		if other.From.File() == m.From.File() {
+			sameFile = true
+		}
+		if other.From.Rank() == m.From.Rank() {
+			sameRank = true
+		}
+	}
+
+	from := m.From.String()
+	switch {
+	case !ambiguous:
+		return ""
+	case !sameFile:
+		return from[:1]
+	case !sameRank:
+		return from[1:]
+	}
+	return from
+}
+
+func contains(moves []chess.Move, m chess.Move) bool {
+	for _, legal := range moves {
+		if legal == m {
+			return true
+		}
+	}
+	return false
+}
+
+func upper(c byte) byte {
+	return c - 'a' + 'A'
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// ParseSAN converts a move in Standard Algebraic Notation into a legal move,
+// check and annotation suffixes are ignored
+func ParseSAN(p *chess.Position, san string) (chess.Move, error) {
+	s := strings.TrimRight(san, "+#!?")
+	legal := p.LegalMoves()
+
+	// Castling is written with letter O, some programs use digit zero
+	switch s {
+	case "O-O", "0-0", "O-O-O", "0-0-0":
+		king := p.KingSquare(p.Turn())
+		file := 6
+		if len(s) == 5 {
+			file = 2
+		}
+		m := chess.Move{From: king, To: chess.NewSquare(file, king.Rank())}
+		if king.File() != 4 || !contains(legal, m) {
+			return chess.NoMove, NewInvalidMoveError(san, "castling is not allowed")
+		}
+		return m, nil
+	}
+
+	pieceType := chess.Pawn
+	if len(s) > 0 && strings.IndexByte("NBRQK", s[0]) >= 0 {
+		pieceType, _ = chess.ParsePieceType(s[0])
+		s = s[1:]
+	}
+
+	promotion := chess.NoPieceType
+	if i := strings.IndexByte(s, '='); i >= 0 {
+		var err error
+		if promotion, err = parsePromotion(s[i+1:]); err != nil {
+			return chess.NoMove, NewInvalidMoveError(san, "invalid promotion")
+		}
+		s = s[:i]
+	} else if pieceType == chess.Pawn && len(s) > 0 && strings.IndexByte("NBRQ", s[len(s)-1]) >= 0 {
+		// Promotion without the equal sign such as e8Q
+		promotion, _ = chess.ParsePieceType(s[len(s)-1])
+		s = s[:len(s)-1]
+	}
+
+	if len(s) < 2 {
+		return chess.NoMove, NewInvalidMoveError(san, "missing target square")
+	}
+	to, err := chess.ParseSquare(s[len(s)-2:])
+	if err != nil {
+		return chess.NoMove, NewInvalidMoveError(san, "invalid target square")
+	}
+
+	// Whatever is left narrows down where the piece comes from
+	fromFile, fromRank := -1, -1
+	for _, c := range strings.Replace(s[:len(s)-2], "x", "", -1) {
+		switch {
+		case c >= 'a' && c <= 'h':
+			fromFile = int(c - 'a')
+		case c >= '1' && c <= '8':
+			fromRank = int(c - '1')
+		default:
+			return chess.NoMove, NewInvalidMoveError(san, "invalid origin")
+		}
+	}
+
+	match := chess.NoMove
+	for _, m := range legal {
+		if m.To != to || m.Promotion != promotion || p.PieceAt(m.From).Type() != pieceType {
+			continue
+		}
+		if (fromFile >= 0 && m.From.File() != fromFile) || (fromRank >= 0 && m.From.Rank() != fromRank) {
+			continue
+		}
+		if match != chess.NoMove {
+			return chess.NoMove, NewInvalidMoveError(san, "ambiguous move")
+		}
+		match = m
+	}
+
+	if match == chess.NoMove {
+		return chess.NoMove, NewInvalidMoveError(san, "no such legal move")
+	}
+	return match, nil
+}
+
+// parsePromotion parses the piece a pawn promotes to
+func parsePromotion(s string) (chess.PieceType, error) {
+	if len(s) != 1 || strings.IndexByte("NBRQ", s[0]) < 0 {
+		return chess.NoPieceType, fmt.Errorf("Invalid promotion: %s", s)
+	}
+	return chess.ParsePieceType(s[0])
+}
@@ -0,0 +1,144 @@
+// Package rating implements the Glicko-2 rating system.
+// See http://www.glicko.net/glicko/glicko2.pdf
+package rating
+
+import (
+	"math"
+)
+
+const (
+	// DefaultRating is the rating of new players
+	DefaultRating = 1500
+	// DefaultDeviation is the rating deviation of new players, it is also the
+	// largest deviation
+	DefaultDeviation = 350
+	// DefaultVolatility is the volatility of new players
+	DefaultVolatility = 0.06
+
+	// tau constrains changes of volatility over time
+	tau = 0.5
+	// scale converts between the Glicko and Glicko-2 scales
+	scale = 173.7178
+	// epsilon is the convergence tolerance of the volatility iteration
+	epsilon = 0.000001
+)
+
+// Score of a game from the point of view of a player
+const (
+	Loss = 0.0
+	Draw = 0.5
+	Win  = 1.0
+)
+
+// Rating is a player's rating with its reliability
+type Rating struct {
+	Rating     float64 `json:"rating"`
+	Deviation  float64 `json:"deviation"`
+	Volatility float64 `json:"volatility"`
+}
+
+// Result is a game played in a rating period
+type Result struct {
+	Opponent Rating
+	Score    float64
+}
+
+// New returns the rating of a new player
+func New() Rating {
+	return Rating{Rating: DefaultRating, Deviation: DefaultDeviation, Volatility: DefaultVolatility}
+}
+
+// Expected returns the expected score against an opponent
+func (r Rating) Expected(opponent Rating) float64 {
+	mu, _ := r.scaled()
+	muj, phij := opponent.scaled()
+	return expected(mu, muj, phij)
+}
+
+// Update returns the rating after a rating period with the results, the
+// deviation grows if no games were played
+func (r Rating) Update(results []Result) Rating {
+	mu, phi := r.scaled()
+
+	if len(results) == 0 {
+		phi = math.Sqrt(phi*phi + r.Volatility*r.Volatility)
+		return unscaled(mu, phi, r.Volatility)
+	}
+
+	// Estimated variance of the rating based on game outcomes only and the
+	// estimated improvement
+	var v, sum float64
+	for _, result := range results {
+		muj, phij := result.Opponent.scaled()
+		e := expected(mu, muj, phij)
+		gj := g(phij)
+		v += gj * gj * e * (1 - e)
+		sum += gj * (result.Score - e)
+	}
+	v = 1 / v
+	delta := v * sum
+
+	sigma := volatility(phi, r.Volatility, v, delta)
+
+	phiStar := math.Sqrt(phi*phi + sigma*sigma)
+	phi = 1 / math.Sqrt(1/(phiStar*phiStar)+1/v)
+	mu += phi * phi * sum
+
+	return unscaled(mu, phi, sigma)
+}
+
+// volatility finds the new volatility with the Illinois algorithm
+func volatility(phi, sigma, v, delta float64) float64 {
+	a := math.Log(sigma * sigma)
+	f := func(x float64) float64 {
+		ex := math.Exp(x)
+		d := phi*phi + v + ex
+		return ex*(delta*delta-phi*phi-v-ex)/(2*d*d) - (x-a)/(tau*tau)
+	}
+
+	A := a
+	var B float64
+	if delta*delta > phi*phi+v {
+		B = math.Log(delta*delta - phi*phi - v)
+	} else {
+		k := 1.0
+		for f(a-k*tau) < 0 {
+			k++
+		}
+		B = a - k*tau
+	}
+
+	fA, fB := f(A), f(B)
+	for math.Abs(B-A) > epsilon {
+		C := A + (A-B)*fA/(fB-fA)
+		fC := f(C)
+		if fC*fB <= 0 {
+			A, fA = B, fB
+		} else {
+			fA /= 2
+		}
+		B, fB = C, fC
+	}
+
+	return math.Exp(A / 2)
+}
+
+func (r Rating) scaled() (mu, phi float64) {
+	return (r.Rating - DefaultRating) / scale, r.Deviation / scale
+}
+
+func unscaled(mu, phi, sigma float64) Rating {
+	deviation := phi * scale
+	if deviation > DefaultDeviation {
+		deviation = DefaultDeviation
+	}
+	return Rating{Rating: mu*scale + DefaultRating, Deviation: deviation, Volatility: sigma}
+}
+
+func g(phi float64) float64 {
+	return 1 / math.Sqrt(1+3*phi*phi/(math.Pi*math.Pi))
+}
+
+func expected(mu, muj, phij float64) float64 {
+	return 1 / (1 + math.Exp(-g(phij)*(mu-muj)))
+}
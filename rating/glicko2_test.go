@@ -0,0 +1,48 @@
+package rating
+
+import (
+	"math"
+	"testing"
+)
+
+func TestUpdate(t *testing.T) {
+	// Example from Glickman's paper
+	player := Rating{Rating: 1500, Deviation: 200, Volatility: 0.06}
+	results := []Result{
+		{Opponent: Rating{Rating: 1400, Deviation: 30, Volatility: 0.06}, Score: Win},
+		{Opponent: Rating{Rating: 1550, Deviation: 100, Volatility: 0.06}, Score: Loss},
+		{Opponent: Rating{Rating: 1700, Deviation: 300, Volatility: 0.06}, Score: Loss},
+	}
+
+	updated := player.Update(results)
+	expected := Rating{Rating: 1464.06, Deviation: 151.52, Volatility: 0.05999}
+	if math.Abs(updated.Rating-expected.Rating) > 0.01 ||
+		math.Abs(updated.Deviation-expected.Deviation) > 0.01 ||
+		math.Abs(updated.Volatility-expected.Volatility) > 0.00001 {
+		t.Errorf("updated rating %+v, want %+v", updated, expected)
+	}
+}
+
+func TestNoGames(t *testing.T) {
+	player := Rating{Rating: 1700, Deviation: 50, Volatility: 0.06}
+	updated := player.Update(nil)
+	if updated.Rating != player.Rating || updated.Deviation <= player.Deviation {
+		t.Errorf("rating after an idle period %+v", updated)
+	}
+
+	if updated := New().Update(nil); updated.Deviation != DefaultDeviation {
+		t.Errorf("deviation grew over %v", DefaultDeviation)
+	}
+}
+
+func TestExpected(t *testing.T) {
+	a, b := New(), New()
+	if e := a.Expected(b); e != 0.5 {
+		t.Errorf("expected score of equal players %v", e)
+	}
+
+	b.Rating = 1900
+	if e := a.Expected(b) + b.Expected(a); math.Abs(e-1) > 0.1 || a.Expected(b) > 0.25 {
+		t.Errorf("expected scores %v and %v", a.Expected(b), b.Expected(a))
+	}
+}
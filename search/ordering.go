@@ -0,0 +1,93 @@
+package search
+
+import (
+	"github.com/RichardKnop/chess-engine/chess"
+)
+
+// pieceValues are rough material values in centipawns used for MVV-LVA
+var pieceValues = [7]int{0, 100, 320, 330, 500, 900, 0}
+
+// Move ordering scores, moves most likely to cause a cutoff go first
+const (
+	scoreHashMove  = 1 << 30
+	scoreCapture   = 1 << 20
+	scoreKiller1   = 1<<20 - 1
+	scoreKiller2   = 1<<20 - 2
+	maxHistoryGain = 1 << 16
+)
+
+// scoreMoves assigns an ordering score to every move in a list
+func (s *Searcher) scoreMoves(p *chess.Position, list *chess.MoveList, scores []int, ply int, hashMove chess.Move) {
+	for i := 0; i < list.Len(); i++ {
+		m := list.At(i)
+		switch {
+		case m == hashMove:
+			scores[i] = scoreHashMove
+		case p.PieceAt(m.To) != chess.NoPiece || m.Promotion != chess.NoPieceType || isEnPassant(p, m):
+			scores[i] = scoreCapture + mvvLva(p, m)
+		case m == s.killers[ply][0]:
+			scores[i] = scoreKiller1
+		case m == s.killers[ply][1]:
+			scores[i] = scoreKiller2
+		default:
+			scores[i] = s.history[p.Turn()][m.From][m.To]
+		}
+	}
+}
+
+// pickMove moves the best scored remaining move to an index, selection sort
+// is cheaper than sorting because most nodes are cut off after a few moves
+func pickMove(list *chess.MoveList, scores []int, index int) {
+	best := index
+	for i := index + 1; i < list.Len(); i++ {
+		if scores[i] > scores[best] {
+			best = i
+		}
+	}
+	list.Swap(index, best)
+	scores[index], scores[best] = scores[best], scores[index]
+}
+
+// mvvLva prefers capturing the most valuable victim with the least valuable attacker
+func mvvLva(p *chess.Position, m chess.Move) int {
+	victim := p.PieceAt(m.To).Type()
+	if victim == chess.NoPieceType && isEnPassant(p, m) {
+		victim = chess.Pawn
+	}
+	attacker := p.PieceAt(m.From).Type()
+	return pieceValues[victim]*10 - pieceValues[attacker]/10 + pieceValues[m.Promotion]
+}
+
+// isEnPassant returns true if a move is an en passant capture
+func isEnPassant(p *chess.Position, m chess.Move) bool {
+	return m.To == p.EnPassant() && p.PieceAt(m.From).Type() == chess.Pawn
+}
+
+// isQuiet returns true for moves which are neither captures nor promotions
+func isQuiet(p *chess.Position, m chess.Move) bool {
+	return p.PieceAt(m.To) == chess.NoPiece && m.Promotion == chess.NoPieceType && !isEnPassant(p, m)
+}
+
+// storeKiller remembers a quiet move which caused a beta cutoff at a ply
+func (s *Searcher) storeKiller(m chess.Move, ply int) {
+	if s.killers[ply][0] != m {
+		s.killers[ply][1] = s.killers[ply][0]
+		s.killers[ply][0] = m
+	}
+}
+
+// updateHistory rewards a quiet move which caused a beta cutoff
+func (s *Searcher) updateHistory(c chess.Color, m chess.Move, depth int) {
+	h := &s.history[c][m.From][m.To]
+	*h += depth * depth
+	if *h >= maxHistoryGain {
+		// Age the whole table so scores never reach killer move scores
+		for c := range s.history {
+			for from := range s.history[c] {
+				for to := range s.history[c][from] {
+					s.history[c][from][to] /= 2
+				}
+			}
+		}
+	}
+}
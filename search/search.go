@@ -0,0 +1,341 @@
+// Package search finds the best move in a position using negamax alpha-beta
+// with iterative deepening and quiescence search
+package search
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/RichardKnop/chess-engine/chess"
+	"github.com/RichardKnop/chess-engine/eval"
+)
+
+// checkInterval is how many nodes are searched between checking limits
+const checkInterval = 2048
+
+// Searcher holds state reused between searches such as move ordering
+// heuristics. A Searcher must not be used by multiple goroutines at once,
+// except for calling Stop.
+type Searcher struct {
+	// OnIteration is called after every completed iteration, it can be
+	// used to report progress, e.g. UCI info lines
+	OnIteration func(Result)
+
+	tt      *TranspositionTable
+	killers [MaxPly][2]chess.Move
+	history [2][64][64]int
+
+	pv    [MaxPly][MaxPly]chess.Move
+	pvLen [MaxPly]int
+
+	nodes    uint64
+	depth    int
+	limits   Limits
+	start    time.Time
+	deadline time.Time
+	stopped  int32
+}
+
+// New creates a new instance of Searcher with a default sized hash table
+func New() *Searcher {
+	return &Searcher{tt: NewTranspositionTable(DefaultHashSize)}
+}
+
+// SetHashSize resizes the transposition table, which also clears it
+func (s *Searcher) SetHashSize(sizeMB int) {
+	s.tt.Resize(sizeMB)
+}
+
+// ClearHash empties the transposition table, e.g. before a new game
+func (s *Searcher) ClearHash() {
+	s.tt.Clear()
+}
+
+// HashStats returns transposition table statistics
+func (s *Searcher) HashStats() TTStats {
+	return s.tt.Stats()
+}
+
+// Hashfull returns permille of the transposition table used by the search
+func (s *Searcher) Hashfull() int {
+	return s.tt.Hashfull()
+}
+
+// Stop aborts a running search, Search then returns the best move found by
+// the last completed iteration. It has no effect when no search is running,
+// callers which cannot tell use Limits.Stop instead.
+func (s *Searcher) Stop() {
+	atomic.StoreInt32(&s.stopped, 1)
+}
+
+// Search runs iterative deepening until a limit is reached and returns the
+// best move found. If the side to move has no legal moves, the best move is
+// chess.NoMove.
+func (s *Searcher) Search(p *chess.Position, limits Limits) Result {
+	root := *p
+	s.reset(limits)
+	defer atomic.StoreInt32(&s.stopped, 0)
+
+	maxDepth := limits.Depth
+	if maxDepth <= 0 || maxDepth >= MaxPly || limits.Infinite {
+		maxDepth = MaxPly - 1
+	}
+
+	var result Result
+	for depth := 1; depth <= maxDepth; depth++ {
+		s.depth = depth
+		score := s.negamax(&root, depth, 0, -Infinity, Infinity)
+
+		// Results of an interrupted iteration are not reliable
+		if s.isStopped() && depth > 1 {
+			break
+		}
+
+		result = Result{
+			Score: score,
+			Depth: depth,
+			Nodes: s.nodes,
+			Time:  time.Since(s.start),
+			PV:    append([]chess.Move(nil), s.pv[0][:s.pvLen[0]]...),
+		}
+		if len(result.PV) > 0 {
+			result.BestMove = result.PV[0]
+		}
+		if s.OnIteration != nil {
+			s.OnIteration(result)
+		}
+
+		s.checkLimits()
+		if s.isStopped() || len(result.PV) == 0 {
+			break
+		}
+		// A forced mate was found, searching deeper cannot improve it
+		if !limits.Infinite && result.IsMate() && depth > 2*abs(result.MateIn()) {
+			break
+		}
+	}
+
+	// With an infinite search the best move must only be reported once stopped
+	for limits.Infinite && !s.isStopped() {
+		time.Sleep(time.Millisecond)
+		s.checkLimits()
+	}
+
+	return result
+}
+
+// reset prepares the searcher for a new search
+func (s *Searcher) reset(limits Limits) {
+	s.limits = limits
+	atomic.StoreInt32(&s.stopped, 0)
+	s.nodes = 0
+	s.start = time.Now()
+	if limits.MoveTime > 0 {
+		s.deadline = s.start.Add(limits.MoveTime)
+	} else {
+		s.deadline = time.Time{}
+	}
+	s.tt.NewSearch()
+
+	for ply := range s.killers {
+		s.killers[ply] = [2]chess.Move{}
+	}
+	for c := range s.history {
+		for from := range s.history[c] {
+			for to := range s.history[c][from] {
+				s.history[c][from][to] /= 8
+			}
+		}
+	}
+}
+
+// isStopped returns true once the search has to be aborted
+func (s *Searcher) isStopped() bool {
+	return atomic.LoadInt32(&s.stopped) != 0
+}
+
+// aborted returns true if the current iteration has to be abandoned, the first
+// iteration always completes so that there is a move to play
+func (s *Searcher) aborted() bool {
+	return s.depth > 1 && s.isStopped()
+}
+
+// checkLimits stops the search when it is stopped by the caller or node or
+// time limits run out
+func (s *Searcher) checkLimits() {
+	select {
+	case <-s.limits.Stop:
+		s.Stop()
+	default:
+	}
+	if s.limits.Infinite {
+		return
+	}
+	if s.limits.Nodes > 0 && s.nodes >= s.limits.Nodes {
+		s.Stop()
+	}
+	if !s.deadline.IsZero() && time.Now().After(s.deadline) {
+		s.Stop()
+	}
+}
+
+// negamax searches a position to a depth, returning a score from the point of
+// view of the side to move
+func (s *Searcher) negamax(p *chess.Position, depth, ply int, alpha, beta int) int {
+	s.pvLen[ply] = 0
+
+	s.nodes++
+	if s.nodes%checkInterval == 0 {
+		s.checkLimits()
+	}
+	if s.aborted() {
+		return 0
+	}
+
+	if ply > 0 && p.HalfmoveClock() >= 100 {
+		return 0
+	}
+	if ply >= MaxPly-1 {
+		return eval.Evaluate(p)
+	}
+
+	inCheck := p.InCheck()
+	if inCheck {
+		// Check extension, do not drop into quiescence while in check
+		depth++
+	}
+	if depth <= 0 {
+		return s.quiescence(p, ply, alpha, beta)
+	}
+
+	hashMove, ttScore, ttDepth, ttBound, ok := s.tt.Probe(p.Hash(), ply)
+	if ok && ply > 0 && ttDepth >= depth {
+		switch {
+		case ttBound == BoundExact,
+			ttBound == BoundLower && ttScore >= beta,
+			ttBound == BoundUpper && ttScore <= alpha:
+			return ttScore
+		}
+	}
+
+	var list chess.MoveList
+	p.GenerateMoves(&list)
+	var scores [chess.MaxMoves]int
+	s.scoreMoves(p, &list, scores[:], ply, hashMove)
+
+	origAlpha := alpha
+	bestMove := chess.NoMove
+	legalMoves := 0
+	for i := 0; i < list.Len(); i++ {
+		pickMove(&list, scores[:], i)
+		m := list.At(i)
+		quiet := isQuiet(p, m)
+
+		u := p.DoMove(m)
+		if p.OpponentInCheck() {
+			p.UndoMove(m, u)
+			continue
+		}
+		legalMoves++
+		score := -s.negamax(p, depth-1, ply+1, -beta, -alpha)
+		p.UndoMove(m, u)
+
+		if s.aborted() {
+			return 0
+		}
+
+		if score > alpha {
+			alpha = score
+			bestMove = m
+			s.updatePV(ply, m)
+		}
+		if alpha >= beta {
+			if quiet {
+				s.storeKiller(m, ply)
+				s.updateHistory(p.Turn(), m, depth)
+			}
+			s.tt.Store(p.Hash(), m, beta, depth, BoundLower, ply)
+			return beta
+		}
+	}
+
+	if legalMoves == 0 {
+		if inCheck {
+			return -Mate + ply
+		}
+		return 0
+	}
+
+	if alpha > origAlpha {
+		s.tt.Store(p.Hash(), bestMove, alpha, depth, BoundExact, ply)
+	} else {
+		s.tt.Store(p.Hash(), chess.NoMove, alpha, depth, BoundUpper, ply)
+	}
+
+	return alpha
+}
+
+// quiescence searches captures only until the position is quiet, so that the
+// evaluation is not done in the middle of an exchange
+func (s *Searcher) quiescence(p *chess.Position, ply int, alpha, beta int) int {
+	s.pvLen[ply] = 0
+
+	s.nodes++
+	if s.nodes%checkInterval == 0 {
+		s.checkLimits()
+	}
+	if s.aborted() {
+		return 0
+	}
+
+	standPat := eval.Evaluate(p)
+	if standPat >= beta || ply >= MaxPly-1 {
+		return standPat
+	}
+	if standPat > alpha {
+		alpha = standPat
+	}
+
+	var list chess.MoveList
+	p.GenerateCaptures(&list)
+	var scores [chess.MaxMoves]int
+	s.scoreMoves(p, &list, scores[:], ply, chess.NoMove)
+
+	for i := 0; i < list.Len(); i++ {
+		pickMove(&list, scores[:], i)
+		m := list.At(i)
+
+		u := p.DoMove(m)
+		if p.OpponentInCheck() {
+			p.UndoMove(m, u)
+			continue
+		}
+		score := -s.quiescence(p, ply+1, -beta, -alpha)
+		p.UndoMove(m, u)
+
+		if score > alpha {
+			alpha = score
+			s.updatePV(ply, m)
+		}
+		if alpha >= beta {
+			return beta
+		}
+	}
+
+	return alpha
+}
+
+// updatePV sets a move followed by the child's principal variation as the
+// principal variation of a ply
+func (s *Searcher) updatePV(ply int, m chess.Move) {
+	s.pv[ply][0] = m
+	n := copy(s.pv[ply][1:], s.pv[ply+1][:s.pvLen[ply+1]])
+	s.pvLen[ply] = n + 1
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,105 @@
+package search_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/RichardKnop/chess-engine/chess"
+	"github.com/RichardKnop/chess-engine/fen"
+	"github.com/RichardKnop/chess-engine/search"
+)
+
+func TestSearch(t *testing.T) {
+	testCases := []struct {
+		name     string
+		fen      string
+		depth    int
+		bestMove string
+		mateIn   int
+	}{
+		{
+			name:     "back rank mate in one",
+			fen:      "6k1/5ppp/8/8/8/8/5PPP/3R2K1 w - - 0 1",
+			depth:    3,
+			bestMove: "d1d8",
+			mateIn:   1,
+		},
+		{
+			name:     "mate in two",
+			fen:      "k7/8/2K5/8/8/8/8/7R w - - 0 1",
+			depth:    4,
+			bestMove: "c6b6",
+			mateIn:   2,
+		},
+		{
+			name:     "winning a hanging queen",
+			fen:      "4k3/8/8/3q4/8/8/8/3RK3 w - - 0 1",
+			depth:    3,
+			bestMove: "d1d5",
+		},
+		{
+			name:     "stalemate",
+			fen:      "7k/5Q2/6K1/8/8/8/8/8 b - - 0 1",
+			depth:    3,
+			bestMove: "0000",
+		},
+	}
+
+	for _, tc := range testCases {
+		p, err := fen.Parse(tc.fen)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+
+		result := search.New().Search(p, search.Limits{Depth: tc.depth})
+		if result.BestMove.String() != tc.bestMove {
+			t.Errorf("%s: best move %s, expected %s", tc.name, result.BestMove, tc.bestMove)
+		}
+		if result.MateIn() != tc.mateIn {
+			t.Errorf("%s: mate in %d, expected %d", tc.name, result.MateIn(), tc.mateIn)
+		}
+	}
+}
+
+func TestSearchNodeLimit(t *testing.T) {
+	result := search.New().Search(chess.NewPosition(), search.Limits{Nodes: 10000})
+	if result.BestMove == chess.NoMove {
+		t.Fatal("Expected a best move")
+	}
+	// Limits are checked periodically so the search can overshoot a little
+	if result.Nodes > 20000 {
+		t.Errorf("Searched %d nodes, expected about 10000", result.Nodes)
+	}
+}
+
+func TestSearchStop(t *testing.T) {
+	s := search.New()
+	done := make(chan search.Result)
+	go func() {
+		done <- s.Search(chess.NewPosition(), search.Limits{Infinite: true})
+	}()
+
+	// Give the search time to start, stopping before it starts has no effect
+	time.Sleep(100 * time.Millisecond)
+	s.Stop()
+	if result := <-done; result.BestMove == chess.NoMove {
+		t.Error("Expected a best move after stopping an infinite search")
+	}
+}
+
+func TestSearchStopChannel(t *testing.T) {
+	s := search.New()
+
+	// A stop closed before the search starts is not lost
+	stop := make(chan struct{})
+	close(stop)
+	if result := s.Search(chess.NewPosition(), search.Limits{Infinite: true, Stop: stop}); result.BestMove == chess.NoMove {
+		t.Error("Expected a best move after stopping an infinite search")
+	}
+
+	// Stops of other searches do not affect the next one
+	s.Stop()
+	if result := s.Search(chess.NewPosition(), search.Limits{Depth: 3}); result.Depth != 3 {
+		t.Errorf("Searched to depth %d, expected 3", result.Depth)
+	}
+}
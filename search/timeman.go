@@ -0,0 +1,30 @@
+package search
+
+import (
+	"time"
+)
+
+const (
+	// defaultMovesToGo is assumed when moves until the next time control are unknown
+	defaultMovesToGo = 30
+	// moveOverhead is kept in reserve for communication delays
+	moveOverhead = 50 * time.Millisecond
+)
+
+// AllocateTime splits remaining time evenly over the moves left until the
+// next time control, never using more than half of what is left
+func AllocateTime(remaining, inc time.Duration, movesToGo int) time.Duration {
+	if movesToGo <= 0 {
+		movesToGo = defaultMovesToGo
+	}
+
+	budget := remaining/time.Duration(movesToGo) + inc*3/4
+	if max := remaining / 2; budget > max {
+		budget = max
+	}
+	budget -= moveOverhead
+	if budget < 10*time.Millisecond {
+		budget = 10 * time.Millisecond
+	}
+	return budget
+}
@@ -0,0 +1,210 @@
+package search
+
+import (
+	"unsafe"
+
+	"github.com/RichardKnop/chess-engine/chess"
+)
+
+// DefaultHashSize is the default transposition table size in megabytes
+const DefaultHashSize = 16
+
+// Bound tells how a stored score relates to the real score of a position
+type Bound uint8
+
+const (
+	// BoundNone marks an empty entry
+	BoundNone Bound = iota
+	// BoundExact means the score is exact (a PV node)
+	BoundExact
+	// BoundLower means the real score is at least the stored score (fail high)
+	BoundLower
+	// BoundUpper means the real score is at most the stored score (fail low)
+	BoundUpper
+)
+
+// ttEntry is a single transposition table slot, fields are ordered so that
+// the entry packs into 16 bytes
+type ttEntry struct {
+	key        uint64
+	score      int16
+	move       chess.Move
+	depth      int8
+	bound      Bound
+	generation uint8
+}
+
+// ttBucket holds a depth-preferred slot and an always-replace slot
+type ttBucket [2]ttEntry
+
+// TranspositionTable caches search results keyed by Zobrist hash
+// See https://www.chessprogramming.org/Transposition_Table
+type TranspositionTable struct {
+	buckets    []ttBucket
+	mask       uint64
+	generation uint8
+
+	probes     uint64
+	hits       uint64
+	stores     uint64
+	overwrites uint64
+}
+
+// TTStats holds transposition table counters used for tuning
+type TTStats struct {
+	SizeMB  int
+	Entries int
+	Probes  uint64
+	Hits    uint64
+	Stores  uint64
+	// Overwrites counts stores replacing an entry of a different position
+	Overwrites uint64
+}
+
+// HitRate returns a share of probes which found a matching entry
+func (s TTStats) HitRate() float64 {
+	if s.Probes == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Probes)
+}
+
+// NewTranspositionTable creates a table using at most sizeMB megabytes
+func NewTranspositionTable(sizeMB int) *TranspositionTable {
+	t := new(TranspositionTable)
+	t.Resize(sizeMB)
+	return t
+}
+
+// Resize reallocates the table, which also clears it
+func (t *TranspositionTable) Resize(sizeMB int) {
+	if sizeMB < 1 {
+		sizeMB = 1
+	}
+
+	// Number of buckets must be a power of two so the index is a mask
+	count := uint64(sizeMB) * 1024 * 1024 / uint64(unsafe.Sizeof(ttBucket{}))
+	size := uint64(1)
+	for size*2 <= count {
+		size *= 2
+	}
+
+	t.buckets = make([]ttBucket, size)
+	t.mask = size - 1
+	t.Clear()
+}
+
+// Clear empties the table and resets statistics
+func (t *TranspositionTable) Clear() {
+	for i := range t.buckets {
+		t.buckets[i] = ttBucket{}
+	}
+	t.generation = 0
+	t.probes, t.hits, t.stores, t.overwrites = 0, 0, 0, 0
+}
+
+// NewSearch ages existing entries so they get replaced before fresh ones
+func (t *TranspositionTable) NewSearch() {
+	t.generation++
+}
+
+// Probe looks up a position, scores are adjusted from "mate in n from this
+// node" back to "mate in n from the root" using the current ply
+func (t *TranspositionTable) Probe(key uint64, ply int) (move chess.Move, score, depth int, bound Bound, ok bool) {
+	t.probes++
+	bucket := &t.buckets[key&t.mask]
+	for i := range bucket {
+		e := &bucket[i]
+		if e.bound != BoundNone && e.key == key {
+			t.hits++
+			return e.move, scoreFromTT(int(e.score), ply), int(e.depth), e.bound, true
+		}
+	}
+	return chess.NoMove, 0, 0, BoundNone, false
+}
+
+// Store saves a search result. The first slot keeps the deepest result of the
+// current search, anything else goes to the second slot.
+func (t *TranspositionTable) Store(key uint64, move chess.Move, score, depth int, bound Bound, ply int) {
+	t.stores++
+	bucket := &t.buckets[key&t.mask]
+
+	slot := &bucket[1]
+	if first := &bucket[0]; first.key == key ||
+		first.generation != t.generation ||
+		depth >= int(first.depth) {
+		slot = first
+	}
+
+	if slot.key == key {
+		// Keep the best move of a previous search of the same position
+		if move == chess.NoMove {
+			move = slot.move
+		}
+	} else if slot.bound != BoundNone {
+		t.overwrites++
+	}
+
+	*slot = ttEntry{
+		key:        key,
+		move:       move,
+		score:      int16(scoreToTT(score, ply)),
+		depth:      int8(depth),
+		bound:      bound,
+		generation: t.generation,
+	}
+}
+
+// Stats returns table counters
+func (t *TranspositionTable) Stats() TTStats {
+	size := len(t.buckets) * int(unsafe.Sizeof(ttBucket{}))
+	return TTStats{
+		SizeMB:     size / 1024 / 1024,
+		Entries:    len(t.buckets) * len(ttBucket{}),
+		Probes:     t.probes,
+		Hits:       t.hits,
+		Stores:     t.stores,
+		Overwrites: t.overwrites,
+	}
+}
+
+// Hashfull returns permille of sampled entries used in the current search
+func (t *TranspositionTable) Hashfull() int {
+	samples := 1000 / len(ttBucket{})
+	if samples > len(t.buckets) {
+		samples = len(t.buckets)
+	}
+
+	used := 0
+	for i := 0; i < samples; i++ {
+		for _, e := range t.buckets[i] {
+			if e.bound != BoundNone && e.generation == t.generation {
+				used++
+			}
+		}
+	}
+	return used * 1000 / (samples * len(ttBucket{}))
+}
+
+// scoreToTT converts mate scores relative to the root into scores relative to
+// the node being stored, so they stay correct when reached through another path
+func scoreToTT(score, ply int) int {
+	switch {
+	case score > MateThreshold:
+		return score + ply
+	case score < -MateThreshold:
+		return score - ply
+	}
+	return score
+}
+
+// scoreFromTT reverses scoreToTT
+func scoreFromTT(score, ply int) int {
+	switch {
+	case score > MateThreshold:
+		return score - ply
+	case score < -MateThreshold:
+		return score + ply
+	}
+	return score
+}
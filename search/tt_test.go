@@ -0,0 +1,77 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/RichardKnop/chess-engine/chess"
+)
+
+func TestTranspositionTable(t *testing.T) {
+	tt := NewTranspositionTable(1)
+	m := chess.Move{From: chess.NewSquare(4, 1), To: chess.NewSquare(4, 3)}
+
+	if _, _, _, _, ok := tt.Probe(42, 0); ok {
+		t.Fatal("Empty table should not return an entry")
+	}
+
+	// Mate in 3 plies found at ply 2 is stored as mate in 3 plies from the node
+	tt.Store(42, m, Mate-5, 4, BoundExact, 2)
+
+	move, score, depth, bound, ok := tt.Probe(42, 6)
+	if !ok {
+		t.Fatal("Stored entry not found")
+	}
+	if move != m || depth != 4 || bound != BoundExact {
+		t.Errorf("Unexpected entry: %s depth %d bound %d", move, depth, bound)
+	}
+	// Reached at ply 6 the same mate is 9 plies away from the root
+	if score != Mate-9 {
+		t.Errorf("Mate score %d, expected %d", score, Mate-9)
+	}
+
+	// Storing without a move keeps the previous best move
+	tt.Store(42, chess.NoMove, 10, 5, BoundUpper, 0)
+	if move, _, _, _, _ := tt.Probe(42, 0); move != m {
+		t.Errorf("Best move %s was not preserved", move)
+	}
+
+	stats := tt.Stats()
+	if stats.Probes != 3 || stats.Hits != 2 || stats.Stores != 2 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+	if stats.SizeMB != 1 {
+		t.Errorf("Table size %d MB, expected 1 MB", stats.SizeMB)
+	}
+
+	tt.Clear()
+	if _, _, _, _, ok := tt.Probe(42, 0); ok {
+		t.Error("Cleared table should not return an entry")
+	}
+}
+
+func TestTranspositionTableReplacement(t *testing.T) {
+	tt := NewTranspositionTable(1)
+	// Keys with the same low bits land in the same bucket
+	deep, shallow, other := uint64(1), uint64(1)|1<<40, uint64(1)|1<<41
+
+	tt.Store(deep, chess.NoMove, 0, 10, BoundExact, 0)
+	tt.Store(shallow, chess.NoMove, 0, 2, BoundExact, 0)
+	tt.Store(other, chess.NoMove, 0, 1, BoundExact, 0)
+
+	if _, _, _, _, ok := tt.Probe(deep, 0); !ok {
+		t.Error("Deep entry should stay in the depth-preferred slot")
+	}
+	if _, _, _, _, ok := tt.Probe(other, 0); !ok {
+		t.Error("Latest entry should be in the always-replace slot")
+	}
+	if _, _, _, _, ok := tt.Probe(shallow, 0); ok {
+		t.Error("Shallow entry should have been replaced")
+	}
+
+	// Entries from an older search are replaced regardless of depth
+	tt.NewSearch()
+	tt.Store(shallow, chess.NoMove, 0, 1, BoundExact, 0)
+	if _, _, _, _, ok := tt.Probe(deep, 0); ok {
+		t.Error("Entry from an older search should have been replaced")
+	}
+}
@@ -0,0 +1,69 @@
+package search
+
+import (
+	"time"
+
+	"github.com/RichardKnop/chess-engine/chess"
+)
+
+const (
+	// MaxPly is the deepest ply the search can reach
+	MaxPly = 128
+
+	// Infinity is larger than any score
+	Infinity = 32001
+	// Mate is the score of checkmate delivered at the root
+	Mate = 32000
+	// MateThreshold separates mate scores from ordinary evaluations
+	MateThreshold = Mate - MaxPly
+)
+
+// Limits tells the search when to stop, zero values mean no limit
+type Limits struct {
+	Depth    int
+	Nodes    uint64
+	MoveTime time.Duration
+	// Infinite searches until Stop is called, ignoring other limits
+	Infinite bool
+	// Stop aborts the search once closed. It belongs to a single search, so
+	// it can be closed before the search starts or after it ended without
+	// affecting other searches.
+	Stop <-chan struct{}
+}
+
+// Result is the outcome of a completed iteration of the search
+type Result struct {
+	BestMove chess.Move
+	// Principal variation, the best line found for both sides
+	PV []chess.Move
+	// Score in centipawns from the point of view of the side to move
+	Score int
+	Depth int
+	Nodes uint64
+	Time  time.Duration
+}
+
+// IsMate returns true if the score is a forced mate for either side
+func (r *Result) IsMate() bool {
+	return r.Score > MateThreshold || r.Score < -MateThreshold
+}
+
+// MateIn returns number of moves to mate, negative if the side to move is
+// getting mated, or 0 if the score is not a mate score
+func (r *Result) MateIn() int {
+	switch {
+	case r.Score > MateThreshold:
+		return (Mate - r.Score + 1) / 2
+	case r.Score < -MateThreshold:
+		return -(Mate + r.Score) / 2
+	}
+	return 0
+}
+
+// NPS returns searched nodes per second
+func (r *Result) NPS() uint64 {
+	if r.Time <= 0 {
+		return 0
+	}
+	return uint64(float64(r.Nodes) / r.Time.Seconds())
+}
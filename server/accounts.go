@@ -0,0 +1,135 @@
+package server
+
+import (
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/RichardKnop/chess-engine/auth"
+)
+
+const (
+	minPasswordLength    = 8
+	maxDisplayNameLength = 30
+	// DefaultPieceSet is used by players without an account
+	DefaultPieceSet = "wikipedia"
+)
+
+var (
+	usernamePattern = regexp.MustCompile(`^[a-z0-9_-]{3,20}$`)
+	countryPattern  = regexp.MustCompile(`^[A-Z]{2}$`)
+
+	// pieceSets are piece images available in the client
+	pieceSets = []string{"wikipedia", "alpha", "uscf"}
+)
+
+// Account is a registered player, the account ID is used as player ID
+type Account struct {
+	ID           string `json:"id"`
+	Username     string `json:"username"`
+	PasswordHash string `json:"password_hash"`
+	// Incremented on logout so tokens issued before are no longer valid
+	TokenVersion int       `json:"token_version"`
+	DisplayName  string    `json:"display_name"`
+	Country      string    `json:"country,omitempty"`
+	PieceSet     string    `json:"piece_set"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// Profile is the public part of an account
+type Profile struct {
+	ID          string `json:"id"`
+	Username    string `json:"username"`
+	DisplayName string `json:"display_name"`
+	Country     string `json:"country,omitempty"`
+	PieceSet    string `json:"piece_set"`
+}
+
+// AccountStore persists accounts, usernames are unique
+type AccountStore interface {
+	// CreateAccount inserts a new account, it returns ErrAccountExists if an
+	// account has the same ID and ErrUsernameTaken if one has the username
+	CreateAccount(a *Account) error
+	// SaveAccount inserts or replaces an account, it returns ErrUsernameTaken
+	// if another account has the same username
+	SaveAccount(a *Account) error
+	// LoadAccount returns AccountNotFoundError if the account does not exist
+	LoadAccount(id string) (*Account, error)
+	// FindAccount returns AccountNotFoundError if no account has the username
+	FindAccount(username string) (*Account, error)
+}
+
+// NewAccount validates a username and a password and creates an account,
+// games played before registering stay linked if the player ID is reused
+func NewAccount(id, username, password string) (*Account, error) {
+	username = strings.ToLower(strings.TrimSpace(username))
+	if !usernamePattern.MatchString(username) {
+		return nil, ErrInvalidUsername
+	}
+	if utf8.RuneCountInString(password) < minPasswordLength {
+		return nil, ErrPasswordTooShort
+	}
+	if len(password) > auth.MaxPasswordLength {
+		return nil, ErrPasswordTooLong
+	}
+
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Account{
+		ID:           id,
+		Username:     username,
+		PasswordHash: hash,
+		TokenVersion: 1,
+		DisplayName:  username,
+		PieceSet:     DefaultPieceSet,
+		CreatedAt:    time.Now(),
+	}, nil
+}
+
+// Authenticate returns true if the password is correct
+func (a *Account) Authenticate(password string) bool {
+	ok, err := auth.CheckPassword(a.PasswordHash, password)
+	return ok && err == nil
+}
+
+// UpdateProfile validates and changes the profile
+func (a *Account) UpdateProfile(p *Profile) error {
+	displayName := strings.TrimSpace(p.DisplayName)
+	if displayName == "" || utf8.RuneCountInString(displayName) > maxDisplayNameLength {
+		return ErrInvalidDisplayName
+	}
+	if p.Country != "" && !countryPattern.MatchString(p.Country) {
+		return ErrInvalidCountry
+	}
+	if !contains(pieceSets, p.PieceSet) {
+		return ErrInvalidPieceSet
+	}
+
+	a.DisplayName, a.Country, a.PieceSet = displayName, p.Country, p.PieceSet
+
+	return nil
+}
+
+// Profile returns the public part of the account
+func (a *Account) Profile() *Profile {
+	return &Profile{
+		ID:          a.ID,
+		Username:    a.Username,
+		DisplayName: a.DisplayName,
+		Country:     a.Country,
+		PieceSet:    a.PieceSet,
+	}
+}
+
+func contains(values []string, s string) bool {
+	for _, value := range values {
+		if value == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,97 @@
+package server
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestAccount(t *testing.T) {
+	for _, invalid := range []struct{ username, password string }{
+		{"ab", "long enough"},
+		{"no spaces", "long enough"},
+		{"../etc", "long enough"},
+		{"alice", "short"},
+	} {
+		if _, err := NewAccount("id", invalid.username, invalid.password); err == nil {
+			t.Errorf("account %q with password %q created", invalid.username, invalid.password)
+		}
+	}
+
+	a, err := NewAccount("6ba7b812-9dad-11d1-80b4-00c04fd430c8", " Alice ", "long enough")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Username != "alice" || a.DisplayName != "alice" || a.PieceSet != DefaultPieceSet {
+		t.Errorf("new account %+v", a)
+	}
+	if !a.Authenticate("long enough") || a.Authenticate("long enough ") {
+		t.Error("password not checked")
+	}
+
+	if err := a.UpdateProfile(&Profile{DisplayName: "Alice", Country: "cz", PieceSet: "alpha"}); err != ErrInvalidCountry {
+		t.Errorf("lowercase country returned %v", err)
+	}
+	if err := a.UpdateProfile(&Profile{DisplayName: "Alice", Country: "CZ", PieceSet: "staunton"}); err != ErrInvalidPieceSet {
+		t.Errorf("unknown piece set returned %v", err)
+	}
+	if err := a.UpdateProfile(&Profile{DisplayName: "Alice", Country: "CZ", PieceSet: "alpha"}); err != nil {
+		t.Fatal(err)
+	}
+	if p := a.Profile(); p.DisplayName != "Alice" || p.Country != "CZ" || p.PieceSet != "alpha" {
+		t.Errorf("updated profile %+v", p)
+	}
+
+	dir, err := ioutil.TempDir("", "accounts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fileStore, err := NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, store := range map[string]AccountStore{"memory": NewMemoryStore(), "file": fileStore} {
+		if err := store.CreateAccount(a); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		// Registering again with the same player ID must not take the
+		// account over
+		taken := *a
+		taken.Username = "mallory"
+		if err := store.CreateAccount(&taken); err != ErrAccountExists {
+			t.Errorf("%s: creating an existing account returned %v", name, err)
+		}
+		if err := store.SaveAccount(a); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		other := *a
+		other.ID = "6ba7b813-9dad-11d1-80b4-00c04fd430c8"
+		if err := store.SaveAccount(&other); err != ErrUsernameTaken {
+			t.Errorf("%s: saving a taken username returned %v", name, err)
+		}
+
+		found, err := store.FindAccount("alice")
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if found.ID != a.ID || found.PasswordHash != a.PasswordHash {
+			t.Errorf("%s: found %+v, saved %+v", name, found, a)
+		}
+		if _, err := store.LoadAccount(other.ID); err == nil {
+			t.Errorf("%s: loading a missing account did not fail", name)
+		}
+	}
+
+	// Usernames are indexed from the files after a restart
+	restarted, err := NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found, err := restarted.FindAccount("alice"); err != nil || found.ID != a.ID {
+		t.Errorf("account after restart %+v, %v", found, err)
+	}
+}
@@ -0,0 +1,184 @@
+package server
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// ChatChannelLobby is read by every connected client
+	ChatChannelLobby = "lobby"
+	// ChatChannelPlayers is read by players of a game only
+	ChatChannelPlayers = "players"
+	// ChatChannelGame is read by players and spectators of a game
+	ChatChannelGame = "game"
+
+	// maxChatLength is the longest chat message in characters
+	maxChatLength = 300
+	// chatHistorySize is how many recent messages of a channel are kept
+	chatHistorySize = 50
+
+	// A client can send a burst of chatBurst messages, after that one
+	// message per chatInterval
+	chatBurst    = 5
+	chatInterval = 2 * time.Second
+)
+
+// ChatMessage is a message sent to a chat channel
+type ChatMessage struct {
+	Channel  string    `json:"channel"`
+	PlayerID string    `json:"player_id"`
+	Text     string    `json:"text"`
+	Time     time.Time `json:"time"`
+}
+
+// NewChatMessage validates text of a chat message
+func NewChatMessage(channel, playerID, text string) (*ChatMessage, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, ErrEmptyChatMessage
+	}
+	if utf8.RuneCountInString(text) > maxChatLength {
+		return nil, ErrChatMessageTooLong
+	}
+
+	return &ChatMessage{
+		Channel:  channel,
+		PlayerID: playerID,
+		Text:     text,
+		Time:     time.Now(),
+	}, nil
+}
+
+// chatHistory keeps recent messages of a channel so clients which connect
+// later can catch up
+type chatHistory struct {
+	mu       sync.Mutex
+	messages []*ChatMessage
+}
+
+func (h *chatHistory) add(m *ChatMessage) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.messages = append(h.messages, m)
+	if len(h.messages) > chatHistorySize {
+		h.messages = append([]*ChatMessage(nil), h.messages[len(h.messages)-chatHistorySize:]...)
+	}
+}
+
+func (h *chatHistory) recent() []*ChatMessage {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return append([]*ChatMessage(nil), h.messages...)
+}
+
+// rateLimiter is a token bucket limiting how often a client can chat
+type rateLimiter struct {
+	tokens float64
+	last   time.Time
+}
+
+// allow takes a token if there is one
+func (r *rateLimiter) allow(now time.Time) bool {
+	if r.last.IsZero() {
+		r.tokens = chatBurst
+	} else {
+		r.tokens += float64(now.Sub(r.last)) / float64(chatInterval)
+		if r.tokens > chatBurst {
+			r.tokens = chatBurst
+		}
+	}
+	r.last = now
+
+	if r.tokens < 1 {
+		return false
+	}
+	r.tokens--
+	return true
+}
+
+// Chat sends a message to a channel of the game, spectators can only chat
+// in the channel they can read
+func (g *Game) Chat(c *Client, channel, text string) error {
+	var history *chatHistory
+	switch channel {
+	case ChatChannelPlayers:
+		if !g.IsSeated(c) {
+			return NewNotPlayingError(c.PlayerID, g.ID)
+		}
+		history = &g.playersChat
+	case ChatChannelGame:
+		if !g.IsSeated(c) && !g.Spectators[c] {
+			return NewNotPlayingError(c.PlayerID, g.ID)
+		}
+		history = &g.gameChat
+	default:
+		return NewInvalidChatChannelError(channel)
+	}
+
+	m, err := NewChatMessage(channel, c.PlayerID, text)
+	if err != nil {
+		return err
+	}
+	history.add(m)
+
+	msg := &Message{
+		Type: "chat_message",
+		Data: &MessageData{
+			GameID:   g.ID,
+			Position: g.FEN(),
+			Chat:     []*ChatMessage{m},
+		},
+	}
+	if channel == ChatChannelGame {
+		return g.notifyPlayers(msg)
+	}
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	for _, p := range g.GetPlayers() {
+		p.deliver(data)
+	}
+	return nil
+}
+
+// NotifyChatHistory sends recent messages of channels the client can read
+func (g *Game) NotifyChatHistory(c *Client) error {
+	messages := g.gameChat.recent()
+	if g.IsSeated(c) {
+		messages = mergeChat(g.playersChat.recent(), messages)
+	}
+	if len(messages) == 0 {
+		return nil
+	}
+
+	return c.Notify(&Message{
+		Type: "chat_history",
+		Data: &MessageData{
+			GameID:   g.ID,
+			Position: g.FEN(),
+			Chat:     messages,
+		},
+	})
+}
+
+// mergeChat merges two histories ordered by time
+func mergeChat(a, b []*ChatMessage) []*ChatMessage {
+	merged := make([]*ChatMessage, 0, len(a)+len(b))
+	for len(a) > 0 && len(b) > 0 {
+		if b[0].Time.Before(a[0].Time) {
+			merged, b = append(merged, b[0]), b[1:]
+		} else {
+			merged, a = append(merged, a[0]), a[1:]
+		}
+	}
+	merged = append(merged, a...)
+	return append(merged, b...)
+}
@@ -0,0 +1,92 @@
+package server
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	var r rateLimiter
+	now := time.Now()
+	for i := 0; i < chatBurst; i++ {
+		if !r.allow(now) {
+			t.Fatalf("message %d of a burst not allowed", i+1)
+		}
+	}
+	if r.allow(now) {
+		t.Error("message over the burst allowed")
+	}
+	if !r.allow(now.Add(chatInterval)) {
+		t.Error("message after waiting not allowed")
+	}
+}
+
+func TestGameChat(t *testing.T) {
+	g, err := NewGame("game", InitialPosition, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	alice := &Client{PlayerID: "alice", send: make(chan []byte, 16)}
+	bob := &Client{PlayerID: "bob", send: make(chan []byte, 16)}
+	eve := &Client{PlayerID: "eve", send: make(chan []byte, 16)}
+	g.Join(alice, OrientationWhite)
+	g.Join(bob, OrientationBlack)
+	g.Watch(eve)
+
+	if err := g.Chat(alice, ChatChannelPlayers, " good luck "); err != nil {
+		t.Fatal(err)
+	}
+	if msg := receive(t, bob, "chat_message"); msg.Data.Chat[0].Text != "good luck" {
+		t.Errorf("received chat %+v", msg.Data.Chat[0])
+	}
+	if len(eve.send) != 0 {
+		t.Error("spectator received players chat")
+	}
+
+	if err := g.Chat(eve, ChatChannelGame, "nice opening"); err != nil {
+		t.Fatal(err)
+	}
+	receive(t, alice, "chat_message")
+
+	invalid := []struct {
+		c       *Client
+		channel string
+		text    string
+	}{
+		{eve, ChatChannelPlayers, "hint: Qh5"},
+		{alice, "whisper", "hi"},
+		{alice, ChatChannelGame, "   "},
+		{alice, ChatChannelGame, strings.Repeat("a", maxChatLength+1)},
+	}
+	for _, i := range invalid {
+		if err := g.Chat(i.c, i.channel, i.text); err == nil {
+			t.Errorf("%s sending %q to %s did not fail", i.c.PlayerID, i.text, i.channel)
+		}
+	}
+
+	// Reconnecting players see both channels, spectators only theirs
+	if err := g.NotifyChatHistory(bob); err != nil {
+		t.Fatal(err)
+	}
+	if msg := receive(t, bob, "chat_history"); len(msg.Data.Chat) != 2 || msg.Data.Chat[0].Text != "good luck" {
+		t.Errorf("player chat history %+v", msg.Data.Chat)
+	}
+	if err := g.NotifyChatHistory(eve); err != nil {
+		t.Fatal(err)
+	}
+	if msg := receive(t, eve, "chat_history"); len(msg.Data.Chat) != 1 {
+		t.Errorf("spectator chat history %+v", msg.Data.Chat)
+	}
+}
+
+func TestChatHistorySize(t *testing.T) {
+	var h chatHistory
+	for i := 0; i < chatHistorySize+10; i++ {
+		h.add(&ChatMessage{Text: strings.Repeat("a", i+1)})
+	}
+	recent := h.recent()
+	if len(recent) != chatHistorySize || len(recent[0].Text) != 11 {
+		t.Errorf("history keeps %d messages starting with %q", len(recent), recent[0].Text)
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -21,8 +23,12 @@ const (
 	// Send pings to peer with this period. Must be less than pongWait.
 	pingPeriod = (pongWait * 9) / 10
 
-	// Maximum message size allowed from peer.
-	maxMessageSize = 512
+	// Maximum message size allowed from peer, imported PGN can be long.
+	maxMessageSize = 64 * 1024
+
+	// Network lag not counted against a player's clock is capped so slow
+	// connections cannot be abused to gain time.
+	maxLagCompensation = 500 * time.Millisecond
 )
 
 var (
@@ -34,6 +40,8 @@ var (
 type Client struct {
 	PlayerID    string
 	Orientation string
+	// Display name of players with an account, empty for anonymous players
+	Name string
 
 	// The websocket connection.
 	conn *websocket.Conn
@@ -41,6 +49,19 @@ type Client struct {
 	// Buffered channel of outbound messages.
 	send chan []byte
 
+	// Guards send which games, the lobby and the hub write to from many
+	// goroutines, nothing is sent once the hub closed it
+	mu     sync.Mutex
+	closed bool
+
+	// When the last ping was sent and half of the measured round trip, both
+	// in nanoseconds. Accessed atomically by the read and write pumps.
+	pingSent int64
+	lag      int64
+
+	// Only used by the read pump handling messages of the client
+	chatLimiter rateLimiter
+
 	engine *Engine
 }
 
@@ -50,10 +71,70 @@ func (c *Client) Notify(msg *Message) error {
 	if err != nil {
 		return err
 	}
-	c.send <- data
+	c.deliver(data)
 	return nil
 }
 
+// deliver queues a message without blocking, callers may hold a game lock so
+// messages to a client which is not keeping up or is gone are dropped
+func (c *Client) deliver(data []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return
+	}
+	select {
+	case c.send <- data:
+	default:
+		log.Printf("Dropping message to player %s, the client is not keeping up", c.PlayerID)
+	}
+}
+
+// close closes the send channel, the write pump then closes the connection
+func (c *Client) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.closed {
+		c.closed = true
+		close(c.send)
+	}
+}
+
+// isClosed returns true once the client has disconnected
+func (c *Client) isClosed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.closed
+}
+
+// Lag returns estimated one way network delay between the client and server
+func (c *Client) Lag() time.Duration {
+	return time.Duration(atomic.LoadInt64(&c.lag))
+}
+
+// ping sends a ping to the peer, the pong reply is used to measure lag
+func (c *Client) ping() error {
+	c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+	atomic.StoreInt64(&c.pingSent, time.Now().UnixNano())
+	return c.conn.WriteMessage(websocket.PingMessage, []byte{})
+}
+
+// measureLag updates lag estimate when a pong arrives
+func (c *Client) measureLag() {
+	sent := atomic.LoadInt64(&c.pingSent)
+	if sent == 0 {
+		return
+	}
+	lag := time.Duration(time.Now().UnixNano()-sent) / 2
+	if lag > maxLagCompensation {
+		lag = maxLagCompensation
+	}
+	atomic.StoreInt64(&c.lag, int64(lag))
+}
+
 // ReadPump pumps messages from the websocket connection to the engine/hub.
 //
 // The application runs readPump in a per-connection goroutine. The application
@@ -79,7 +160,11 @@ func (c *Client) ReadPump() (err error) {
 
 	c.conn.SetReadLimit(maxMessageSize)
 	c.conn.SetReadDeadline(time.Now().Add(pongWait))
-	c.conn.SetPongHandler(func(string) error { c.conn.SetReadDeadline(time.Now().Add(pongWait)); return nil })
+	c.conn.SetPongHandler(func(string) error {
+		c.conn.SetReadDeadline(time.Now().Add(pongWait))
+		c.measureLag()
+		return nil
+	})
 
 	for {
 		// Read the message from the websocket
@@ -124,6 +209,11 @@ func (c *Client) WritePump() error {
 		c.conn.Close()
 	}()
 
+	// Measure lag right away rather than after the first ping period
+	if e := c.ping(); e != nil {
+		return e
+	}
+
 	for {
 		select {
 		case message, ok := <-c.send:
@@ -151,23 +241,41 @@ func (c *Client) WritePump() error {
 				return e
 			}
 		case <-ticker.C:
-			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
-			if e := c.conn.WriteMessage(websocket.PingMessage, []byte{}); e != nil {
+			if e := c.ping(); e != nil {
 				return e
 			}
 		}
 	}
 }
 
+// handleMessage dispatches a message, player ID sent by the client is ignored
+// as the player was identified when connecting
 func (c *Client) handleMessage(msg *Message) error {
-	if msg.Data.PlayerID != "" {
-		c.PlayerID = msg.Data.PlayerID
-	}
-
 	handlers := map[string]func(msg *Message) error{
-		"find_game": c.findGame,
-		"get_game":  c.getGame,
-		"make_move": c.makeMove,
+		"join_queue":          c.joinQueue,
+		"leave_queue":         c.leaveQueue,
+		"create_seek":         c.createSeek,
+		"cancel_seek":         c.cancelSeek,
+		"accept_seek":         c.acceptSeek,
+		"challenge_player":    c.challengePlayer,
+		"accept_challenge":    c.acceptChallenge,
+		"decline_challenge":   c.declineChallenge,
+		"play_computer":       c.playComputer,
+		"create_private_game": c.createPrivateGame,
+		"get_game":            c.getGame,
+		"watch_game":          c.watchGame,
+		"make_move":           c.makeMove,
+		"resign":              c.resign,
+		"offer_draw":          c.offerDraw,
+		"accept_draw":         c.acceptDraw,
+		"decline_draw":        c.declineDraw,
+		"request_takeback":    c.requestTakeback,
+		"accept_takeback":     c.acceptTakeback,
+		"export_pgn":          c.exportPGN,
+		"chat_message":        c.chatMessage,
+		"import_game":         c.importGame,
+		"step_forward":        c.stepForward,
+		"step_backward":       c.stepBackward,
 	}
 
 	// Handle message based on its type
@@ -175,15 +283,92 @@ func (c *Client) handleMessage(msg *Message) error {
 	if !ok {
 		return NewUnknownMessageType(msg.Type)
 	}
+	// Handlers read data of every message
+	if msg.Data == nil {
+		c.notifyError(msg, ErrMissingData)
+		return ErrMissingData
+	}
+
+	if err := handler(msg); err != nil {
+		c.notifyError(msg, err)
+		return err
+	}
+
+	return nil
+}
+
+// notifyError sends an error back to the client which sent the message
+func (c *Client) notifyError(msg *Message, err error) {
+	reply := &Message{
+		Type: "error",
+		Data: &MessageData{Error: err.Error()},
+	}
+	if msg.Data != nil {
+		reply.Data.GameID = msg.Data.GameID
+	}
+	if e := c.Notify(reply); e != nil {
+		log.Printf("Error notifying client: %v", e)
+	}
+}
+
+func (c *Client) joinQueue(msg *Message) error {
+	tc, err := ParseTimeControl(msg.Data.TimeControl)
+	if err != nil {
+		return err
+	}
+
+	return c.engine.JoinQueue(c, msg.Data.Orientation, tc, msg.Data.RatingRange)
+}
+
+func (c *Client) leaveQueue(msg *Message) error {
+	return c.engine.LeaveQueue(c)
+}
+
+func (c *Client) createSeek(msg *Message) error {
+	tc, err := ParseTimeControl(msg.Data.TimeControl)
+	if err != nil {
+		return err
+	}
+
+	return c.engine.CreateSeek(c, msg.Data.Orientation, tc, msg.Data.Rated, msg.Data.RatingRange)
+}
+
+func (c *Client) cancelSeek(msg *Message) error {
+	return c.engine.CancelSeek(c, msg.Data.SeekID)
+}
+
+func (c *Client) acceptSeek(msg *Message) error {
+	return c.engine.AcceptSeek(c, msg.Data.SeekID)
+}
+
+func (c *Client) challengePlayer(msg *Message) error {
+	tc, err := ParseTimeControl(msg.Data.TimeControl)
+	if err != nil {
+		return err
+	}
+
+	return c.engine.Challenge(c, msg.Data.PlayerID, msg.Data.Orientation, tc, msg.Data.Rated)
+}
+
+func (c *Client) acceptChallenge(msg *Message) error {
+	return c.engine.AcceptChallenge(c, msg.Data.ChallengeID)
+}
 
-	return handler(msg)
+func (c *Client) declineChallenge(msg *Message) error {
+	return c.engine.DeclineChallenge(c, msg.Data.ChallengeID)
 }
 
-func (c *Client) findGame(msg *Message) error {
-	g, err := c.engine.FindGame(msg.Data.Orientation)
+func (c *Client) playComputer(msg *Message) error {
+	tc, err := ParseTimeControl(msg.Data.TimeControl)
+	if err != nil {
+		return err
+	}
+
+	g, err := c.engine.NewComputerGame(msg.Data.Orientation, msg.Data.Level, tc)
 	if err != nil {
 		return err
 	}
+	defer g.mu.Unlock()
 
 	if err := g.Join(c, msg.Data.Orientation); err != nil {
 		return err
@@ -193,19 +378,61 @@ func (c *Client) findGame(msg *Message) error {
 		return err
 	}
 
-	if len(g.GetPlayers()) == 2 && !g.Started {
-		g.Started = true
-		return g.NotifyGameStarted()
+	g.Started = true
+	if err := g.NotifyGameStarted(); err != nil {
+		return err
 	}
 
+	// Computer playing white moves first
+	g.requestComputerMove()
+
 	return nil
 }
 
+func (c *Client) createPrivateGame(msg *Message) error {
+	tc, err := ParseTimeControl(msg.Data.TimeControl)
+	if err != nil {
+		return err
+	}
+
+	g, err := c.engine.NewPrivateGame(c, msg.Data.Orientation, tc, msg.Data.Rated, msg.Data.PlayerID)
+	if err != nil {
+		return err
+	}
+	defer g.mu.Unlock()
+
+	orientation := OrientationWhite
+	if g.Black == c {
+		orientation = OrientationBlack
+	}
+
+	if err := c.Notify(&Message{
+		Type: "private_game_created",
+		Data: &MessageData{
+			GameID:      g.ID,
+			Position:    g.FEN(),
+			Orientation: orientation,
+			TimeControl: tc.String(),
+			InviteCode:  g.InviteCode,
+			Rated:       g.Rated,
+		},
+	}); err != nil {
+		return err
+	}
+
+	return g.NotifyGameState()
+}
+
 func (c *Client) getGame(msg *Message) error {
 	g, err := c.engine.GetGame(msg.Data.GameID)
 	if err != nil {
 		return err
 	}
+	defer g.mu.Unlock()
+
+	if err := g.Admit(c.PlayerID, msg.Data.InviteCode); err != nil {
+		return err
+	}
 
 	if err := g.Join(c, msg.Data.Orientation); err != nil {
 		return err
@@ -215,24 +442,210 @@ func (c *Client) getGame(msg *Message) error {
 		return err
 	}
 
-	if len(g.GetPlayers()) == 2 {
-		return g.NotifyGameStarted()
+	if err := g.NotifyChatHistory(c); err != nil {
+		return err
+	}
+
+	if g.Imported {
+		return g.NotifyReplayPosition()
+	}
+
+	if g.seatsFilled() {
+		g.Started = true
+		if err := g.NotifyGameStarted(); err != nil {
+			return err
+		}
+	}
+
+	if g.IsOver() {
+		return g.NotifyGameOver()
 	}
 
 	return nil
 }
 
-func (c *Client) makeMove(msg *Message) error {
+func (c *Client) watchGame(msg *Message) error {
 	g, err := c.engine.GetGame(msg.Data.GameID)
 	if err != nil {
 		return err
 	}
+	defer g.mu.Unlock()
+
+	if err := g.Admit(c.PlayerID, msg.Data.InviteCode); err != nil {
+		return err
+	}
+
+	if err := g.Watch(c); err != nil {
+		return err
+	}
+
+	// Spectator counts of everybody in the game change
+	if err := g.NotifyGameState(); err != nil {
+		return err
+	}
+
+	if err := g.NotifyChatHistory(c); err != nil {
+		return err
+	}
+
+	if g.Imported {
+		return g.NotifyReplayPosition()
+	}
+
+	return nil
+}
+
+func (c *Client) makeMove(msg *Message) error {
+	g, err := c.seatedGame(msg.Data.GameID)
+	if err != nil {
+		return err
+	}
+	defer g.mu.Unlock()
 	return g.MakeMove(
-		msg.Data.PlayerID,
+		c.PlayerID,
 		msg.Data.Source,
 		msg.Data.Target,
-		msg.Data.Piece,
-		msg.Data.OldPosition,
-		msg.Data.NewPosition,
+		msg.Data.Promotion,
 	)
 }
+
+func (c *Client) resign(msg *Message) error {
+	g, err := c.seatedGame(msg.Data.GameID)
+	if err != nil {
+		return err
+	}
+	defer g.mu.Unlock()
+	return g.Resign(c.PlayerID)
+}
+
+func (c *Client) offerDraw(msg *Message) error {
+	g, err := c.seatedGame(msg.Data.GameID)
+	if err != nil {
+		return err
+	}
+	defer g.mu.Unlock()
+	return g.OfferDraw(c.PlayerID)
+}
+
+func (c *Client) acceptDraw(msg *Message) error {
+	g, err := c.seatedGame(msg.Data.GameID)
+	if err != nil {
+		return err
+	}
+	defer g.mu.Unlock()
+	return g.AcceptDraw(c.PlayerID)
+}
+
+func (c *Client) declineDraw(msg *Message) error {
+	g, err := c.seatedGame(msg.Data.GameID)
+	if err != nil {
+		return err
+	}
+	defer g.mu.Unlock()
+	return g.DeclineDraw(c.PlayerID)
+}
+
+func (c *Client) requestTakeback(msg *Message) error {
+	g, err := c.seatedGame(msg.Data.GameID)
+	if err != nil {
+		return err
+	}
+	defer g.mu.Unlock()
+	return g.RequestTakeback(c.PlayerID)
+}
+
+func (c *Client) acceptTakeback(msg *Message) error {
+	g, err := c.seatedGame(msg.Data.GameID)
+	if err != nil {
+		return err
+	}
+	defer g.mu.Unlock()
+	return g.AcceptTakeback(c.PlayerID)
+}
+
+func (c *Client) chatMessage(msg *Message) error {
+	if !c.chatLimiter.allow(time.Now()) {
+		return ErrChatRateLimited
+	}
+
+	if msg.Data.Channel == ChatChannelLobby {
+		return c.engine.Chat(c, msg.Data.Text)
+	}
+
+	g, err := c.engine.GetGame(msg.Data.GameID)
+	if err != nil {
+		return err
+	}
+	defer g.mu.Unlock()
+	return g.Chat(c, msg.Data.Channel, msg.Data.Text)
+}
+
+func (c *Client) exportPGN(msg *Message) error {
+	data, err := c.engine.PGN(msg.Data.GameID, c.PlayerID, msg.Data.InviteCode)
+	if err != nil {
+		return err
+	}
+
+	return c.Notify(&Message{
+		Type: "pgn",
+		Data: &MessageData{
+			GameID: msg.Data.GameID,
+			PGN:    data,
+		},
+	})
+}
+
+func (c *Client) importGame(msg *Message) error {
+	g, err := c.engine.ImportGame(msg.Data.PGN)
+	if err != nil {
+		return err
+	}
+	defer g.mu.Unlock()
+
+	if err := g.Join(c, msg.Data.Orientation); err != nil {
+		return err
+	}
+
+	if err := g.NotifyGameState(); err != nil {
+		return err
+	}
+
+	return g.NotifyReplayPosition()
+}
+
+func (c *Client) stepForward(msg *Message) error {
+	g, err := c.seatedGame(msg.Data.GameID)
+	if err != nil {
+		return err
+	}
+	defer g.mu.Unlock()
+	return g.Step(1)
+}
+
+func (c *Client) stepBackward(msg *Message) error {
+	g, err := c.seatedGame(msg.Data.GameID)
+	if err != nil {
+		return err
+	}
+	defer g.mu.Unlock()
+	return g.Step(-1)
+}
+
+// seatedGame returns a locked game the client plays in, spectators are
+// rejected even if they claim the player ID of a player
+func (c *Client) seatedGame(gameID string) (*Game, error) {
+	g, err := c.engine.GetGame(gameID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !g.IsSeated(c) {
+		defer g.mu.Unlock()
+		if g.Spectators[c] {
+			return nil, ErrSpectator
+		}
+		return nil, NewNotPlayingError(c.PlayerID, g.ID)
+	}
+
+	return g, nil
+}
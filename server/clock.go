@@ -0,0 +1,252 @@
+package server
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/RichardKnop/chess-engine/chess"
+)
+
+// Period is a part of a time control. A period with Moves set to zero lasts
+// until the end of the game, otherwise time of the next period is added once
+// the moves have been played. The last period repeats.
+type Period struct {
+	Moves int
+	Time  time.Duration
+	// Increment is added after every move (Fischer)
+	Increment time.Duration
+	// Delay gives back time used for a move up to the delay (Bronstein)
+	Delay time.Duration
+}
+
+// TimeControl is a sequence of periods
+type TimeControl struct {
+	Periods []Period
+}
+
+// ParseTimeControl parses periods separated by a colon where each period is
+// [moves/]seconds[+increment|ddelay], for example "300+3", "900d10" or
+// "40/5400+30:1800+30". An empty string means the game is not timed.
+func ParseTimeControl(s string) (*TimeControl, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	tc := new(TimeControl)
+	for _, field := range strings.Split(s, ":") {
+		var period Period
+
+		if i := strings.Index(field, "/"); i >= 0 {
+			moves, err := strconv.Atoi(field[:i])
+			if err != nil || moves < 1 {
+				return nil, NewInvalidTimeControlError(s)
+			}
+			period.Moves, field = moves, field[i+1:]
+		}
+
+		var extra *time.Duration
+		if i := strings.IndexAny(field, "+d"); i >= 0 {
+			extra = &period.Increment
+			if field[i] == 'd' {
+				extra = &period.Delay
+			}
+			seconds, err := strconv.Atoi(field[i+1:])
+			if err != nil || seconds < 0 {
+				return nil, NewInvalidTimeControlError(s)
+			}
+			*extra, field = time.Duration(seconds)*time.Second, field[:i]
+		}
+
+		seconds, err := strconv.Atoi(field)
+		if err != nil || seconds < 0 || (seconds == 0 && extra == nil) {
+			return nil, NewInvalidTimeControlError(s)
+		}
+		period.Time = time.Duration(seconds) * time.Second
+
+		tc.Periods = append(tc.Periods, period)
+	}
+
+	return tc, nil
+}
+
+// String formats the time control the way ParseTimeControl reads it
+func (tc *TimeControl) String() string {
+	if tc == nil {
+		return ""
+	}
+
+	periods := make([]string, len(tc.Periods))
+	for i, p := range tc.Periods {
+		s := strconv.Itoa(int(p.Time / time.Second))
+		if p.Moves > 0 {
+			s = fmt.Sprintf("%d/%s", p.Moves, s)
+		}
+		if p.Increment > 0 {
+			s += fmt.Sprintf("+%d", p.Increment/time.Second)
+		}
+		if p.Delay > 0 {
+			s += fmt.Sprintf("d%d", p.Delay/time.Second)
+		}
+		periods[i] = s
+	}
+	return strings.Join(periods, ":")
+}
+
+// Clock keeps time of both players. The server is authoritative, times sent
+// by clients are never trusted.
+type Clock struct {
+	control *TimeControl
+
+	remaining [2]time.Duration
+	// Current period of each player and moves played in it
+	period [2]int
+	moves  [2]int
+
+	// The clock starts running after the first move
+	running   bool
+	turn      chess.Color
+	turnStart time.Time
+}
+
+// ClockState is the clock as reported to clients, times are in milliseconds
+type ClockState struct {
+	White   int64  `json:"white"`
+	Black   int64  `json:"black"`
+	Turn    string `json:"turn"`
+	Running bool   `json:"running"`
+}
+
+// NewClock creates a new clock with the side to move in the initial position
+func NewClock(tc *TimeControl, turn chess.Color) *Clock {
+	c := &Clock{control: tc, turn: turn}
+	c.remaining[chess.White] = tc.Periods[0].Time
+	c.remaining[chess.Black] = tc.Periods[0].Time
+	return c
+}
+
+// Remaining returns time left to a player at the given moment
+func (c *Clock) Remaining(color chess.Color, now time.Time) time.Duration {
+	remaining := c.remaining[color]
+	if c.running && c.turn == color {
+		remaining -= now.Sub(c.turnStart)
+	}
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
+// Flagged returns true if the player on the move has run out of time
+func (c *Clock) Flagged(now time.Time) bool {
+	return c.running && c.Remaining(c.turn, now) == 0
+}
+
+// Punch is called after a move, it stops the clock of the player who moved
+// and starts the opponent's. Network lag of the player is not counted as time
+// used. Returns false if the player ran out of time before moving.
+func (c *Clock) Punch(now time.Time, lag time.Duration) bool {
+	mover := c.turn
+	c.turn = mover.Other()
+
+	if !c.running {
+		c.running = true
+		c.turnStart = now
+		c.countMove(mover)
+		return true
+	}
+
+	used := now.Sub(c.turnStart) - lag
+	if used < 0 {
+		used = 0
+	}
+	c.turnStart = now
+
+	if used >= c.remaining[mover] {
+		c.remaining[mover] = 0
+		c.turn = mover
+		c.running = false
+		return false
+	}
+
+	period := c.control.Periods[c.period[mover]]
+	c.remaining[mover] -= used
+	c.remaining[mover] += period.Increment
+	if used < period.Delay {
+		c.remaining[mover] += used
+	} else {
+		c.remaining[mover] += period.Delay
+	}
+	c.countMove(mover)
+
+	return true
+}
+
+// Stop freezes both clocks, called when the game is over
+func (c *Clock) Stop(now time.Time) {
+	if !c.running {
+		return
+	}
+	c.remaining[c.turn] = c.Remaining(c.turn, now)
+	c.running = false
+}
+
+// Rewind sets the clock back after moves were taken back. Moves counts moves
+// each player has left on the board and remaining is the time each of them
+// had after their last one. The player on the move starts thinking now, the
+// clock stays paused when no move is left.
+func (c *Clock) Rewind(turn chess.Color, moves [2]int, remaining [2]time.Duration, now time.Time) {
+	for _, color := range [2]chess.Color{chess.White, chess.Black} {
+		c.period[color], c.moves[color] = 0, 0
+		for i := 0; i < moves[color]; i++ {
+			c.countMove(color)
+		}
+		c.remaining[color] = remaining[color]
+	}
+	c.turn = turn
+	c.running = moves[chess.White]+moves[chess.Black] > 0
+	c.turnStart = now
+}
+
+// MovesToGo returns moves a player has to make before more time is added,
+// zero means the rest of the game
+func (c *Clock) MovesToGo(color chess.Color) int {
+	period := c.control.Periods[c.period[color]]
+	if period.Moves == 0 {
+		return 0
+	}
+	return period.Moves - c.moves[color]
+}
+
+// Increment returns time a player gets back after making a move
+func (c *Clock) Increment(color chess.Color) time.Duration {
+	period := c.control.Periods[c.period[color]]
+	return period.Increment + period.Delay
+}
+
+// State returns the clock as reported to clients
+func (c *Clock) State(now time.Time) *ClockState {
+	return &ClockState{
+		White:   int64(c.Remaining(chess.White, now) / time.Millisecond),
+		Black:   int64(c.Remaining(chess.Black, now) / time.Millisecond),
+		Turn:    c.turn.String(),
+		Running: c.running,
+	}
+}
+
+// countMove moves a player into the next period once enough moves are played
+func (c *Clock) countMove(color chess.Color) {
+	c.moves[color]++
+
+	period := c.control.Periods[c.period[color]]
+	if period.Moves == 0 || c.moves[color] < period.Moves {
+		return
+	}
+
+	if c.period[color]+1 < len(c.control.Periods) {
+		c.period[color]++
+	}
+	c.moves[color] = 0
+	c.remaining[color] += c.control.Periods[c.period[color]].Time
+}
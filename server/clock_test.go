@@ -0,0 +1,104 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/RichardKnop/chess-engine/chess"
+)
+
+func TestParseTimeControl(t *testing.T) {
+	valid := []string{"300", "300+3", "900d10", "40/5400+30:1800+30", "0+1"}
+	for _, s := range valid {
+		tc, err := ParseTimeControl(s)
+		if err != nil {
+			t.Errorf("%s: %v", s, err)
+			continue
+		}
+		if tc.String() != s {
+			t.Errorf("%s: formatted as %s", s, tc.String())
+		}
+	}
+
+	invalid := []string{"abc", "0", "-5", "300+", "40/", "0/300", "300+x", "300:"}
+	for _, s := range invalid {
+		if _, err := ParseTimeControl(s); err == nil {
+			t.Errorf("%s: expected an error", s)
+		}
+	}
+}
+
+func TestClock(t *testing.T) {
+	start := time.Now()
+	at := func(seconds int) time.Time {
+		return start.Add(time.Duration(seconds) * time.Second)
+	}
+
+	t.Run("fischer increment", func(t *testing.T) {
+		tc, _ := ParseTimeControl("60+2")
+		c := NewClock(tc, chess.White)
+
+		// The clock starts after the first move
+		c.Punch(at(10), 0)
+		if got := c.Remaining(chess.White, at(10)); got != 60*time.Second {
+			t.Errorf("white has %s after the first move", got)
+		}
+
+		c.Punch(at(20), 0)
+		if got := c.Remaining(chess.Black, at(20)); got != 52*time.Second {
+			t.Errorf("black has %s, want 52s", got)
+		}
+		if got := c.Remaining(chess.White, at(25)); got != 55*time.Second {
+			t.Errorf("white has %s while thinking, want 55s", got)
+		}
+	})
+
+	t.Run("bronstein delay", func(t *testing.T) {
+		tc, _ := ParseTimeControl("60d5")
+		c := NewClock(tc, chess.White)
+		c.Punch(at(0), 0)
+		c.Punch(at(3), 0)
+		c.Punch(at(13), 0)
+		if got := c.Remaining(chess.Black, at(13)); got != 60*time.Second {
+			t.Errorf("black has %s, want 60s", got)
+		}
+		if got := c.Remaining(chess.White, at(13)); got != 55*time.Second {
+			t.Errorf("white has %s, want 55s", got)
+		}
+	})
+
+	t.Run("multiple periods", func(t *testing.T) {
+		tc, _ := ParseTimeControl("2/60:30")
+		c := NewClock(tc, chess.White)
+		c.Punch(at(0), 0)
+		c.Punch(at(10), 0)
+		if got := c.MovesToGo(chess.White); got != 1 {
+			t.Errorf("white has %d moves to go, want 1", got)
+		}
+		c.Punch(at(20), 0)
+		if got := c.Remaining(chess.White, at(20)); got != 80*time.Second {
+			t.Errorf("white has %s after the time control, want 80s", got)
+		}
+		if got := c.MovesToGo(chess.White); got != 0 {
+			t.Errorf("white has %d moves to go in sudden death", got)
+		}
+	})
+
+	t.Run("lag compensation and flag", func(t *testing.T) {
+		tc, _ := ParseTimeControl("10")
+		c := NewClock(tc, chess.White)
+		c.Punch(at(0), 0)
+		if !c.Punch(at(10), time.Second) {
+			t.Error("black flagged although lag was compensated")
+		}
+		if c.Flagged(at(9)) {
+			t.Error("white flagged early")
+		}
+		if !c.Flagged(at(20)) {
+			t.Error("white did not flag")
+		}
+		if c.Punch(at(20), 0) {
+			t.Error("a move after the flag was accepted")
+		}
+	})
+}
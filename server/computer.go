@@ -0,0 +1,135 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/RichardKnop/chess-engine/chess"
+	"github.com/RichardKnop/chess-engine/search"
+)
+
+const (
+	// DefaultComputerLevel is used when a client does not choose a level
+	DefaultComputerLevel = 3
+
+	// computerHashSize is transposition table size of each computer player
+	computerHashSize = 4
+
+	// drawScore is how bad the computer has to think its position is before
+	// it accepts a draw offer
+	drawScore = -50
+)
+
+// computerLevels limit how deep and how long the computer thinks, level 1 is
+// the weakest and the last level is the strongest
+var computerLevels = []search.Limits{
+	{Depth: 1, MoveTime: 100 * time.Millisecond},
+	{Depth: 2, MoveTime: 200 * time.Millisecond},
+	{Depth: 3, MoveTime: 300 * time.Millisecond},
+	{Depth: 4, MoveTime: 500 * time.Millisecond},
+	{Depth: 6, MoveTime: 1 * time.Second},
+	{Depth: 8, MoveTime: 2 * time.Second},
+	{Depth: 12, MoveTime: 3 * time.Second},
+	{MoveTime: 5 * time.Second},
+}
+
+// ComputerPlayer is an in-process engine playing one side of a game
+type ComputerPlayer struct {
+	PlayerID string
+	Color    chess.Color
+	Level    int
+
+	// Only one search runs at a time
+	mu       sync.Mutex
+	searcher *search.Searcher
+	// Score of the last search from the computer's point of view, guarded
+	// by the lock of the game
+	lastScore int
+}
+
+// NewComputerPlayer creates a new computer player of a given strength
+func NewComputerPlayer(color chess.Color, level int) (*ComputerPlayer, error) {
+	if level == 0 {
+		level = DefaultComputerLevel
+	}
+	if level < 1 || level > len(computerLevels) {
+		return nil, NewInvalidLevelError(level, len(computerLevels))
+	}
+
+	searcher := search.New()
+	searcher.SetHashSize(computerHashSize)
+
+	return &ComputerPlayer{
+		PlayerID: fmt.Sprintf("computer-%d", level),
+		Color:    color,
+		Level:    level,
+		searcher: searcher,
+	}, nil
+}
+
+// limits returns search limits of the computer's level, thinking for less
+// time if the game clock requires it
+func (cp *ComputerPlayer) limits(g *Game) search.Limits {
+	limits := computerLevels[cp.Level-1]
+	if g.Clock == nil {
+		return limits
+	}
+
+	budget := search.AllocateTime(
+		g.Clock.Remaining(cp.Color, time.Now()),
+		g.Clock.Increment(cp.Color),
+		g.Clock.MovesToGo(cp.Color),
+	)
+	if limits.MoveTime == 0 || budget < limits.MoveTime {
+		limits.MoveTime = budget
+	}
+	return limits
+}
+
+// Play searches the current position and plays the best move found, the
+// game is only locked before and after the search
+func (cp *ComputerPlayer) Play(g *Game) error {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+
+	g.mu.Lock()
+	position := g.Position
+	limits := cp.limits(g)
+	g.mu.Unlock()
+
+	if position.Turn() != cp.Color {
+		return NewNotYourTurnError(cp.PlayerID)
+	}
+
+	result := cp.searcher.Search(position, limits)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if result.BestMove == chess.NoMove {
+		// Checkmate or stalemate, there is nothing to play
+		return nil
+	}
+	cp.lastScore = result.Score
+
+	if g.Position != position {
+		// Moves were taken back while thinking
+		return nil
+	}
+
+	log.Printf("Computer %s found %s (depth %d, score %d)", cp.PlayerID, result.BestMove, result.Depth, result.Score)
+
+	m := result.BestMove
+	promotion := ""
+	if m.Promotion != chess.NoPieceType {
+		promotion = string(m.Promotion.Char())
+	}
+	return g.MakeMove(cp.PlayerID, m.From.String(), m.To.String(), promotion)
+}
+
+// AcceptsDraw returns true if the computer thinks it is worse
+func (cp *ComputerPlayer) AcceptsDraw() bool {
+	return cp.lastScore <= drawScore
+}
@@ -0,0 +1,183 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/RichardKnop/chess-engine/chess"
+	"github.com/RichardKnop/chess-engine/fen"
+	"github.com/RichardKnop/chess-engine/search"
+)
+
+// checkLegal checks a move announced in move_made was legal in the position
+func checkLegal(t *testing.T, position string, msg *Message) {
+	p, err := fen.Parse(position)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := parseMove(p, msg.Data.Source, msg.Data.Target, msg.Data.Promotion)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !p.IsLegal(m) {
+		t.Errorf("computer played illegal move %s in %s", m, position)
+	}
+}
+
+func TestComputerReplies(t *testing.T) {
+	e := NewEngine(NewMemoryStore())
+	go e.Run()
+
+	server, url := serveEngine(e)
+	defer server.Close()
+
+	// The computer replies to a move of the player
+	alice := dialEngine(t, url, "alice")
+	defer alice.conn.Close()
+	if err := alice.send("play_computer", &MessageData{Orientation: OrientationWhite, Level: 1}); err != nil {
+		t.Fatal(err)
+	}
+	started, err := alice.await("game_started")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := alice.send("make_move", &MessageData{GameID: started.Data.GameID, Source: "e2", Target: "e4"}); err != nil {
+		t.Fatal(err)
+	}
+	own, err := alice.await("move_made")
+	if err != nil {
+		t.Fatal(err)
+	}
+	reply, err := alice.await("move_made")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply.Data.PlayerID != "computer-1" {
+		t.Errorf("reply made by %s", reply.Data.PlayerID)
+	}
+	checkLegal(t, own.Data.Position, reply)
+
+	// The computer moves first when it plays white
+	bob := dialEngine(t, url, "bob")
+	defer bob.conn.Close()
+	if err := bob.send("play_computer", &MessageData{Orientation: OrientationBlack, Level: 1}); err != nil {
+		t.Fatal(err)
+	}
+	first, err := bob.await("move_made")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkLegal(t, InitialPosition, first)
+}
+
+func TestComputerLimits(t *testing.T) {
+	cp, err := NewComputerPlayer(chess.Black, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cp.Level != DefaultComputerLevel {
+		t.Errorf("default level %d, want %d", cp.Level, DefaultComputerLevel)
+	}
+	for _, level := range []int{-1, len(computerLevels) + 1} {
+		if _, err := NewComputerPlayer(chess.Black, level); err == nil {
+			t.Errorf("level %d was accepted", level)
+		} else if _, ok := err.(*InvalidLevelError); !ok {
+			t.Errorf("level %d returned %v", level, err)
+		}
+	}
+
+	tc, _ := ParseTimeControl("60")
+	untimed, err := NewGame("untimed", InitialPosition, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	timed, err := NewGame("timed", InitialPosition, tc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	budget := search.AllocateTime(time.Minute, 0, 0)
+	for level := 1; level <= len(computerLevels); level++ {
+		cp, err := NewComputerPlayer(chess.Black, level)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if limits := cp.limits(untimed); limits != computerLevels[level-1] {
+			t.Errorf("level %d: untimed game limits %+v", level, limits)
+		}
+
+		// The clock cuts thinking time short but never extends it
+		limits := cp.limits(timed)
+		if limits.Depth != computerLevels[level-1].Depth {
+			t.Errorf("level %d: timed game searches to depth %d", level, limits.Depth)
+		}
+		if limits.MoveTime <= 0 || limits.MoveTime > budget {
+			t.Errorf("level %d: thinks for %s with a minute on the clock", level, limits.MoveTime)
+		}
+		if max := computerLevels[level-1].MoveTime; max > 0 && limits.MoveTime > max {
+			t.Errorf("level %d: thinks for %s, more than %s", level, limits.MoveTime, max)
+		}
+	}
+
+	// Weak levels do not search deeper than allowed
+	for level := 1; level <= 3; level++ {
+		cp, _ := NewComputerPlayer(chess.White, level)
+		if result := cp.searcher.Search(untimed.Position, cp.limits(untimed)); result.Depth > level {
+			t.Errorf("level %d searched to depth %d", level, result.Depth)
+		}
+	}
+}
+
+func TestComputerGameOver(t *testing.T) {
+	// newComputerGame sets up a game where alice plays white against the
+	// computer playing black
+	newComputerGame := func(position string) (*Game, *Client) {
+		e := NewEngine(NewMemoryStore())
+		g, err := e.newGame(position, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer g.mu.Unlock()
+
+		if g.Computer, err = NewComputerPlayer(chess.Black, 2); err != nil {
+			t.Fatal(err)
+		}
+		alice := &Client{PlayerID: "alice", send: make(chan []byte, 16), engine: e}
+		if err := g.Join(alice, OrientationWhite); err != nil {
+			t.Fatal(err)
+		}
+		g.Started = true
+		return g, alice
+	}
+
+	t.Run("computer mates", func(t *testing.T) {
+		g, alice := newComputerGame("rnbqkbnr/pppp1ppp/8/4p3/6P1/5P2/PPPPP2P/RNBQKBNR b KQkq - 0 2")
+		if err := g.Computer.Play(g); err != nil {
+			t.Fatal(err)
+		}
+		if msg := receive(t, alice, "game_over"); msg.Data.Result != ResultBlackWins || msg.Data.Reason != ReasonCheckmate {
+			t.Errorf("computer's mate ended the game %s by %s", msg.Data.Result, msg.Data.Reason)
+		}
+	})
+
+	t.Run("computer is mated", func(t *testing.T) {
+		g, alice := newComputerGame("r1bqkb1r/pppp1ppp/2n2n2/4p2Q/2B1P3/8/PPPP1PPP/RNB1K1NR w KQkq - 4 4")
+		g.mu.Lock()
+		err := g.MakeMove("alice", "h5", "f7", "")
+		g.mu.Unlock()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if msg := receive(t, alice, "game_over"); msg.Data.Result != ResultWhiteWins || msg.Data.Reason != ReasonCheckmate {
+			t.Errorf("mating the computer ended the game %s by %s", msg.Data.Result, msg.Data.Reason)
+		}
+
+		// There is nothing left for the computer to play
+		if err := g.Computer.Play(g); err != nil {
+			t.Fatal(err)
+		}
+		if len(g.Moves) != 1 || g.Result != ResultWhiteWins {
+			t.Errorf("computer changed a finished game: %d moves, result %s", len(g.Moves), g.Result)
+		}
+	})
+}
@@ -1,8 +1,13 @@
 package server
 
 import (
+	"encoding/json"
 	"log"
+	"sync"
+	"time"
 
+	"github.com/RichardKnop/chess-engine/chess"
+	"github.com/RichardKnop/chess-engine/pgn"
 	"github.com/gorilla/websocket"
 	"github.com/satori/go.uuid"
 )
@@ -11,106 +16,403 @@ import (
 type Engine struct {
 	hub *Hub
 
-	// Active games
+	// Active games, other games are loaded from the store when requested.
+	// The lock is always taken before the lock of a game.
+	mu    sync.Mutex
 	games map[string]*Game
+	store Store
+
+	// Recent messages of the lobby chat
+	lobbyChat chatHistory
+
+	// Pairs players looking for a game
+	matchmaker *Matchmaker
+
+	// Online players with their seeks and challenges
+	lobby *Lobby
 }
 
 // NewEngine creates a new instance of Engine
-func NewEngine() *Engine {
-	return &Engine{
+func NewEngine(store Store) *Engine {
+	e := &Engine{
 		hub:   NewHub(),
 		games: make(map[string]*Game, 0),
+		store: store,
+		lobby: NewLobby(),
 	}
+	e.matchmaker = NewMatchmaker(e.startMatch)
+	return e
 }
 
-// Run starts the hub
+// Run starts the hub and the matchmaker
 func (e *Engine) Run() {
+	go e.matchmaker.Run()
 	e.hub.Run()
 }
 
-// NewClient creates a new instance of Client
-func (e *Engine) NewClient(conn *websocket.Conn) *Client {
+// NewClient creates a new instance of Client for an authenticated player,
+// the player ID cannot change for the lifetime of the connection
+func (e *Engine) NewClient(conn *websocket.Conn, playerID, name string) *Client {
 	client := &Client{
-		conn:   conn,
-		send:   make(chan []byte, 256),
-		engine: e,
+		PlayerID: playerID,
+		Name:     name,
+		conn:     conn,
+		send:     make(chan []byte, 256),
+		engine:   e,
 	}
 
 	e.hub.register <- client
+	e.lobby.Enter(client)
+
+	// Catch up with the lobby chat
+	if messages := e.lobbyChat.recent(); len(messages) > 0 {
+		client.Notify(&Message{
+			Type: "chat_history",
+			Data: &MessageData{Chat: messages},
+		})
+	}
+	client.Notify(e.seeksMessage())
 
 	return client
 }
 
-// FindGame returns in memory game state
-func (e *Engine) FindGame(orientation string) (*Game, error) {
-	log.Printf("Finding a game for a player with %s pieces", orientation)
+// Chat sends a message to the lobby chat read by every connected client
+func (e *Engine) Chat(c *Client, text string) error {
+	m, err := NewChatMessage(ChatChannelLobby, c.PlayerID, text)
+	if err != nil {
+		return err
+	}
+	e.lobbyChat.add(m)
 
-	for _, game := range e.games {
-		if game.White != nil && game.Black != nil {
-			continue
-		}
+	data, err := json.Marshal(&Message{
+		Type: "chat_message",
+		Data: &MessageData{Chat: []*ChatMessage{m}},
+	})
+	if err != nil {
+		return err
+	}
+	e.hub.broadcast <- data
 
-		switch orientation {
-		case OrientationWhite:
-			if game.White != nil {
-				continue
-			}
-		case OrientationBlack:
-			if game.Black != nil {
-				continue
+	return nil
+}
+
+// JoinQueue puts a player in the matchmaking queue, the player is notified
+// with match_found once an opponent is found
+func (e *Engine) JoinQueue(c *Client, orientation string, tc *TimeControl, ratingRange int) error {
+	switch orientation {
+	case "", OrientationWhite, OrientationBlack:
+	default:
+		return ErrInvalidOrientation
+	}
+
+	s := &Seeker{
+		Client:      c,
+		TimeControl: tc,
+		Orientation: orientation,
+		Rating:      e.playerRating(c.PlayerID, tc),
+		RatingRange: float64(ratingRange),
+		Joined:      time.Now(),
+	}
+
+	if err := c.Notify(&Message{
+		Type: "queue_joined",
+		Data: &MessageData{
+			TimeControl: tc.String(),
+			Orientation: orientation,
+		},
+	}); err != nil {
+		return err
+	}
+
+	e.matchmaker.Enter(s)
+
+	return nil
+}
+
+// LeaveQueue takes a player out of the matchmaking queue
+func (e *Engine) LeaveQueue(c *Client) error {
+	if !e.matchmaker.Leave(c) {
+		return ErrNotInQueue
+	}
+	return c.Notify(&Message{Type: "queue_left", Data: new(MessageData)})
+}
+
+// startMatch creates a game for a pair found by the matchmaker
+func (e *Engine) startMatch(white, black *Seeker) {
+	// Either player may have disconnected since the pair was found or while
+	// the game was being set up, the other one goes back to the queue
+	offline := white.Client.isClosed() || black.Client.isClosed()
+	if !offline {
+		err := e.startGame(white.Client, black.Client, white.TimeControl, true)
+		if _, offline = err.(*PlayerOfflineError); !offline && err != nil {
+			log.Printf("Error starting a game of %s and %s: %v", white.Client.PlayerID, black.Client.PlayerID, err)
+		}
+	}
+	if offline {
+		for _, s := range []*Seeker{white, black} {
+			if !s.Client.isClosed() {
+				e.matchmaker.Return(s)
 			}
 		}
+	}
+}
 
-		return game, nil
+// startGame creates a game of two players who agreed to play, only timed
+// games can be rated. PlayerOfflineError is returned when either player
+// disconnects before the game starts.
+func (e *Engine) startGame(white, black *Client, tc *TimeControl, rated bool) error {
+	g, err := e.newGame(InitialPosition, tc)
+	if err != nil {
+		return err
 	}
+	// Deferred calls run in reverse order, the game is unloaded once unlocked
+	var dropped bool
+	defer func() {
+		if dropped {
+			e.unloadGame(g)
+		}
+	}()
+	defer g.mu.Unlock()
+	g.Rated = rated && tc.Category() != ""
 
-	log.Print("Suitable game not found, creating a new game")
+	if err := g.Join(white, OrientationWhite); err != nil {
+		return err
+	}
+	if err := g.Join(black, OrientationBlack); err != nil {
+		return err
+	}
 
-	return e.newGame(InitialPosition)
+	// A player who disconnected before joining is not removed from the game
+	// when the disconnect is handled, so nobody would play it
+	for _, c := range []*Client{white, black} {
+		if c.isClosed() {
+			g.White, g.Black, dropped = nil, nil, true
+			return NewPlayerOfflineError(c.PlayerID)
+		}
+	}
+	g.Started = true
+
+	log.Printf("Matched %s and %s in game %s", white.PlayerID, black.PlayerID, g.ID)
+
+	for _, c := range []*Client{white, black} {
+		orientation := OrientationWhite
+		if c == black {
+			orientation = OrientationBlack
+		}
+		if err := c.Notify(&Message{
+			Type: "match_found",
+			Data: &MessageData{
+				GameID:      g.ID,
+				Position:    g.FEN(),
+				Orientation: orientation,
+				TimeControl: tc.String(),
+				Rated:       g.Rated,
+			},
+		}); err != nil {
+			return err
+		}
+	}
+
+	if err := g.NotifyGameState(); err != nil {
+		return err
+	}
+	return g.NotifyGameStarted()
+}
+
+// NewComputerGame creates a new game where the computer plays against
+// a player with pieces of the given orientation, the game is returned locked
+func (e *Engine) NewComputerGame(orientation string, level int, tc *TimeControl) (*Game, error) {
+	var color chess.Color
+	switch orientation {
+	case OrientationWhite:
+		color = chess.Black
+	case OrientationBlack:
+		color = chess.White
+	default:
+		return nil, ErrInvalidOrientation
+	}
+
+	computer, err := NewComputerPlayer(color, level)
+	if err != nil {
+		return nil, err
+	}
+
+	g, err := e.newGame(InitialPosition, tc)
+	if err != nil {
+		return nil, err
+	}
+	g.Computer = computer
+	g.save()
+
+	log.Printf("Computer level %d plays %s pieces in game %s", level, color, g.ID)
+
+	return g, nil
 }
 
 // ClientDisconnected is called when a client disconnects
 func (e *Engine) ClientDisconnected(c *Client) error {
+	// Closed first so a pair the matchmaker has just found is not started
+	c.close()
+	e.matchmaker.Leave(c)
+	e.leaveLobby(c)
+
+	e.mu.Lock()
 	// Remove the client from all game instances
 	for gameID, g := range e.games {
+		g.mu.Lock()
 		g.Leave(c)
 
-		if len(g.GetPlayers()) == 0 {
-			log.Printf("Deleting game %s", gameID)
+		// The game stays in the store and is loaded again when a player
+		// comes back, games waiting for a reconnect have to stay in memory
+		if g.isIdle() {
+			log.Printf("Unloading game %s", gameID)
 			delete(e.games, gameID)
 		}
+		g.mu.Unlock()
 	}
+	e.mu.Unlock()
 
 	c.engine.hub.unregister <- c
 
 	return nil
 }
 
-// GetGame returns in memory game state
+// GetGame returns in memory game state, loading the game from the store if
+// it is not active. The game is returned locked and the caller has to unlock
+// it. It is locked before the engine is unlocked, so a disconnecting client
+// cannot unload it in between and only decides to unload it after the caller
+// is done.
 func (e *Engine) GetGame(gameID string) (*Game, error) {
-	g, ok := e.games[gameID]
-	if !ok {
-		return nil, NewGameNotFoundError(gameID)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if g, ok := e.games[gameID]; ok {
+		g.mu.Lock()
+		return g, nil
+	}
+
+	r, err := e.store.Load(gameID)
+	if err != nil {
+		return nil, err
 	}
+
+	g, err := RestoreGame(r)
+	if err != nil {
+		return nil, err
+	}
+	g.store = e.store
+	g.mu.Lock()
+	e.games[gameID] = g
+
+	log.Printf("Loaded game %s with %d moves", gameID, len(g.Moves))
+
 	return g, nil
 }
 
-// newGame creates a new game with blank state
-func (e *Engine) newGame(position string) (*Game, error) {
-	gameID := uuid.NewV4().String()
-	_, ok := e.games[gameID]
+// ImportGame creates a game which can be replayed from the first game in
+// PGN text, the game is returned locked
+func (e *Engine) ImportGame(data string) (*Game, error) {
+	games, err := pgn.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(games) == 0 {
+		return nil, ErrNoGameInPGN
+	}
+
+	g, err := NewImportedGame(uuid.NewV4().String(), games[0])
+	if err != nil {
+		return nil, err
+	}
+	g.store = e.store
+	g.save()
+	if err := e.addGame(g); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Imported game %s with %d moves", g.ID, len(g.Moves))
+
+	return g, nil
+}
+
+// PGN exports a game, games which are not active are read from the store
+// without loading them. Private games are only exported to their players and
+// players with the invite code, they are not found for anybody else.
+func (e *Engine) PGN(gameID, playerID, inviteCode string) (string, error) {
+	e.mu.Lock()
+	g, ok := e.games[gameID]
+	if ok {
+		g.mu.Lock()
+	}
+	e.mu.Unlock()
 
-	// This should never happen (UUIDs should be unique) but just in case
 	if ok {
-		return nil, NewGameAlreadyExistsError(gameID)
+		defer g.mu.Unlock()
+	} else {
+		r, err := e.store.Load(gameID)
+		if err != nil {
+			return "", err
+		}
+
+		if g, err = RestoreGame(r); err != nil {
+			return "", err
+		}
 	}
 
-	// Create a new game
-	g, err := NewGame(gameID, position)
+	if err := g.Admit(playerID, inviteCode); err != nil {
+		return "", NewGameNotFoundError(gameID)
+	}
+
+	return g.PGN()
+}
+
+// newGame creates a new game with blank state, the game is returned locked so
+// nobody sees it before it is set up
+func (e *Engine) newGame(position string, tc *TimeControl) (*Game, error) {
+	g, err := NewGame(uuid.NewV4().String(), position, tc)
 	if err != nil {
 		return nil, err
 	}
-	e.games[gameID] = g
+	g.store = e.store
+	g.save()
+	if err := e.addGame(g); err != nil {
+		return nil, err
+	}
 
 	return g, nil
 }
+
+// addGame locks a new game and makes it active
+func (e *Engine) addGame(g *Game) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	// This should never happen (UUIDs should be unique) but just in case
+	if _, ok := e.games[g.ID]; ok {
+		return NewGameAlreadyExistsError(g.ID)
+	}
+
+	g.mu.Lock()
+	e.games[g.ID] = g
+	g.unload = func() { e.unloadGame(g) }
+
+	return nil
+}
+
+// unloadGame removes a game nobody is in from memory, it stays in the store
+// and is loaded again when somebody opens it
+func (e *Engine) unloadGame(g *Game) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.games[g.ID] != g {
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.isIdle() {
+		log.Printf("Unloading game %s", g.ID)
+		delete(e.games, g.ID)
+	}
+}
@@ -0,0 +1,305 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// scholarsMate is played in every game of TestConcurrentClients
+var scholarsMate = [][2]string{
+	{"e2", "e4"}, {"e7", "e5"}, {"f1", "c4"}, {"b8", "c6"}, {"d1", "h5"}, {"g8", "f6"}, {"h5", "f7"},
+}
+
+// testClient is a websocket connection to an engine served over HTTP
+type testClient struct {
+	conn     *websocket.Conn
+	messages chan *Message
+}
+
+func dialEngine(t *testing.T, url, playerID string) *testClient {
+	conn, _, err := websocket.DefaultDialer.Dial(url+"?player_id="+playerID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := &testClient{conn: conn, messages: make(chan *Message, 256)}
+	go func() {
+		defer close(c.messages)
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			// The write pump joins queued messages with new lines
+			for _, line := range bytes.Split(data, newline) {
+				msg := new(Message)
+				if json.Unmarshal(line, msg) == nil {
+					c.messages <- msg
+				}
+			}
+		}
+	}()
+
+	return c
+}
+
+func (c *testClient) send(msgType string, data *MessageData) error {
+	payload, err := json.Marshal(&Message{Type: msgType, Data: data})
+	if err != nil {
+		return err
+	}
+	return c.conn.WriteMessage(websocket.TextMessage, payload)
+}
+
+// await skips messages until one of the type arrives
+func (c *testClient) await(msgType string) (*Message, error) {
+	timeout := time.After(10 * time.Second)
+	for {
+		select {
+		case msg, ok := <-c.messages:
+			if !ok {
+				return nil, fmt.Errorf("connection closed waiting for %s", msgType)
+			}
+			if msg.Type == msgType {
+				return msg, nil
+			}
+		case <-timeout:
+			return nil, fmt.Errorf("%s message not received", msgType)
+		}
+	}
+}
+
+// play makes moves of one side of scholar's mate, color is 0 for white and
+// 1 for black, and returns the result
+func (c *testClient) play(gameID string, color int) (string, error) {
+	for ply, move := range scholarsMate {
+		if ply%2 == color {
+			if err := c.send("make_move", &MessageData{GameID: gameID, Source: move[0], Target: move[1]}); err != nil {
+				return "", err
+			}
+		}
+		if _, err := c.await("move_made"); err != nil {
+			return "", fmt.Errorf("game %s: %v", gameID, err)
+		}
+	}
+
+	msg, err := c.await("game_over")
+	if err != nil {
+		return "", err
+	}
+	return msg.Data.Result, nil
+}
+
+// serveEngine serves websocket connections to an engine over HTTP, players
+// are identified by the player_id query parameter. Returns the server and the
+// websocket URL.
+func serveEngine(e *Engine) (*httptest.Server, string) {
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		client := e.NewClient(conn, r.URL.Query().Get("player_id"), "")
+		go client.ReadPump()
+		go client.WritePump()
+	}))
+	return server, "ws" + strings.TrimPrefix(server.URL, "http")
+}
+
+func TestConcurrentClients(t *testing.T) {
+	e := NewEngine(NewMemoryStore())
+	go e.Run()
+
+	server, url := serveEngine(e)
+	defer server.Close()
+
+	const games = 8
+	var wg sync.WaitGroup
+	for i := 0; i < games; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			white := dialEngine(t, url, fmt.Sprintf("white-%d", i))
+			black := dialEngine(t, url, fmt.Sprintf("black-%d", i))
+			defer white.conn.Close()
+			defer black.conn.Close()
+
+			if err := white.send("create_private_game", &MessageData{Orientation: OrientationWhite}); err != nil {
+				t.Error(err)
+				return
+			}
+			created, err := white.await("private_game_created")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			gameID, code := created.Data.GameID, created.Data.InviteCode
+
+			// Spectators come and go, chat and try to take seats while
+			// the game is played
+			var noise sync.WaitGroup
+			for j := 0; j < 3; j++ {
+				noise.Add(1)
+				go func(j int) {
+					defer noise.Done()
+					c := dialEngine(t, url, fmt.Sprintf("spectator-%d-%d", i, j))
+					defer c.conn.Close()
+					c.send("watch_game", &MessageData{GameID: gameID, InviteCode: code})
+					c.send("get_game", &MessageData{GameID: gameID, InviteCode: code, Orientation: OrientationWhite})
+					c.send("chat_message", &MessageData{GameID: gameID, Channel: ChatChannelGame, Text: "good luck"})
+					c.send("chat_message", &MessageData{Channel: ChatChannelLobby, Text: "hello"})
+					c.send("create_seek", &MessageData{TimeControl: "300+3"})
+					c.send("export_pgn", &MessageData{GameID: gameID, InviteCode: code})
+					if _, err := c.await("pgn"); err != nil {
+						t.Error(err)
+					}
+				}(j)
+			}
+
+			if err := black.send("get_game", &MessageData{GameID: gameID, InviteCode: code, Orientation: OrientationBlack}); err != nil {
+				t.Error(err)
+				return
+			}
+			if _, err := black.await("game_started"); err != nil {
+				t.Error(err)
+				return
+			}
+
+			results := make(chan string, 2)
+			errs := make(chan error, 2)
+			for color, c := range []*testClient{white, black} {
+				go func(c *testClient, color int) {
+					result, err := c.play(gameID, color)
+					if err != nil {
+						errs <- err
+						return
+					}
+					results <- result
+				}(c, color)
+			}
+			for j := 0; j < 2; j++ {
+				select {
+				case result := <-results:
+					if result != ResultWhiteWins {
+						t.Errorf("game %s ended %s, want %s", gameID, result, ResultWhiteWins)
+					}
+				case err := <-errs:
+					t.Error(err)
+				}
+			}
+
+			noise.Wait()
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestStuckClients(t *testing.T) {
+	e := NewEngine(NewMemoryStore())
+	g, err := e.newGame(InitialPosition, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Players read everything, one spectator never reads and another one
+	// was closed by the hub before leaving the game
+	alice := &Client{PlayerID: "alice", send: make(chan []byte, 1), engine: e}
+	bob := &Client{PlayerID: "bob", send: make(chan []byte, 1), engine: e}
+	stuck := &Client{PlayerID: "stuck", send: make(chan []byte, 1), engine: e}
+	closed := &Client{PlayerID: "closed", send: make(chan []byte, 1), engine: e}
+	closed.close()
+	g.Join(alice, OrientationWhite)
+	g.Join(bob, OrientationBlack)
+	g.Watch(stuck)
+	g.Watch(closed)
+	g.mu.Unlock()
+
+	done := make(chan struct{})
+	defer close(done)
+	for _, c := range []*Client{alice, bob} {
+		go func(c *Client) {
+			for {
+				select {
+				case <-c.send:
+				case <-done:
+					return
+				}
+			}
+		}(c)
+	}
+
+	played := make(chan struct{})
+	go func() {
+		defer close(played)
+		for ply, move := range scholarsMate {
+			c := alice
+			if ply%2 == 1 {
+				c = bob
+			}
+			msg := &Message{Type: "make_move", Data: &MessageData{GameID: g.ID, Source: move[0], Target: move[1]}}
+			if err := c.handleMessage(msg); err != nil {
+				t.Error(err)
+				return
+			}
+			c.handleMessage(&Message{Type: "chat_message", Data: &MessageData{GameID: g.ID, Channel: ChatChannelGame, Text: "hi"}})
+		}
+	}()
+
+	select {
+	case <-played:
+	case <-time.After(5 * time.Second):
+		t.Fatal("a client which does not read blocked the game")
+	}
+
+	g, err = e.GetGame(g.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.mu.Unlock()
+	if g.Result != ResultWhiteWins {
+		t.Errorf("game result %q, want %s", g.Result, ResultWhiteWins)
+	}
+}
+
+func TestMessageWithoutData(t *testing.T) {
+	e := NewEngine(NewMemoryStore())
+	go e.Run()
+
+	server, url := serveEngine(e)
+	defer server.Close()
+
+	alice := dialEngine(t, url, "alice")
+	defer alice.conn.Close()
+
+	for _, msgType := range []string{"make_move", "get_game", "chat_message"} {
+		if err := alice.send(msgType, nil); err != nil {
+			t.Fatal(err)
+		}
+		msg, err := alice.await("error")
+		if err != nil {
+			t.Fatalf("%s: %v", msgType, err)
+		}
+		if msg.Data.Error != ErrMissingData.Error() {
+			t.Errorf("%s without data returned %s", msgType, msg.Data.Error)
+		}
+	}
+
+	// The client stays connected
+	if err := alice.send("join_queue", &MessageData{TimeControl: "300+3"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := alice.await("queue_joined"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -3,11 +3,83 @@ package server
 import (
 	"errors"
 	"fmt"
+
+	"github.com/RichardKnop/chess-engine/auth"
 )
 
 var (
 	// ErrInvalidOrientation ...
 	ErrInvalidOrientation = errors.New("Orientation can only be either black or white")
+	// ErrComputerSeat ...
+	ErrComputerSeat = errors.New("Seat is taken by the computer")
+	// ErrSpectator ...
+	ErrSpectator = errors.New("Spectators cannot play")
+	// ErrAlreadyPlaying ...
+	ErrAlreadyPlaying = errors.New("Players cannot watch their own game")
+	// ErrNotInQueue ...
+	ErrNotInQueue = errors.New("Player is not in the matchmaking queue")
+	// ErrTooManySeeks ...
+	ErrTooManySeeks = fmt.Errorf("Players can have at most %d open seeks", maxSeeksPerPlayer)
+	// ErrNotYourSeek ...
+	ErrNotYourSeek = errors.New("Only the player who created a seek can cancel it")
+	// ErrOwnSeek ...
+	ErrOwnSeek = errors.New("Players cannot accept their own seek")
+	// ErrRatingOutOfRange ...
+	ErrRatingOutOfRange = errors.New("Rating is outside of the range accepted by the seek")
+	// ErrTooManyChallenges ...
+	ErrTooManyChallenges = fmt.Errorf("Players can have at most %d open challenges", maxChallengesPerPlayer)
+	// ErrChallengeYourself ...
+	ErrChallengeYourself = errors.New("Players cannot challenge themselves")
+	// ErrSeatReserved ...
+	ErrSeatReserved = errors.New("Seat is reserved for a disconnected player")
+	// ErrSeatTaken ...
+	ErrSeatTaken = errors.New("Seat is taken by another player")
+	// ErrBothSeats ...
+	ErrBothSeats = errors.New("Players cannot take both seats")
+	// ErrNotInvited ...
+	ErrNotInvited = errors.New("Only the invited player can take the seat")
+	// ErrInvalidInviteCode ...
+	ErrInvalidInviteCode = errors.New("Game is private, a valid invite code is needed")
+	// ErrGameOver ...
+	ErrGameOver = errors.New("Game is over")
+	// ErrNoDrawOffer ...
+	ErrNoDrawOffer = errors.New("There is no draw offer to respond to")
+	// ErrNoTakebackRequest ...
+	ErrNoTakebackRequest = errors.New("There is no takeback request to accept")
+	// ErrImportedGame ...
+	ErrImportedGame = errors.New("Imported games can only be replayed")
+	// ErrNotImported ...
+	ErrNotImported = errors.New("Only imported games can be replayed")
+	// ErrNoGameInPGN ...
+	ErrNoGameInPGN = errors.New("PGN does not contain any game")
+	// ErrNothingToTakeBack ...
+	ErrNothingToTakeBack = errors.New("There are no moves to take back")
+	// ErrEmptyChatMessage ...
+	ErrEmptyChatMessage = errors.New("Chat message is empty")
+	// ErrChatMessageTooLong ...
+	ErrChatMessageTooLong = fmt.Errorf("Chat message is longer than %d characters", maxChatLength)
+	// ErrInvalidUsername ...
+	ErrInvalidUsername = errors.New("Username must have 3 to 20 lowercase letters, digits, dashes or underscores")
+	// ErrPasswordTooShort ...
+	ErrPasswordTooShort = fmt.Errorf("Password must have at least %d characters", minPasswordLength)
+	// ErrPasswordTooLong ...
+	ErrPasswordTooLong = fmt.Errorf("Password must have at most %d bytes", auth.MaxPasswordLength)
+	// ErrUsernameTaken ...
+	ErrUsernameTaken = errors.New("Username is already taken")
+	// ErrAccountExists ...
+	ErrAccountExists = errors.New("Player already has an account")
+	// ErrInvalidCredentials ...
+	ErrInvalidCredentials = errors.New("Invalid username or password")
+	// ErrInvalidDisplayName ...
+	ErrInvalidDisplayName = fmt.Errorf("Display name must have 1 to %d characters", maxDisplayNameLength)
+	// ErrInvalidCountry ...
+	ErrInvalidCountry = errors.New("Country must be a two letter ISO 3166 code")
+	// ErrInvalidPieceSet ...
+	ErrInvalidPieceSet = errors.New("Unknown piece set")
+	// ErrChatRateLimited ...
+	ErrChatRateLimited = errors.New("Too many chat messages, slow down")
+	// ErrMissingData ...
+	ErrMissingData = errors.New("Message data is missing")
 )
 
 // GameNotFoundError represents a custom error
@@ -54,3 +126,171 @@ func (e UnknownMessageType) Error() string {
 func NewUnknownMessageType(msgType string) *UnknownMessageType {
 	return &UnknownMessageType{msgType: msgType}
 }
+
+// IllegalMoveError represents a custom error
+type IllegalMoveError struct {
+	source string
+	target string
+}
+
+// Error implements the error interface
+func (e IllegalMoveError) Error() string {
+	return fmt.Sprintf("Illegal move from %s to %s", e.source, e.target)
+}
+
+// NewIllegalMoveError creates a new instance of IllegalMoveError
+func NewIllegalMoveError(source, target string) *IllegalMoveError {
+	return &IllegalMoveError{source: source, target: target}
+}
+
+// NotYourTurnError represents a custom error
+type NotYourTurnError struct {
+	playerID string
+}
+
+// Error implements the error interface
+func (e NotYourTurnError) Error() string {
+	return fmt.Sprintf("Player %s is not on the move", e.playerID)
+}
+
+// NewNotYourTurnError creates a new instance of NotYourTurnError
+func NewNotYourTurnError(playerID string) *NotYourTurnError {
+	return &NotYourTurnError{playerID: playerID}
+}
+
+// InvalidLevelError represents a custom error
+type InvalidLevelError struct {
+	level    int
+	maxLevel int
+}
+
+// Error implements the error interface
+func (e InvalidLevelError) Error() string {
+	return fmt.Sprintf("Invalid computer level %d, choose between 1 and %d", e.level, e.maxLevel)
+}
+
+// NewInvalidLevelError creates a new instance of InvalidLevelError
+func NewInvalidLevelError(level, maxLevel int) *InvalidLevelError {
+	return &InvalidLevelError{level: level, maxLevel: maxLevel}
+}
+
+// InvalidTimeControlError represents a custom error
+type InvalidTimeControlError struct {
+	timeControl string
+}
+
+// Error implements the error interface
+func (e InvalidTimeControlError) Error() string {
+	return fmt.Sprintf("Invalid time control: %s", e.timeControl)
+}
+
+// NewInvalidTimeControlError creates a new instance of InvalidTimeControlError
+func NewInvalidTimeControlError(timeControl string) *InvalidTimeControlError {
+	return &InvalidTimeControlError{timeControl: timeControl}
+}
+
+// NotPlayingError represents a custom error
+type NotPlayingError struct {
+	playerID string
+	gameID   string
+}
+
+// Error implements the error interface
+func (e NotPlayingError) Error() string {
+	return fmt.Sprintf("Player %s is not playing in game %s", e.playerID, e.gameID)
+}
+
+// NewNotPlayingError creates a new instance of NotPlayingError
+func NewNotPlayingError(playerID, gameID string) *NotPlayingError {
+	return &NotPlayingError{playerID: playerID, gameID: gameID}
+}
+
+// InvalidChatChannelError represents a custom error
+type InvalidChatChannelError struct {
+	channel string
+}
+
+// Error implements the error interface
+func (e InvalidChatChannelError) Error() string {
+	return fmt.Sprintf("Invalid chat channel: %s", e.channel)
+}
+
+// NewInvalidChatChannelError creates a new instance of InvalidChatChannelError
+func NewInvalidChatChannelError(channel string) *InvalidChatChannelError {
+	return &InvalidChatChannelError{channel: channel}
+}
+
+// AccountNotFoundError represents a custom error
+type AccountNotFoundError struct {
+	account string
+}
+
+// Error implements the error interface
+func (e AccountNotFoundError) Error() string {
+	return fmt.Sprintf("Account %s does not exist", e.account)
+}
+
+// NewAccountNotFoundError creates a new instance of AccountNotFoundError
+func NewAccountNotFoundError(account string) *AccountNotFoundError {
+	return &AccountNotFoundError{account: account}
+}
+
+// PlayerNotFoundError represents a custom error
+type PlayerNotFoundError struct {
+	playerID string
+}
+
+// Error implements the error interface
+func (e PlayerNotFoundError) Error() string {
+	return fmt.Sprintf("Player %s does not exist", e.playerID)
+}
+
+// NewPlayerNotFoundError creates a new instance of PlayerNotFoundError
+func NewPlayerNotFoundError(playerID string) *PlayerNotFoundError {
+	return &PlayerNotFoundError{playerID: playerID}
+}
+
+// PlayerOfflineError represents a custom error
+type PlayerOfflineError struct {
+	playerID string
+}
+
+// Error implements the error interface
+func (e PlayerOfflineError) Error() string {
+	return fmt.Sprintf("Player %s is not online", e.playerID)
+}
+
+// NewPlayerOfflineError creates a new instance of PlayerOfflineError
+func NewPlayerOfflineError(playerID string) *PlayerOfflineError {
+	return &PlayerOfflineError{playerID: playerID}
+}
+
+// SeekNotFoundError represents a custom error
+type SeekNotFoundError struct {
+	seekID string
+}
+
+// Error implements the error interface
+func (e SeekNotFoundError) Error() string {
+	return fmt.Sprintf("Seek %s does not exist", e.seekID)
+}
+
+// NewSeekNotFoundError creates a new instance of SeekNotFoundError
+func NewSeekNotFoundError(seekID string) *SeekNotFoundError {
+	return &SeekNotFoundError{seekID: seekID}
+}
+
+// ChallengeNotFoundError represents a custom error
+type ChallengeNotFoundError struct {
+	challengeID string
+}
+
+// Error implements the error interface
+func (e ChallengeNotFoundError) Error() string {
+	return fmt.Sprintf("Challenge %s does not exist", e.challengeID)
+}
+
+// NewChallengeNotFoundError creates a new instance of ChallengeNotFoundError
+func NewChallengeNotFoundError(challengeID string) *ChallengeNotFoundError {
+	return &ChallengeNotFoundError{challengeID: challengeID}
+}
@@ -4,113 +4,629 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"sync"
+	"time"
+
+	"github.com/RichardKnop/chess-engine/chess"
+	"github.com/RichardKnop/chess-engine/fen"
+	"github.com/RichardKnop/chess-engine/pgn"
 )
 
 // Move represents a single move
 type Move struct {
-	PlayerID string
-	Source   string
-	Target   string
-	Piece    string
+	PlayerID  string `json:"player_id"`
+	Source    string `json:"source"`
+	Target    string `json:"target"`
+	Piece     string `json:"piece"`
+	Promotion string `json:"promotion,omitempty"`
+	// Time left to the player after the move in milliseconds, timed games only
+	Clock int64 `json:"clock,omitempty"`
 }
 
 // Game represents a game of chess
 type Game struct {
-	ID      string
-	Started bool
-	// FEM position string
-	Position string
+	// Serializes everything done with the game, it is held while a message,
+	// a timer or a move of the computer is handled
+	mu sync.Mutex
+
+	ID        string
+	Started   bool
+	CreatedAt time.Time
+	// Current position including side to move, castling rights,
+	// en passant square and move clocks
+	Position *chess.Position
 	// Sequence of all the moves played
 	Moves []*Move
+	// All positions reached starting with the initial one, used to detect
+	// repetitions and to take moves back
+	History []*chess.Position
+	// Result and reason are set once the game is over
+	Result string
+	Reason string
+	// Player IDs of players with a pending draw offer or takeback request
+	DrawOffer       string
+	TakebackRequest string
+	// Untimed games have no time control and no clock
+	TimeControl *TimeControl
+	Clock       *Clock
+	// Fires when the player on the move runs out of time
+	flagTimer *time.Timer
+	// Seats of disconnected players are reserved until the timer fires
+	abandonTimers [2]*time.Timer
 	// Player with white pieces
 	White *Client
 	// Player with black pieces
 	Black *Client
+	// Clients watching the game, they receive every notification players
+	// do but can never move
+	Spectators map[*Client]bool
+	// Recent chat of players only and of players with spectators
+	playersChat chatHistory
+	gameChat    chatHistory
+	// Player IDs of the seats, kept when players disconnect, and display
+	// names of players with an account
+	WhiteID   string
+	BlackID   string
+	WhiteName string
+	BlackName string
+	// Engine filling one of the seats when playing against the computer
+	Computer *ComputerPlayer
+	// Ratings of players change after rated games
+	Rated bool
+	// Private games can only be opened with the invite code, InvitedID is
+	// the only player who can take the free seat if set
+	Private    bool
+	InviteCode string
+	InvitedID  string
+	// Games imported from PGN can only be replayed, ReplayPly is the number
+	// of moves currently shown and Tags are the imported PGN tags
+	Imported  bool
+	ReplayPly int
+	Tags      map[string]string
+	// Persists the game after every change, nil if the game is not stored
+	store Store
+	// Called without the lock held when a game ends with nobody in it so
+	// the engine can unload it, nil if the game is not loaded by an engine
+	unload func()
 }
 
-// NewGame creates a new game of chess
-func NewGame(gameID, position string) (*Game, error) {
+// NewGame creates a new game of chess, time control is optional
+func NewGame(gameID, position string, tc *TimeControl) (*Game, error) {
 	// Default to initial position if not specified
 	if position == "" {
 		position = InitialPosition
 	}
 
+	p, err := fen.Parse(position)
+	if err != nil {
+		return nil, err
+	}
+
 	g := &Game{
-		ID:       gameID,
-		Position: position,
-		Moves:    make([]*Move, 0),
+		ID:         gameID,
+		CreatedAt:  time.Now(),
+		Position:   p,
+		Moves:      make([]*Move, 0),
+		History:    []*chess.Position{p},
+		Spectators: make(map[*Client]bool),
 	}
+	if tc != nil {
+		g.TimeControl = tc
+		g.Clock = NewClock(tc, p.Turn())
+	}
+	// A game may be set up from a position which is already decided
+	g.checkGameOver()
 
 	log.Printf("New game created: %s", g.ID)
 
 	return g, nil
 }
 
+// RestoreGame recreates a stored game by replaying its moves
+func RestoreGame(r *GameRecord) (*Game, error) {
+	tc, err := ParseTimeControl(r.TimeControl)
+	if err != nil {
+		return nil, err
+	}
+
+	g, err := NewGame(r.ID, r.InitialPosition, nil)
+	if err != nil {
+		return nil, err
+	}
+	g.CreatedAt = r.CreatedAt
+	g.WhiteID, g.BlackID = r.WhiteID, r.BlackID
+	g.WhiteName, g.BlackName = r.WhiteName, r.BlackName
+	g.Imported, g.Tags = r.Imported, r.Tags
+	g.Rated = r.Rated
+	g.Private, g.InviteCode, g.InvitedID = r.Private, r.InviteCode, r.InvitedID
+	g.Started = r.WhiteID != "" || r.BlackID != ""
+
+	if r.ComputerLevel > 0 {
+		color := chess.White
+		if r.ComputerColor == OrientationBlack {
+			color = chess.Black
+		}
+		if g.Computer, err = NewComputerPlayer(color, r.ComputerLevel); err != nil {
+			return nil, err
+		}
+	}
+
+	var clock *Clock
+	if tc != nil {
+		clock = NewClock(tc, g.Position.Turn())
+	}
+
+	for _, move := range r.Moves {
+		m, err := parseMove(g.Position, move.Source, move.Target, move.Promotion)
+		if err != nil {
+			return nil, err
+		}
+		p, err := g.Position.MakeMove(m)
+		if err != nil {
+			return nil, NewIllegalMoveError(move.Source, move.Target)
+		}
+		if clock != nil {
+			clock.countMove(g.Position.Turn())
+		}
+		g.Position = p
+		g.History = append(g.History, p)
+		g.Moves = append(g.Moves, move)
+	}
+
+	if clock != nil {
+		// The clock stays paused until the next move is made
+		clock.turn = g.Position.Turn()
+		clock.remaining[chess.White] = time.Duration(r.WhiteTime) * time.Millisecond
+		clock.remaining[chess.Black] = time.Duration(r.BlackTime) * time.Millisecond
+		g.TimeControl, g.Clock = tc, clock
+	}
+
+	g.Result, g.Reason = r.Result, r.Reason
+
+	return g, nil
+}
+
+// Record returns everything needed to store and later restore the game
+func (g *Game) Record() *GameRecord {
+	r := &GameRecord{
+		ID:              g.ID,
+		InitialPosition: fen.Encode(g.History[0]),
+		TimeControl:     g.TimeControl.String(),
+		WhiteID:         g.WhiteID,
+		BlackID:         g.BlackID,
+		WhiteName:       g.WhiteName,
+		BlackName:       g.BlackName,
+		Moves:           append([]*Move(nil), g.Moves...),
+		Result:          g.Result,
+		Reason:          g.Reason,
+		Rated:           g.Rated,
+		Private:         g.Private,
+		InviteCode:      g.InviteCode,
+		InvitedID:       g.InvitedID,
+		Imported:        g.Imported,
+		Tags:            g.Tags,
+		CreatedAt:       g.CreatedAt,
+		UpdatedAt:       time.Now(),
+	}
+	if g.Computer != nil {
+		r.ComputerColor = g.Computer.Color.String()
+		r.ComputerLevel = g.Computer.Level
+	}
+	if g.Clock != nil {
+		state := g.Clock.State(r.UpdatedAt)
+		r.WhiteTime, r.BlackTime = state.White, state.Black
+	}
+	return r
+}
+
+// PGN returns the game in Portable Game Notation, games in progress end with
+// an unknown result
+func (g *Game) PGN() (string, error) {
+	game := pgn.NewGame()
+	game.Tags["Event"] = "Casual game"
+	game.Tags["Site"] = pgnSite
+	game.Tags["Date"] = g.CreatedAt.Format("2006.01.02")
+	game.Tags["White"] = g.playerName(chess.White)
+	game.Tags["Black"] = g.playerName(chess.Black)
+	if g.TimeControl != nil {
+		game.Tags["TimeControl"] = g.TimeControl.String()
+	}
+	if g.Reason != "" {
+		game.Tags["Termination"] = g.Reason
+	}
+	for name, value := range g.Tags {
+		game.Tags[name] = value
+	}
+	game.Position = g.History[0]
+	game.Result = g.Result
+
+	for i, move := range g.Moves {
+		m, err := parseMove(g.History[i], move.Source, move.Target, move.Promotion)
+		if err != nil {
+			return "", err
+		}
+		game.Moves = append(game.Moves, m)
+
+		comment := ""
+		if g.TimeControl != nil {
+			comment = pgn.ClockComment(time.Duration(move.Clock) * time.Millisecond)
+		}
+		game.Comments = append(game.Comments, comment)
+	}
+
+	return pgn.Encode(game)
+}
+
+// playerName returns how a player of a color is called in exported games
+func (g *Game) playerName(color chess.Color) string {
+	if g.Computer != nil && g.Computer.Color == color {
+		return fmt.Sprintf("Computer level %d", g.Computer.Level)
+	}
+	if color == chess.White && g.WhiteName != "" {
+		return g.WhiteName
+	}
+	if color == chess.White {
+		return g.WhiteID
+	}
+	if g.BlackName != "" {
+		return g.BlackName
+	}
+	return g.BlackID
+}
+
+// save persists the game, failing to store it does not stop the game
+func (g *Game) save() {
+	if g.store == nil {
+		return
+	}
+	if err := g.store.Save(g.Record()); err != nil {
+		log.Printf("Error saving game %s: %v", g.ID, err)
+	}
+}
+
 // Join is called when a player joins the game
 func (g *Game) Join(c *Client, orientation string) error {
-	switch orientation {
-	case OrientationWhite:
-		g.White = c
-	case OrientationBlack:
-		g.Black = c
-	default:
-		return fmt.Errorf("Invalid orientation: %s", orientation)
+	if orientation != OrientationWhite && orientation != OrientationBlack {
+		return ErrInvalidOrientation
+	}
+	if g.Computer != nil && g.Computer.Color.String() == orientation {
+		return ErrComputerSeat
 	}
 
+	// Seats of disconnected players are reserved for them
+	switch {
+	case orientation == OrientationWhite && g.abandonTimers[chess.White] != nil && g.WhiteID != c.PlayerID,
+		orientation == OrientationBlack && g.abandonTimers[chess.Black] != nil && g.BlackID != c.PlayerID:
+		return ErrSeatReserved
+	}
+
+	// Seats belong to the first player who takes them
+	switch {
+	case orientation == OrientationWhite && g.WhiteID != "" && g.WhiteID != c.PlayerID,
+		orientation == OrientationBlack && g.BlackID != "" && g.BlackID != c.PlayerID:
+		return ErrSeatTaken
+	}
+	// A player cannot play against themselves from two connections
+	switch {
+	case orientation == OrientationWhite && g.BlackID == c.PlayerID,
+		orientation == OrientationBlack && g.WhiteID == c.PlayerID:
+		return ErrBothSeats
+	}
+	if g.InvitedID != "" && c.PlayerID != g.InvitedID && c.PlayerID != g.WhiteID && c.PlayerID != g.BlackID {
+		return ErrNotInvited
+	}
+
+	delete(g.Spectators, c)
+
+	if orientation == OrientationWhite {
+		g.White, g.WhiteID, g.WhiteName = c, c.PlayerID, c.Name
+	} else {
+		g.Black, g.BlackID, g.BlackName = c, c.PlayerID, c.Name
+	}
+	g.save()
+
 	log.Printf("Player %s joined game %s playing with %s pieces", c.PlayerID, g.ID, orientation)
 
-	return nil
+	return g.reconnected(c)
 }
 
-// Leave is called when a player leaves the game
+// Leave is called when a player leaves the game, the seat of a player who
+// leaves a game in progress is reserved for a while so they can reconnect
 func (g *Game) Leave(c *Client) error {
-	if g.White != nil && g.White.PlayerID == c.PlayerID {
-		g.White = nil
-		log.Printf("Player %s left game %s", c.PlayerID, g.ID)
+	if g.Spectators[c] {
+		delete(g.Spectators, c)
+		log.Printf("Spectator %s stopped watching game %s", c.PlayerID, g.ID)
+		return g.NotifyGameState()
 	}
-	if g.Black != nil && g.Black.PlayerID == c.PlayerID {
-		g.Black = nil
-		log.Printf("Player %s left game %s", c.PlayerID, g.ID)
+
+	// Compare clients rather than player IDs, the player may have already
+	// reconnected over a new connection
+	var color chess.Color
+	switch {
+	case g.White != nil && g.White == c:
+		g.White, color = nil, chess.White
+	case g.Black != nil && g.Black == c:
+		g.Black, color = nil, chess.Black
+	default:
+		return nil
+	}
+
+	log.Printf("Player %s left game %s", c.PlayerID, g.ID)
+
+	if !g.Started || g.IsOver() || g.Imported {
+		return nil
 	}
 
+	return g.disconnected(color, c.PlayerID)
+}
+
+// Watch adds a spectator to the game, players cannot watch their own game
+func (g *Game) Watch(c *Client) error {
+	if g.IsSeated(c) {
+		return ErrAlreadyPlaying
+	}
+
+	g.Spectators[c] = true
+	log.Printf("Spectator %s is watching game %s", c.PlayerID, g.ID)
+
 	return nil
 }
 
-// MakeMove moves a piece
-func (g *Game) MakeMove(playerID, source, target, piece, oldPosition, newPosition string) error {
-	m := &Move{
-		PlayerID: playerID,
-		Target:   target,
-		Source:   source,
-		Piece:    piece,
+// IsSeated returns true if the client plays in the game
+func (g *Game) IsSeated(c *Client) bool {
+	return (g.White != nil && g.White == c) || (g.Black != nil && g.Black == c)
+}
+
+// MakeMove validates a move and if it is legal, moves a piece
+func (g *Game) MakeMove(playerID, source, target, promotion string) error {
+	if g.Imported {
+		return ErrImportedGame
+	}
+	if g.IsOver() {
+		return ErrGameOver
+	}
+
+	if activePlayerID := g.getActivePlayerID(); activePlayerID == nil || *activePlayerID != playerID {
+		return NewNotYourTurnError(playerID)
 	}
 
-	// TODO - validate move
+	m, err := parseMove(g.Position, source, target, promotion)
+	if err != nil {
+		return err
+	}
 
-	g.Position = newPosition
-	g.Moves = append(g.Moves, m)
+	piece := g.Position.PieceAt(m.From)
+	p, err := g.Position.MakeMove(m)
+	if err != nil {
+		return NewIllegalMoveError(source, target)
+	}
+
+	now := time.Now()
+	if g.Clock != nil && !g.Clock.Punch(now, g.playerLag(playerID)) {
+		g.timeout()
+		return g.NotifyGameOver()
+	}
+
+	move := &Move{
+		PlayerID:  playerID,
+		Target:    target,
+		Source:    source,
+		Piece:     piece.Code(),
+		Promotion: promotion,
+	}
+	if g.Clock != nil {
+		move.Clock = int64(g.Clock.Remaining(g.Position.Turn(), now) / time.Millisecond)
+	}
+
+	g.Position = p
+	g.History = append(g.History, p)
+	// Pending offers are only valid until the next move
+	g.DrawOffer, g.TakebackRequest = "", ""
+	g.Moves = append(g.Moves, move)
 
 	msg := &Message{
 		Type: "move_made",
+		Data: &MessageData{
+			GameID:    g.ID,
+			Position:  g.FEN(),
+			PlayerID:  playerID,
+			Target:    target,
+			Source:    source,
+			Piece:     piece.Code(),
+			Promotion: promotion,
+			Clock:     g.clockState(now),
+		},
+	}
+	g.save()
+	if err := g.notifyPlayers(msg); err != nil {
+		return err
+	}
+
+	if g.checkGameOver() {
+		return g.NotifyGameOver()
+	}
+
+	g.scheduleFlag()
+	g.requestComputerMove()
+
+	return nil
+}
+
+// IsOver returns true once the game has a result
+func (g *Game) IsOver() bool {
+	return g.Result != ""
+}
+
+// checkGameOver applies rules ending the game to the current position, sets
+// the result and returns true if the game is over
+func (g *Game) checkGameOver() bool {
+	p := g.Position
+
+	switch {
+	case p.IsCheckmate():
+		g.Result, g.Reason = ResultWhiteWins, ReasonCheckmate
+		if p.Turn() == chess.White {
+			g.Result = ResultBlackWins
+		}
+	case p.IsStalemate():
+		g.Result, g.Reason = ResultDraw, ReasonStalemate
+	case g.repetitions() >= 3:
+		g.Result, g.Reason = ResultDraw, ReasonThreefoldRepetition
+	case p.IsFiftyMoveDraw():
+		g.Result, g.Reason = ResultDraw, ReasonFiftyMoveRule
+	case p.IsInsufficientMaterial():
+		g.Result, g.Reason = ResultDraw, ReasonInsufficientMaterial
+	default:
+		return false
+	}
+
+	g.finish()
+
+	return true
+}
+
+// timeout ends the game lost by the player on the move unless the opponent
+// has too little material to ever checkmate
+func (g *Game) timeout() {
+	winner := g.Position.Turn().Other()
+	switch {
+	case !g.Position.HasMatingMaterial(winner):
+		g.Result, g.Reason = ResultDraw, ReasonTimeoutVsInsufficientMaterial
+	case winner == chess.White:
+		g.Result, g.Reason = ResultWhiteWins, ReasonTimeout
+	default:
+		g.Result, g.Reason = ResultBlackWins, ReasonTimeout
+	}
+
+	g.finish()
+}
+
+// finish stops the clock once a result has been set
+func (g *Game) finish() {
+	if g.Clock != nil {
+		g.Clock.Stop(time.Now())
+	}
+	if g.flagTimer != nil {
+		g.flagTimer.Stop()
+	}
+	// Seats are not reserved in a finished game so it can be unloaded once
+	// everybody leaves
+	for color, timer := range g.abandonTimers {
+		if timer != nil {
+			timer.Stop()
+			g.abandonTimers[color] = nil
+		}
+	}
+	g.save()
+	g.rate()
+
+	log.Printf("Game %s is over: %s (%s)", g.ID, g.Result, g.Reason)
+}
+
+// scheduleFlag arms a timer ending the game when the player on the move runs
+// out of time without moving
+func (g *Game) scheduleFlag() {
+	if g.Clock == nil {
+		return
+	}
+	if g.flagTimer != nil {
+		g.flagTimer.Stop()
+	}
+
+	// Give the player's move time to arrive over a slow connection
+	remaining := g.Clock.Remaining(g.Position.Turn(), time.Now())
+	if activePlayerID := g.getActivePlayerID(); activePlayerID != nil {
+		remaining += g.playerLag(*activePlayerID)
+	}
+	g.flagTimer = time.AfterFunc(remaining, func() {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+
+		if g.IsOver() {
+			return
+		}
+		if !g.Clock.Flagged(time.Now()) {
+			// The player moved meanwhile
+			return
+		}
+		g.timeout()
+		if err := g.NotifyGameOver(); err != nil {
+			log.Printf("Error notifying players of game %s: %v", g.ID, err)
+		}
+	})
+}
+
+// clockState returns the clock for clients, nil in untimed games
+func (g *Game) clockState(now time.Time) *ClockState {
+	if g.Clock == nil {
+		return nil
+	}
+	return g.Clock.State(now)
+}
+
+// playerLag returns network lag of a connected player, it is not counted
+// against the player's time
+func (g *Game) playerLag(playerID string) time.Duration {
+	for _, p := range g.GetPlayers() {
+		if p.PlayerID == playerID {
+			return p.Lag()
+		}
+	}
+	return 0
+}
+
+// repetitions returns how many times the current position occurred, only
+// positions since the last capture or pawn move can repeat
+func (g *Game) repetitions() int {
+	count := 0
+	current := g.Position.Hash()
+	for i := len(g.History) - 1; i >= 0 && i >= len(g.History)-1-g.Position.HalfmoveClock(); i -= 2 {
+		if g.History[i].Hash() == current {
+			count++
+		}
+	}
+	return count
+}
+
+// NotifyGameOver notifies players about the result of the game
+func (g *Game) NotifyGameOver() error {
+	msg := &Message{
+		Type: "game_over",
 		Data: &MessageData{
 			GameID:   g.ID,
-			Position: g.Position,
-			PlayerID: playerID,
-			Target:   target,
-			Source:   source,
-			Piece:    piece,
+			Position: g.FEN(),
+			Result:   g.Result,
+			Reason:   g.Reason,
+			Clock:    g.clockState(time.Now()),
 		},
 	}
 	return g.notifyPlayers(msg)
 }
 
+// requestComputerMove lets the computer think in the background if it is on
+// the move, the move is then played like any other move
+func (g *Game) requestComputerMove() {
+	if g.Computer == nil || g.IsOver() || g.Position.Turn() != g.Computer.Color {
+		return
+	}
+
+	go func() {
+		if err := g.Computer.Play(g); err != nil {
+			log.Printf("Computer failed to move in game %s: %v", g.ID, err)
+		}
+	}()
+}
+
 // NotifyGameStarted notifies players the game has started
 func (g *Game) NotifyGameStarted() error {
 	msg := &Message{
 		Type: "game_started",
 		Data: &MessageData{
-			GameID:   g.ID,
-			Position: g.Position,
+			GameID:      g.ID,
+			Position:    g.FEN(),
+			TimeControl: g.TimeControl.String(),
+			Clock:       g.clockState(time.Now()),
+			Rated:       g.Rated,
 		},
 	}
 	return g.notifyPlayers(msg)
@@ -121,8 +637,12 @@ func (g *Game) NotifyGameState() error {
 	msg := &Message{
 		Type: "state_update",
 		Data: &MessageData{
-			GameID:   g.ID,
-			Position: g.Position,
+			GameID:     g.ID,
+			Position:   g.FEN(),
+			Result:     g.Result,
+			Reason:     g.Reason,
+			Clock:      g.clockState(time.Now()),
+			Spectators: len(g.Spectators),
 		},
 	}
 	if activePlayerID := g.getActivePlayerID(); activePlayerID != nil {
@@ -131,6 +651,11 @@ func (g *Game) NotifyGameState() error {
 	return g.notifyPlayers(msg)
 }
 
+// FEN returns current position in Forsyth-Edwards Notation
+func (g *Game) FEN() string {
+	return fen.Encode(g.Position)
+}
+
 // GetPlayers returns slice of players currently connected to the game
 func (g *Game) GetPlayers() []*Client {
 	var players []*Client
@@ -143,20 +668,54 @@ func (g *Game) GetPlayers() []*Client {
 	return players
 }
 
+// seatsFilled returns true when both sides are played by a client or the computer
+func (g *Game) seatsFilled() bool {
+	return (g.White != nil || (g.Computer != nil && g.Computer.Color == chess.White)) &&
+		(g.Black != nil || (g.Computer != nil && g.Computer.Color == chess.Black))
+}
+
 // getActivePlayerID returns player ID of a player who is on the move currently
 func (g *Game) getActivePlayerID() *string {
-	if len(g.Moves)%2 == 0 && g.White != nil {
+	if g.Computer != nil && g.Position.Turn() == g.Computer.Color {
+		return &g.Computer.PlayerID
+	}
+
+	if g.Position.Turn() == chess.White && g.White != nil {
 		return &g.White.PlayerID
 	}
 
-	if len(g.Moves)%2 == 1 && g.Black != nil {
+	if g.Position.Turn() == chess.Black && g.Black != nil {
 		return &g.Black.PlayerID
 	}
 
 	return nil
 }
 
-// notifyPlayers sends a message to all players
+// parseMove converts squares sent by a client into a move in a position,
+// promoting pawns to a queen unless another piece was requested
+func parseMove(p *chess.Position, source, target, promotion string) (chess.Move, error) {
+	from, err := chess.ParseSquare(source)
+	if err != nil {
+		return chess.Move{}, NewIllegalMoveError(source, target)
+	}
+	to, err := chess.ParseSquare(target)
+	if err != nil {
+		return chess.Move{}, NewIllegalMoveError(source, target)
+	}
+
+	m := chess.Move{From: from, To: to}
+	if promotion != "" {
+		if m.Promotion, err = chess.ParsePieceType(promotion[0]); err != nil {
+			return chess.Move{}, NewIllegalMoveError(source, target)
+		}
+	} else if p.PieceAt(from).Type() == chess.Pawn && (to.Rank() == 0 || to.Rank() == 7) {
+		m.Promotion = chess.Queen
+	}
+
+	return m, nil
+}
+
+// notifyPlayers sends a message to all players and spectators
 func (g *Game) notifyPlayers(msg *Message) error {
 	data, err := json.Marshal(msg)
 	if err != nil {
@@ -164,7 +723,10 @@ func (g *Game) notifyPlayers(msg *Message) error {
 	}
 
 	for _, p := range g.GetPlayers() {
-		p.send <- data
+		p.deliver(data)
+	}
+	for s := range g.Spectators {
+		s.deliver(data)
 	}
 
 	return nil
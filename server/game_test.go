@@ -0,0 +1,117 @@
+package server
+
+import (
+	"testing"
+)
+
+func TestMakeMoveValidation(t *testing.T) {
+	isIllegal := func(err error) bool {
+		_, ok := err.(*IllegalMoveError)
+		return ok
+	}
+	isNotYourTurn := func(err error) bool {
+		_, ok := err.(*NotYourTurnError)
+		return ok
+	}
+
+	testCases := []struct {
+		name      string
+		position  string
+		playerID  string
+		source    string
+		target    string
+		promotion string
+		check     func(err error) bool
+	}{
+		{"out of turn", InitialPosition, "bob", "e7", "e5", "", isNotYourTurn},
+		{"opponent's piece", InitialPosition, "alice", "e7", "e5", "", isIllegal},
+		{"invalid square", InitialPosition, "alice", "e2", "e9", "", isIllegal},
+		{"pinned piece", "4k3/4r3/8/8/8/8/4B3/4K3 w - - 0 1", "alice", "e2", "d3", "", isIllegal},
+		{"castling through check", "4kr2/8/8/8/8/8/8/R3K2R w KQ - 0 1", "alice", "e1", "g1", "", isIllegal},
+		{"en passant exposing the king", "8/8/8/K2pP2r/8/8/8/4k3 w - d6 0 2", "alice", "e5", "d6", "", isIllegal},
+		{"invalid promotion piece", "4k3/P7/8/8/8/8/8/4K3 w - - 0 1", "alice", "a7", "a8", "x", isIllegal},
+		{"promotion to a king", "4k3/P7/8/8/8/8/8/4K3 w - - 0 1", "alice", "a7", "a8", "k", isIllegal},
+	}
+	for _, tc := range testCases {
+		g := newTestGame(t, tc.position)
+		before := g.FEN()
+		err := g.MakeMove(tc.playerID, tc.source, tc.target, tc.promotion)
+		if !tc.check(err) {
+			t.Errorf("%s: move returned %v", tc.name, err)
+		}
+		if g.FEN() != before || len(g.Moves) != 0 {
+			t.Errorf("%s: rejected move changed the game to %s", tc.name, g.FEN())
+		}
+		g.mu.Unlock()
+	}
+
+	// Pawns reaching the last rank become queens unless another piece is
+	// chosen
+	g := newTestGame(t, "4k3/P7/8/8/8/8/8/4K3 w - - 0 1")
+	if err := g.MakeMove("alice", "a7", "a8", ""); err != nil {
+		t.Fatal(err)
+	}
+	if s := g.FEN(); s != "Q3k3/8/8/8/8/8/8/4K3 b - - 0 1" {
+		t.Errorf("promotion without a piece resulted in %s", s)
+	}
+	g.mu.Unlock()
+
+	// Nothing can be played once the game has ended
+	g = newTestGame(t, InitialPosition)
+	for _, m := range [][2]string{{"f2", "f3"}, {"e7", "e5"}, {"g2", "g4"}, {"d8", "h4"}} {
+		if err := g.MakeMove(*g.getActivePlayerID(), m[0], m[1], ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if g.Result != ResultBlackWins {
+		t.Fatalf("fool's mate resulted in %q", g.Result)
+	}
+	if err := g.MakeMove("alice", "e2", "e4", ""); err != ErrGameOver {
+		t.Errorf("move after checkmate returned %v", err)
+	}
+	g.mu.Unlock()
+}
+
+// newTestGame returns a locked game from a position with alice playing white
+// and bob playing black
+func newTestGame(t *testing.T, position string) *Game {
+	e := NewEngine(NewMemoryStore())
+	g, err := e.newGame(position, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	alice := &Client{PlayerID: "alice", send: make(chan []byte, 16), engine: e}
+	bob := &Client{PlayerID: "bob", send: make(chan []byte, 16), engine: e}
+	if err := g.Join(alice, OrientationWhite); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Join(bob, OrientationBlack); err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func TestThreefoldRepetition(t *testing.T) {
+	// The first occurrence follows a double pawn push which sets an en
+	// passant square no black pawn can use
+	g := newTestGame(t, InitialPosition)
+	defer g.mu.Unlock()
+
+	moves := [][2]string{
+		{"e2", "e4"},
+		{"g8", "f6"}, {"g1", "f3"}, {"f6", "g8"}, {"f3", "g1"},
+		{"g8", "f6"}, {"g1", "f3"}, {"f6", "g8"}, {"f3", "g1"},
+	}
+	for i, m := range moves {
+		if g.IsOver() {
+			t.Fatalf("game ended %s by %s after %d moves", g.Result, g.Reason, i)
+		}
+		if err := g.MakeMove(*g.getActivePlayerID(), m[0], m[1], ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if g.Result != ResultDraw || g.Reason != ReasonThreefoldRepetition {
+		t.Errorf("third occurrence ended the game %q by %q", g.Result, g.Reason)
+	}
+}
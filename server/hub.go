@@ -10,6 +10,9 @@ type Hub struct {
 
 	// Unregister requests from clients
 	unregister chan *Client
+
+	// Messages sent to all clients
+	broadcast chan []byte
 }
 
 // NewHub creates a new instance of Hub
@@ -17,6 +20,7 @@ func NewHub() *Hub {
 	return &Hub{
 		register:   make(chan *Client),
 		unregister: make(chan *Client),
+		broadcast:  make(chan []byte),
 		clients:    make(map[*Client]bool),
 	}
 }
@@ -30,7 +34,11 @@ func (h *Hub) Run() {
 		case client := <-h.unregister:
 			if _, ok := h.clients[client]; ok {
 				delete(h.clients, client)
-				close(client.send)
+				client.close()
+			}
+		case message := <-h.broadcast:
+			for client := range h.clients {
+				client.deliver(message)
 			}
 		}
 	}
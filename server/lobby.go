@@ -0,0 +1,441 @@
+package server
+
+import (
+	"encoding/json"
+	"log"
+	"math"
+	"math/rand"
+	"sync"
+
+	"github.com/satori/go.uuid"
+)
+
+const (
+	// maxSeeksPerPlayer keeps a player from flooding the seek list
+	maxSeeksPerPlayer = 3
+	// maxChallengesPerPlayer keeps a player from flooding others with
+	// challenges
+	maxChallengesPerPlayer = 3
+)
+
+// Seek is an open invitation to a game visible to everybody in the lobby
+type Seek struct {
+	ID          string `json:"id"`
+	PlayerID    string `json:"player_id"`
+	Name        string `json:"name,omitempty"`
+	TimeControl string `json:"time_control"`
+	// Orientation the player who created the seek plays, empty for random
+	Orientation string `json:"orientation,omitempty"`
+	Rated       bool   `json:"rated"`
+	// Rating of the player and how far from it the opponent's rating may be,
+	// zero range accepts anybody
+	Rating      int `json:"rating"`
+	RatingRange int `json:"rating_range,omitempty"`
+
+	client      *Client
+	timeControl *TimeControl
+}
+
+// Challenge is an invitation to a game sent to a single player
+type Challenge struct {
+	ID             string `json:"id"`
+	ChallengerID   string `json:"challenger_id"`
+	ChallengerName string `json:"challenger_name,omitempty"`
+	// PlayerID is the challenged player
+	PlayerID    string `json:"player_id"`
+	TimeControl string `json:"time_control"`
+	// Orientation the challenger plays, empty for random
+	Orientation string `json:"orientation,omitempty"`
+	Rated       bool   `json:"rated"`
+
+	client      *Client
+	timeControl *TimeControl
+}
+
+// Lobby keeps online players, their seeks and challenges
+type Lobby struct {
+	mu         sync.Mutex
+	clients    map[string]map[*Client]bool
+	seeks      []*Seek
+	challenges map[string]*Challenge
+}
+
+// NewLobby creates a new instance of Lobby
+func NewLobby() *Lobby {
+	return &Lobby{
+		clients:    make(map[string]map[*Client]bool),
+		challenges: make(map[string]*Challenge),
+	}
+}
+
+// Enter adds a connected client, a player can be connected more than once
+func (l *Lobby) Enter(c *Client) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.clients[c.PlayerID] == nil {
+		l.clients[c.PlayerID] = make(map[*Client]bool)
+	}
+	l.clients[c.PlayerID][c] = true
+}
+
+// Leave removes a disconnected client with its seeks and challenges it sent,
+// challenges sent to the player are removed once their last client leaves.
+// The removed seeks, sent and received challenges are returned.
+func (l *Lobby) Leave(c *Client) ([]*Seek, []*Challenge, []*Challenge) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.clients[c.PlayerID], c)
+	offline := len(l.clients[c.PlayerID]) == 0
+	if offline {
+		delete(l.clients, c.PlayerID)
+	}
+
+	var seeks []*Seek
+	for i := 0; i < len(l.seeks); i++ {
+		if l.seeks[i].client == c {
+			seeks = append(seeks, l.seeks[i])
+			l.seeks = append(l.seeks[:i], l.seeks[i+1:]...)
+			i--
+		}
+	}
+
+	var sent, received []*Challenge
+	for id, ch := range l.challenges {
+		switch {
+		case ch.client == c:
+			sent = append(sent, ch)
+		case offline && ch.PlayerID == c.PlayerID:
+			received = append(received, ch)
+		default:
+			continue
+		}
+		delete(l.challenges, id)
+	}
+
+	return seeks, sent, received
+}
+
+// Clients returns all connections of a player
+func (l *Lobby) Clients(playerID string) []*Client {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var clients []*Client
+	for c := range l.clients[playerID] {
+		clients = append(clients, c)
+	}
+	return clients
+}
+
+// AddSeek publishes a seek
+func (l *Lobby) AddSeek(s *Seek) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	count := 0
+	for _, seek := range l.seeks {
+		if seek.PlayerID == s.PlayerID {
+			count++
+		}
+	}
+	if count >= maxSeeksPerPlayer {
+		return ErrTooManySeeks
+	}
+
+	l.seeks = append(l.seeks, s)
+
+	return nil
+}
+
+// RemoveSeek removes a seek, only the player who created it can remove it
+func (l *Lobby) RemoveSeek(seekID, playerID string) (*Seek, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for i, s := range l.seeks {
+		if s.ID != seekID {
+			continue
+		}
+		if playerID != "" && s.PlayerID != playerID {
+			return nil, ErrNotYourSeek
+		}
+		l.seeks = append(l.seeks[:i], l.seeks[i+1:]...)
+		return s, nil
+	}
+
+	return nil, NewSeekNotFoundError(seekID)
+}
+
+// Seeks returns all open seeks
+func (l *Lobby) Seeks() []*Seek {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return append(make([]*Seek, 0, len(l.seeks)), l.seeks...)
+}
+
+// AddChallenge sends a challenge
+func (l *Lobby) AddChallenge(ch *Challenge) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	count := 0
+	for _, challenge := range l.challenges {
+		if challenge.ChallengerID == ch.ChallengerID {
+			count++
+		}
+	}
+	if count >= maxChallengesPerPlayer {
+		return ErrTooManyChallenges
+	}
+
+	l.challenges[ch.ID] = ch
+
+	return nil
+}
+
+// TakeChallenge removes a challenge sent to a player and returns it
+func (l *Lobby) TakeChallenge(challengeID, playerID string) (*Challenge, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	ch, ok := l.challenges[challengeID]
+	if !ok || ch.PlayerID != playerID {
+		return nil, NewChallengeNotFoundError(challengeID)
+	}
+	delete(l.challenges, challengeID)
+
+	return ch, nil
+}
+
+// CreateSeek publishes a seek of a player to the lobby
+func (e *Engine) CreateSeek(c *Client, orientation string, tc *TimeControl, rated bool, ratingRange int) error {
+	switch orientation {
+	case "", OrientationWhite, OrientationBlack:
+	default:
+		return ErrInvalidOrientation
+	}
+
+	s := &Seek{
+		ID:          uuid.NewV4().String(),
+		PlayerID:    c.PlayerID,
+		Name:        c.Name,
+		TimeControl: tc.String(),
+		Orientation: orientation,
+		Rated:       rated && tc.Category() != "",
+		Rating:      int(e.playerRating(c.PlayerID, tc)),
+		RatingRange: ratingRange,
+		client:      c,
+		timeControl: tc,
+	}
+	if err := e.lobby.AddSeek(s); err != nil {
+		return err
+	}
+
+	log.Printf("Player %s created seek %s", c.PlayerID, s.ID)
+
+	return e.broadcastSeeks()
+}
+
+// CancelSeek removes a seek of a player from the lobby
+func (e *Engine) CancelSeek(c *Client, seekID string) error {
+	if _, err := e.lobby.RemoveSeek(seekID, c.PlayerID); err != nil {
+		return err
+	}
+	return e.broadcastSeeks()
+}
+
+// AcceptSeek starts a game with the player who created the seek,
+// PlayerOfflineError is returned when that player has disconnected
+func (e *Engine) AcceptSeek(c *Client, seekID string) error {
+	for _, s := range e.lobby.Seeks() {
+		if s.ID != seekID {
+			continue
+		}
+		if s.PlayerID == c.PlayerID {
+			return ErrOwnSeek
+		}
+		rating := e.playerRating(c.PlayerID, s.timeControl)
+		if s.RatingRange > 0 && math.Abs(rating-float64(s.Rating)) > float64(s.RatingRange) {
+			return ErrRatingOutOfRange
+		}
+	}
+
+	// Removing the seek makes sure only one player can accept it
+	s, err := e.lobby.RemoveSeek(seekID, "")
+	if err != nil {
+		return err
+	}
+	if err := e.broadcastSeeks(); err != nil {
+		return err
+	}
+
+	// The seek may be accepted just before it is removed from a disconnected
+	// player, startGame checks again once both players joined
+	if s.client.isClosed() {
+		return NewPlayerOfflineError(s.PlayerID)
+	}
+
+	white, black := pickColors(s.client, c, s.Orientation)
+	return e.startGame(white, black, s.timeControl, s.Rated)
+}
+
+// Challenge invites an online player to a game
+func (e *Engine) Challenge(c *Client, playerID, orientation string, tc *TimeControl, rated bool) error {
+	switch orientation {
+	case "", OrientationWhite, OrientationBlack:
+	default:
+		return ErrInvalidOrientation
+	}
+	if playerID == c.PlayerID {
+		return ErrChallengeYourself
+	}
+
+	clients := e.lobby.Clients(playerID)
+	if len(clients) == 0 {
+		return NewPlayerOfflineError(playerID)
+	}
+
+	ch := &Challenge{
+		ID:             uuid.NewV4().String(),
+		ChallengerID:   c.PlayerID,
+		ChallengerName: c.Name,
+		PlayerID:       playerID,
+		TimeControl:    tc.String(),
+		Orientation:    orientation,
+		Rated:          rated && tc.Category() != "",
+		client:         c,
+		timeControl:    tc,
+	}
+	if err := e.lobby.AddChallenge(ch); err != nil {
+		return err
+	}
+
+	log.Printf("Player %s challenged player %s", c.PlayerID, playerID)
+
+	msg := &Message{
+		Type: "challenge_received",
+		Data: &MessageData{Challenge: ch},
+	}
+	for _, client := range clients {
+		if err := client.Notify(msg); err != nil {
+			return err
+		}
+	}
+
+	return c.Notify(&Message{
+		Type: "challenge_sent",
+		Data: &MessageData{Challenge: ch},
+	})
+}
+
+// AcceptChallenge starts a game with the challenger, PlayerOfflineError is
+// returned when the challenger has disconnected
+func (e *Engine) AcceptChallenge(c *Client, challengeID string) error {
+	ch, err := e.lobby.TakeChallenge(challengeID, c.PlayerID)
+	if err != nil {
+		return err
+	}
+
+	// The challenge may be accepted just before it is cancelled for
+	// a disconnected challenger, startGame checks again once both players
+	// joined
+	if ch.client.isClosed() {
+		return NewPlayerOfflineError(ch.ChallengerID)
+	}
+
+	white, black := pickColors(ch.client, c, ch.Orientation)
+	return e.startGame(white, black, ch.timeControl, ch.Rated)
+}
+
+// DeclineChallenge lets the challenger know the challenge was declined
+func (e *Engine) DeclineChallenge(c *Client, challengeID string) error {
+	ch, err := e.lobby.TakeChallenge(challengeID, c.PlayerID)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Player %s declined challenge of player %s", c.PlayerID, ch.ChallengerID)
+
+	return ch.client.Notify(&Message{
+		Type: "challenge_declined",
+		Data: &MessageData{Challenge: ch},
+	})
+}
+
+// leaveLobby removes seeks and challenges of a disconnected client, both
+// sides of a removed challenge which are still online are notified
+func (e *Engine) leaveLobby(c *Client) {
+	seeks, sent, received := e.lobby.Leave(c)
+
+	for _, ch := range sent {
+		msg := &Message{
+			Type: "challenge_cancelled",
+			Data: &MessageData{Challenge: ch},
+		}
+		for _, client := range e.lobby.Clients(ch.PlayerID) {
+			client.Notify(msg)
+		}
+	}
+	for _, ch := range received {
+		ch.client.Notify(&Message{
+			Type: "challenge_cancelled",
+			Data: &MessageData{Challenge: ch},
+		})
+	}
+
+	if len(seeks) > 0 {
+		if err := e.broadcastSeeks(); err != nil {
+			log.Printf("Error broadcasting seeks: %v", err)
+		}
+	}
+}
+
+// broadcastSeeks sends the seek list to every connected client
+func (e *Engine) broadcastSeeks() error {
+	data, err := json.Marshal(e.seeksMessage())
+	if err != nil {
+		return err
+	}
+	e.hub.broadcast <- data
+	return nil
+}
+
+func (e *Engine) seeksMessage() *Message {
+	return &Message{
+		Type: "seeks",
+		Data: &MessageData{Seeks: e.lobby.Seeks()},
+	}
+}
+
+// playerRating returns rating of a player in the category of a time control
+func (e *Engine) playerRating(playerID string, tc *TimeControl) float64 {
+	ratings, err := e.store.LoadRatings(playerID)
+	if err != nil {
+		// Players without stored ratings are seeded as new players
+		ratings = NewPlayerRatings(playerID)
+	}
+	return ratings.Rating(tc.Category()).Rating
+}
+
+// pickColors returns white and black, orientation is what the player who
+// invited the other one plays
+func pickColors(inviter, invited *Client, orientation string) (*Client, *Client) {
+	if orientation == "" {
+		orientation = randomOrientation()
+	}
+	if orientation == OrientationWhite {
+		return inviter, invited
+	}
+	return invited, inviter
+}
+
+// randomOrientation returns white or black with equal chance
+func randomOrientation() string {
+	if rand.Intn(2) == 0 {
+		return OrientationWhite
+	}
+	return OrientationBlack
+}
@@ -0,0 +1,172 @@
+package server
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSeeks(t *testing.T) {
+	e := NewEngine(NewMemoryStore())
+	go e.hub.Run()
+
+	alice := e.NewClient(nil, "alice", "Alice")
+	bob := e.NewClient(nil, "bob", "Bob")
+	receive(t, bob, "seeks")
+
+	blitz, _ := ParseTimeControl("300+3")
+	if err := e.CreateSeek(alice, OrientationBlack, blitz, true, 100); err != nil {
+		t.Fatal(err)
+	}
+	seeks := receive(t, bob, "seeks").Data.Seeks
+	if len(seeks) != 1 || seeks[0].PlayerID != "alice" || !seeks[0].Rated || seeks[0].Rating != 1500 {
+		t.Fatalf("seek list %+v", seeks)
+	}
+
+	if err := e.AcceptSeek(alice, seeks[0].ID); err != ErrOwnSeek {
+		t.Errorf("accepting own seek returned %v", err)
+	}
+	if err := e.CancelSeek(bob, seeks[0].ID); err != ErrNotYourSeek {
+		t.Errorf("cancelling another player's seek returned %v", err)
+	}
+
+	if err := e.AcceptSeek(bob, seeks[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if msg := receive(t, bob, "match_found"); msg.Data.Orientation != OrientationWhite || !msg.Data.Rated {
+		t.Errorf("bob got %+v", msg.Data)
+	}
+	if len(receive(t, bob, "seeks").Data.Seeks) != 0 {
+		t.Error("accepted seek still listed")
+	}
+	if _, ok := e.AcceptSeek(alice, seeks[0].ID).(*SeekNotFoundError); !ok {
+		t.Error("seek accepted twice")
+	}
+
+	// A seek of a player who disconnected does not start a game
+	carol := e.NewClient(nil, "carol", "Carol")
+	if err := e.CreateSeek(carol, "", blitz, false, 0); err != nil {
+		t.Fatal(err)
+	}
+	seeks = receive(t, bob, "seeks").Data.Seeks
+	carol.close()
+	if _, ok := e.AcceptSeek(bob, seeks[0].ID).(*PlayerOfflineError); !ok {
+		t.Error("seek of a disconnected player accepted")
+	}
+	if games := activeGames(e); games != 1 {
+		t.Errorf("%d games active, want 1", games)
+	}
+}
+
+// activeGames returns number of games in memory
+func activeGames(e *Engine) int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return len(e.games)
+}
+
+func TestChallenges(t *testing.T) {
+	e := NewEngine(NewMemoryStore())
+	go e.hub.Run()
+
+	alice := e.NewClient(nil, "alice", "Alice")
+	if _, ok := e.Challenge(alice, "bob", "", nil, false).(*PlayerOfflineError); !ok {
+		t.Error("offline player challenged")
+	}
+	if err := e.Challenge(alice, "alice", "", nil, false); err != ErrChallengeYourself {
+		t.Errorf("challenging yourself returned %v", err)
+	}
+
+	bob := e.NewClient(nil, "bob", "Bob")
+	if err := e.Challenge(alice, "bob", OrientationWhite, nil, true); err != nil {
+		t.Fatal(err)
+	}
+	ch := receive(t, bob, "challenge_received").Data.Challenge
+	if ch.ChallengerID != "alice" || ch.Rated {
+		t.Errorf("challenge %+v, untimed games cannot be rated", ch)
+	}
+
+	if _, ok := e.AcceptChallenge(alice, ch.ID).(*ChallengeNotFoundError); !ok {
+		t.Error("challenger accepted own challenge")
+	}
+	if err := e.DeclineChallenge(bob, ch.ID); err != nil {
+		t.Fatal(err)
+	}
+	receive(t, alice, "challenge_declined")
+
+	if err := e.Challenge(alice, "bob", OrientationWhite, nil, false); err != nil {
+		t.Fatal(err)
+	}
+	ch = receive(t, bob, "challenge_received").Data.Challenge
+	if err := e.AcceptChallenge(bob, ch.ID); err != nil {
+		t.Fatal(err)
+	}
+	if msg := receive(t, alice, "match_found"); msg.Data.Orientation != OrientationWhite {
+		t.Errorf("alice plays %s, want white", msg.Data.Orientation)
+	}
+
+	// A challenger who disconnects while the game is set up is reported
+	// offline, holding the engine lock stops accepting right before the
+	// players join
+	erin := e.NewClient(nil, "erin", "Erin")
+	if err := e.Challenge(erin, "bob", "", nil, false); err != nil {
+		t.Fatal(err)
+	}
+	ch = receive(t, bob, "challenge_received").Data.Challenge
+	e.mu.Lock()
+	accepted := make(chan error)
+	go func() {
+		accepted <- e.AcceptChallenge(bob, ch.ID)
+	}()
+	time.Sleep(50 * time.Millisecond)
+	erin.close()
+	e.mu.Unlock()
+	if _, ok := (<-accepted).(*PlayerOfflineError); !ok {
+		t.Error("challenge of a disconnected player accepted")
+	}
+	if games := activeGames(e); games != 1 {
+		t.Errorf("%d games active, want 1", games)
+	}
+
+	// Challenges of a disconnected player are cancelled
+	if err := e.Challenge(alice, "bob", "", nil, false); err != nil {
+		t.Fatal(err)
+	}
+	e.leaveLobby(alice)
+	receive(t, bob, "challenge_cancelled")
+
+	// Challenges sent to a player are cancelled once their last connection
+	// is gone
+	carol := e.NewClient(nil, "carol", "Carol")
+	bobAgain := e.NewClient(nil, "bob", "Bob")
+	if err := e.Challenge(carol, "bob", "", nil, false); err != nil {
+		t.Fatal(err)
+	}
+	ch = receive(t, bob, "challenge_received").Data.Challenge
+	receive(t, carol, "challenge_sent")
+	e.leaveLobby(bob)
+	for len(carol.send) > 0 {
+		if data := <-carol.send; strings.Contains(string(data), "challenge_cancelled") {
+			t.Fatal("challenge cancelled while bob is still connected")
+		}
+	}
+	e.leaveLobby(bobAgain)
+	if msg := receive(t, carol, "challenge_cancelled"); msg.Data.Challenge.ID != ch.ID {
+		t.Errorf("cancelled challenge %s, want %s", msg.Data.Challenge.ID, ch.ID)
+	}
+	if _, err := e.lobby.TakeChallenge(ch.ID, "bob"); err == nil {
+		t.Error("challenge of an offline player was kept")
+	}
+
+	// Players can only have a few open challenges
+	e.NewClient(nil, "dave", "Dave")
+	for i := 0; i < maxChallengesPerPlayer; i++ {
+		if err := e.Challenge(carol, "dave", "", nil, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := e.Challenge(carol, "dave", "", nil, false); err != ErrTooManyChallenges {
+		t.Errorf("challenge over the limit returned %v", err)
+	}
+}
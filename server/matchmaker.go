@@ -0,0 +1,174 @@
+package server
+
+import (
+	"log"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultRatingRange is used by seekers who do not choose a range
+	defaultRatingRange = 200
+	// ratingRangeGrowth widens the rating range of seekers every second they
+	// wait so everybody is paired eventually
+	ratingRangeGrowth = 10
+	// matchInterval is how often the queue is searched for pairs
+	matchInterval = time.Second
+)
+
+// Seeker is a player waiting in the matchmaking queue
+type Seeker struct {
+	Client      *Client
+	TimeControl *TimeControl
+	// Orientation the player wants to play, empty if they do not mind
+	Orientation string
+	// Rating of the player in the category of the time control and how far
+	// from it the opponent's rating may be
+	Rating      float64
+	RatingRange float64
+	Joined      time.Time
+}
+
+// ratingRange returns how far the opponent's rating may be at a moment
+func (s *Seeker) ratingRange(now time.Time) float64 {
+	return s.RatingRange + ratingRangeGrowth*now.Sub(s.Joined).Seconds()
+}
+
+// accepts returns true if the seeker can be paired with another seeker
+func (s *Seeker) accepts(other *Seeker, now time.Time) bool {
+	if s.Client.PlayerID == other.Client.PlayerID {
+		return false
+	}
+	if s.TimeControl.String() != other.TimeControl.String() {
+		return false
+	}
+	if s.Orientation != "" && s.Orientation == other.Orientation {
+		return false
+	}
+	return math.Abs(s.Rating-other.Rating) <= s.ratingRange(now)
+}
+
+// Matchmaker pairs players waiting in a queue with compatible opponents
+type Matchmaker struct {
+	mu      sync.Mutex
+	seekers []*Seeker
+	// Called with both seekers when a pair is found, white first
+	onMatch func(white, black *Seeker)
+}
+
+// NewMatchmaker creates a new instance of Matchmaker
+func NewMatchmaker(onMatch func(white, black *Seeker)) *Matchmaker {
+	return &Matchmaker{onMatch: onMatch}
+}
+
+// Run pairs seekers periodically, ranges of waiting seekers widen over time
+func (m *Matchmaker) Run() {
+	ticker := time.NewTicker(matchInterval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		m.Match(now)
+	}
+}
+
+// Enter puts a player in the queue, a player already in the queue is
+// replaced so they only seek one game at a time
+func (m *Matchmaker) Enter(s *Seeker) {
+	if s.RatingRange <= 0 {
+		s.RatingRange = defaultRatingRange
+	}
+
+	m.mu.Lock()
+	m.remove(s.Client)
+	m.seekers = append(m.seekers, s)
+	m.mu.Unlock()
+
+	log.Printf("Player %s entered the queue for %q rated %.0f", s.Client.PlayerID, s.TimeControl.String(), s.Rating)
+
+	m.Match(s.Joined)
+}
+
+// Leave removes a player from the queue, returns false if they were not in it
+func (m *Matchmaker) Leave(c *Client) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.remove(c)
+}
+
+// Return puts a seeker back in the queue after their opponent left before
+// the game was created, they keep waiting since they first joined
+func (m *Matchmaker) Return(s *Seeker) {
+	m.mu.Lock()
+	m.remove(s.Client)
+	m.seekers = append(m.seekers, s)
+	m.mu.Unlock()
+
+	log.Printf("Player %s returned to the queue, the opponent left", s.Client.PlayerID)
+}
+
+// Match pairs compatible seekers, those waiting longest are paired first
+func (m *Matchmaker) Match(now time.Time) {
+	var pairs [][2]*Seeker
+
+	m.mu.Lock()
+	sort.SliceStable(m.seekers, func(i, j int) bool {
+		return m.seekers[i].Joined.Before(m.seekers[j].Joined)
+	})
+	for i := 0; i < len(m.seekers); i++ {
+		for j := i + 1; j < len(m.seekers); j++ {
+			a, b := m.seekers[i], m.seekers[j]
+			if !a.accepts(b, now) || !b.accepts(a, now) {
+				continue
+			}
+			pairs = append(pairs, colors(a, b))
+			m.seekers = append(m.seekers[:j], m.seekers[j+1:]...)
+			m.seekers = append(m.seekers[:i], m.seekers[i+1:]...)
+			i--
+			break
+		}
+	}
+	m.mu.Unlock()
+
+	// Games are created outside of the lock as it notifies clients
+	for _, pair := range pairs {
+		m.onMatch(pair[0], pair[1])
+	}
+}
+
+// Len returns number of players in the queue
+func (m *Matchmaker) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return len(m.seekers)
+}
+
+// remove takes a client out of the queue, the mutex must be held
+func (m *Matchmaker) remove(c *Client) bool {
+	for i, s := range m.seekers {
+		if s.Client == c {
+			m.seekers = append(m.seekers[:i], m.seekers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// colors returns the seekers as white and black respecting preferences,
+// colors are random if neither has one
+func colors(a, b *Seeker) [2]*Seeker {
+	switch {
+	case a.Orientation == OrientationWhite, b.Orientation == OrientationBlack:
+		return [2]*Seeker{a, b}
+	case a.Orientation == OrientationBlack, b.Orientation == OrientationWhite:
+		return [2]*Seeker{b, a}
+	case rand.Intn(2) == 0:
+		return [2]*Seeker{a, b}
+	default:
+		return [2]*Seeker{b, a}
+	}
+}
@@ -0,0 +1,131 @@
+package server
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMatchmaker(t *testing.T) {
+	var matches [][2]*Seeker
+	m := NewMatchmaker(func(white, black *Seeker) {
+		matches = append(matches, [2]*Seeker{white, black})
+	})
+
+	blitz, _ := ParseTimeControl("300+3")
+	bullet, _ := ParseTimeControl("60+0")
+	now := time.Now()
+	seeker := func(playerID, orientation string, tc *TimeControl, rating float64) *Seeker {
+		return &Seeker{
+			Client:      &Client{PlayerID: playerID},
+			TimeControl: tc,
+			Orientation: orientation,
+			Rating:      rating,
+			Joined:      now,
+		}
+	}
+
+	alice := seeker("alice", OrientationBlack, blitz, 1500)
+	bob := seeker("bob", "", blitz, 1900)
+	carol := seeker("carol", OrientationBlack, blitz, 1550)
+	dave := seeker("dave", "", bullet, 1500)
+
+	m.Enter(alice)
+	m.Enter(dave)
+	m.Enter(carol)
+	if len(matches) != 0 {
+		t.Fatalf("players wanting the same color or another time control were paired: %v", matches)
+	}
+
+	// Ratings are too far apart until the ranges widen
+	m.Enter(bob)
+	if len(matches) != 0 {
+		t.Fatal("players with distant ratings paired right away")
+	}
+	m.Match(now.Add(25 * time.Second))
+	if len(matches) != 1 {
+		t.Fatalf("%d matches after ranges widened, want 1", len(matches))
+	}
+	if white, black := matches[0][0], matches[0][1]; white != bob || black != alice {
+		t.Errorf("paired %s with white and %s with black, want bob and alice", white.Client.PlayerID, black.Client.PlayerID)
+	}
+
+	if m.Len() != 2 {
+		t.Errorf("%d players left in the queue, want 2", m.Len())
+	}
+	if !m.Leave(carol.Client) || m.Leave(carol.Client) {
+		t.Error("leaving the queue")
+	}
+
+	// Entering again replaces the previous seek
+	again := seeker("dave", "", blitz, 1500)
+	again.Client = dave.Client
+	m.Enter(again)
+	if m.Len() != 1 {
+		t.Errorf("%d players in the queue after entering twice, want 1", m.Len())
+	}
+}
+
+func TestMatchWithDisconnectedPlayer(t *testing.T) {
+	e := NewEngine(NewMemoryStore())
+	blitz, _ := ParseTimeControl("300+3")
+
+	alice := &Client{PlayerID: "alice", send: make(chan []byte, 16), engine: e}
+	bob := &Client{PlayerID: "bob", send: make(chan []byte, 16), engine: e}
+	joined := time.Now().Add(-time.Minute)
+	white := &Seeker{Client: alice, TimeControl: blitz, Rating: 1500, RatingRange: defaultRatingRange, Joined: joined}
+	black := &Seeker{Client: bob, TimeControl: blitz, Rating: 1500, RatingRange: defaultRatingRange, Joined: joined}
+
+	// Alice disconnects after the matchmaker paired her with bob
+	alice.close()
+	e.startMatch(white, black)
+
+	e.mu.Lock()
+	games := len(e.games)
+	e.mu.Unlock()
+	if games != 0 {
+		t.Errorf("%d games started with a disconnected player", games)
+	}
+	if len(bob.send) != 0 {
+		t.Errorf("bob was sent %d messages", len(bob.send))
+	}
+	if e.matchmaker.Len() != 1 || !e.matchmaker.Leave(bob) {
+		t.Error("bob did not go back to the queue")
+	}
+}
+
+func TestMatchPlayerDisconnectsWhileJoining(t *testing.T) {
+	e := NewEngine(NewMemoryStore())
+	blitz, _ := ParseTimeControl("300+3")
+
+	alice := &Client{PlayerID: "alice", send: make(chan []byte, 16), engine: e}
+	bob := &Client{PlayerID: "bob", send: make(chan []byte, 16), engine: e}
+	joined := time.Now().Add(-time.Minute)
+	white := &Seeker{Client: alice, TimeControl: blitz, Rating: 1500, RatingRange: defaultRatingRange, Joined: joined}
+	black := &Seeker{Client: bob, TimeControl: blitz, Rating: 1500, RatingRange: defaultRatingRange, Joined: joined}
+
+	// Holding the engine lock stops the match right before the players join
+	// the new game, alice disconnects in between
+	e.mu.Lock()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		e.startMatch(white, black)
+	}()
+	time.Sleep(50 * time.Millisecond)
+	alice.close()
+	e.mu.Unlock()
+	<-done
+
+	e.mu.Lock()
+	games := len(e.games)
+	e.mu.Unlock()
+	if games != 0 {
+		t.Errorf("%d games left with a disconnected player", games)
+	}
+	if len(bob.send) != 0 {
+		t.Errorf("bob was sent %d messages", len(bob.send))
+	}
+	if e.matchmaker.Len() != 1 || !e.matchmaker.Leave(bob) {
+		t.Error("bob did not go back to the queue")
+	}
+}
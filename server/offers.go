@@ -0,0 +1,225 @@
+package server
+
+import (
+	"log"
+	"time"
+
+	"github.com/RichardKnop/chess-engine/chess"
+)
+
+// Resign ends the game lost by the player who resigned
+func (g *Game) Resign(playerID string) error {
+	color, err := g.playerColor(playerID)
+	if err != nil {
+		return err
+	}
+
+	g.Result, g.Reason = ResultWhiteWins, ReasonResignation
+	if color == chess.White {
+		g.Result = ResultBlackWins
+	}
+	g.finish()
+
+	return g.NotifyGameOver()
+}
+
+// OfferDraw offers a draw to the opponent, offering a draw when the opponent
+// has already offered one accepts it
+func (g *Game) OfferDraw(playerID string) error {
+	color, err := g.playerColor(playerID)
+	if err != nil {
+		return err
+	}
+
+	if g.DrawOffer != "" && g.DrawOffer != playerID {
+		return g.AcceptDraw(playerID)
+	}
+
+	g.DrawOffer = playerID
+	log.Printf("Player %s offered a draw in game %s", playerID, g.ID)
+
+	if err := g.notifyOffer("draw_offered", playerID); err != nil {
+		return err
+	}
+
+	if g.Computer != nil && g.Computer.Color != color {
+		if g.Computer.AcceptsDraw() {
+			return g.AcceptDraw(g.Computer.PlayerID)
+		}
+		return g.DeclineDraw(g.Computer.PlayerID)
+	}
+
+	return nil
+}
+
+// AcceptDraw ends the game in a draw if the opponent offered one
+func (g *Game) AcceptDraw(playerID string) error {
+	if _, err := g.playerColor(playerID); err != nil {
+		return err
+	}
+	if g.DrawOffer == "" || g.DrawOffer == playerID {
+		return ErrNoDrawOffer
+	}
+
+	g.DrawOffer = ""
+	g.Result, g.Reason = ResultDraw, ReasonAgreement
+	g.finish()
+
+	return g.NotifyGameOver()
+}
+
+// DeclineDraw rejects a draw offered by the opponent
+func (g *Game) DeclineDraw(playerID string) error {
+	if _, err := g.playerColor(playerID); err != nil {
+		return err
+	}
+	if g.DrawOffer == "" || g.DrawOffer == playerID {
+		return ErrNoDrawOffer
+	}
+
+	g.DrawOffer = ""
+
+	return g.notifyOffer("draw_declined", playerID)
+}
+
+// RequestTakeback asks the opponent to take back the last move of the player
+func (g *Game) RequestTakeback(playerID string) error {
+	color, err := g.playerColor(playerID)
+	if err != nil {
+		return err
+	}
+	if g.takebackPlies(color) == 0 {
+		return ErrNothingToTakeBack
+	}
+
+	g.TakebackRequest = playerID
+	log.Printf("Player %s requested a takeback in game %s", playerID, g.ID)
+
+	if err := g.notifyOffer("takeback_requested", playerID); err != nil {
+		return err
+	}
+
+	// The computer is always happy to let a player take a move back
+	if g.Computer != nil && g.Computer.Color != color {
+		return g.AcceptTakeback(g.Computer.PlayerID)
+	}
+
+	return nil
+}
+
+// AcceptTakeback takes back the last move of the player who requested it and
+// any move played by the opponent since
+func (g *Game) AcceptTakeback(playerID string) error {
+	if _, err := g.playerColor(playerID); err != nil {
+		return err
+	}
+	if g.TakebackRequest == "" || g.TakebackRequest == playerID {
+		return ErrNoTakebackRequest
+	}
+
+	requester, err := g.playerColor(g.TakebackRequest)
+	if err != nil {
+		return err
+	}
+	plies := g.takebackPlies(requester)
+	if plies == 0 {
+		return ErrNothingToTakeBack
+	}
+
+	g.TakebackRequest, g.DrawOffer = "", ""
+	g.History = g.History[:len(g.History)-plies]
+	g.Moves = g.Moves[:len(g.Moves)-plies]
+	g.Position = g.History[len(g.History)-1]
+
+	now := time.Now()
+	if g.Clock != nil {
+		g.rewindClock(now)
+	}
+
+	g.save()
+
+	log.Printf("Took back %d moves in game %s", plies, g.ID)
+
+	msg := &Message{
+		Type: "takeback_accepted",
+		Data: &MessageData{
+			GameID:   g.ID,
+			Position: g.FEN(),
+			Clock:    g.clockState(now),
+		},
+	}
+	if activePlayerID := g.getActivePlayerID(); activePlayerID != nil {
+		msg.Data.PlayerID = *activePlayerID
+	}
+	if err := g.notifyPlayers(msg); err != nil {
+		return err
+	}
+
+	g.scheduleFlag()
+	g.requestComputerMove()
+
+	return nil
+}
+
+// rewindClock sets the clock back to the position after moves were taken
+// back, each player gets the time they had after their last remaining move
+func (g *Game) rewindClock(now time.Time) {
+	var moves [2]int
+	initial := g.TimeControl.Periods[0].Time
+	remaining := [2]time.Duration{initial, initial}
+	for i, move := range g.Moves {
+		color := g.History[i].Turn()
+		moves[color]++
+		remaining[color] = time.Duration(move.Clock) * time.Millisecond
+	}
+	g.Clock.Rewind(g.Position.Turn(), moves, remaining, now)
+}
+
+// takebackPlies returns how many moves have to be taken back so the player
+// of a color is on the move again before their last move
+func (g *Game) takebackPlies(color chess.Color) int {
+	plies := 1
+	if g.Position.Turn() == color {
+		// The opponent has already replied
+		plies = 2
+	}
+	if plies > len(g.Moves) {
+		return 0
+	}
+	return plies
+}
+
+// playerColor returns color of a player seated in a game which is not over
+func (g *Game) playerColor(playerID string) (chess.Color, error) {
+	if g.Imported {
+		return chess.White, ErrImportedGame
+	}
+	if g.IsOver() {
+		return chess.White, ErrGameOver
+	}
+
+	switch {
+	case g.White != nil && g.White.PlayerID == playerID:
+		return chess.White, nil
+	case g.Black != nil && g.Black.PlayerID == playerID:
+		return chess.Black, nil
+	case g.Computer != nil && g.Computer.PlayerID == playerID:
+		return g.Computer.Color, nil
+	}
+
+	return chess.White, NewNotPlayingError(playerID, g.ID)
+}
+
+// notifyOffer notifies players about a draw offer, a takeback request or
+// a similar action of a player
+func (g *Game) notifyOffer(msgType, playerID string) error {
+	msg := &Message{
+		Type: msgType,
+		Data: &MessageData{
+			GameID:   g.ID,
+			Position: g.FEN(),
+			PlayerID: playerID,
+		},
+	}
+	return g.notifyPlayers(msg)
+}
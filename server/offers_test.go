@@ -0,0 +1,172 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/RichardKnop/chess-engine/chess"
+)
+
+// startPrivateGame creates a game between alice playing white and bob
+// playing black and returns their connections and ID of the game
+func startPrivateGame(t *testing.T, url, timeControl string) (*testClient, *testClient, string) {
+	white := dialEngine(t, url, "alice")
+	black := dialEngine(t, url, "bob")
+
+	if err := white.send("create_private_game", &MessageData{Orientation: OrientationWhite, TimeControl: timeControl}); err != nil {
+		t.Fatal(err)
+	}
+	created, err := white.await("private_game_created")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gameID := created.Data.GameID
+
+	if err := black.send("get_game", &MessageData{GameID: gameID, InviteCode: created.Data.InviteCode, Orientation: OrientationBlack}); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []*testClient{white, black} {
+		if _, err := c.await("game_started"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return white, black, gameID
+}
+
+// expect sends a message and waits until every client receives a message of
+// the type
+func expect(t *testing.T, from *testClient, msgType string, data *MessageData, replyType string, to ...*testClient) []*Message {
+	if err := from.send(msgType, data); err != nil {
+		t.Fatal(err)
+	}
+	replies := make([]*Message, len(to))
+	for i, c := range to {
+		msg, err := c.await(replyType)
+		if err != nil {
+			t.Fatalf("%s: %v", msgType, err)
+		}
+		replies[i] = msg
+	}
+	return replies
+}
+
+func TestOffers(t *testing.T) {
+	e := NewEngine(NewMemoryStore())
+	go e.Run()
+
+	server, url := serveEngine(e)
+	defer server.Close()
+
+	t.Run("resign", func(t *testing.T) {
+		white, black, gameID := startPrivateGame(t, url, "")
+		defer white.conn.Close()
+		defer black.conn.Close()
+
+		for _, msg := range expect(t, black, "resign", &MessageData{GameID: gameID}, "game_over", white, black) {
+			if msg.Data.Result != ResultWhiteWins || msg.Data.Reason != ReasonResignation {
+				t.Errorf("resignation ended the game %s by %s", msg.Data.Result, msg.Data.Reason)
+			}
+		}
+
+		msg := expect(t, white, "resign", &MessageData{GameID: gameID}, "error", white)[0]
+		if msg.Data.Error != ErrGameOver.Error() {
+			t.Errorf("resigning a finished game returned %s", msg.Data.Error)
+		}
+	})
+
+	t.Run("draw", func(t *testing.T) {
+		white, black, gameID := startPrivateGame(t, url, "")
+		defer white.conn.Close()
+		defer black.conn.Close()
+
+		msg := expect(t, black, "accept_draw", &MessageData{GameID: gameID}, "error", black)[0]
+		if msg.Data.Error != ErrNoDrawOffer.Error() {
+			t.Errorf("accepting a draw which was not offered returned %s", msg.Data.Error)
+		}
+
+		for _, msg := range expect(t, white, "offer_draw", &MessageData{GameID: gameID}, "draw_offered", white, black) {
+			if msg.Data.PlayerID != "alice" {
+				t.Errorf("draw offered by %s", msg.Data.PlayerID)
+			}
+		}
+		msg = expect(t, white, "accept_draw", &MessageData{GameID: gameID}, "error", white)[0]
+		if msg.Data.Error != ErrNoDrawOffer.Error() {
+			t.Errorf("accepting own draw offer returned %s", msg.Data.Error)
+		}
+		expect(t, black, "decline_draw", &MessageData{GameID: gameID}, "draw_declined", white, black)
+
+		// A declined offer cannot be accepted any more
+		msg = expect(t, black, "accept_draw", &MessageData{GameID: gameID}, "error", black)[0]
+		if msg.Data.Error != ErrNoDrawOffer.Error() {
+			t.Errorf("accepting a declined draw returned %s", msg.Data.Error)
+		}
+
+		expect(t, white, "offer_draw", &MessageData{GameID: gameID}, "draw_offered", white, black)
+		for _, msg := range expect(t, black, "accept_draw", &MessageData{GameID: gameID}, "game_over", white, black) {
+			if msg.Data.Result != ResultDraw || msg.Data.Reason != ReasonAgreement {
+				t.Errorf("accepted draw ended the game %s by %s", msg.Data.Result, msg.Data.Reason)
+			}
+		}
+	})
+
+	t.Run("takeback", func(t *testing.T) {
+		// Two moves in a minute, then another minute for every two moves
+		white, black, gameID := startPrivateGame(t, url, "2/60")
+		defer white.conn.Close()
+		defer black.conn.Close()
+
+		msg := expect(t, white, "request_takeback", &MessageData{GameID: gameID}, "error", white)[0]
+		if msg.Data.Error != ErrNothingToTakeBack.Error() {
+			t.Errorf("taking back before the first move returned %s", msg.Data.Error)
+		}
+
+		for _, m := range [][2]string{{"e2", "e4"}, {"e7", "e5"}, {"d2", "d4"}} {
+			c := white
+			if m[0][1] == '7' {
+				c = black
+			}
+			expect(t, c, "make_move", &MessageData{GameID: gameID, Source: m[0], Target: m[1]}, "move_made", white, black)
+		}
+
+		// White has moved into the second period and got another minute
+		g, err := e.GetGame(gameID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := g.Clock.Remaining(chess.White, time.Now()); got <= time.Minute {
+			t.Errorf("white has %s after the second move, want more than a minute", got)
+		}
+		g.mu.Unlock()
+
+		expect(t, white, "request_takeback", &MessageData{GameID: gameID}, "takeback_requested", white, black)
+		for _, msg := range expect(t, black, "accept_takeback", &MessageData{GameID: gameID}, "takeback_accepted", white, black) {
+			if msg.Data.Position != "rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq e6 0 2" {
+				t.Errorf("position after takeback %s", msg.Data.Position)
+			}
+			if msg.Data.Clock.White != 60000 || msg.Data.Clock.Turn != OrientationWhite || !msg.Data.Clock.Running {
+				t.Errorf("clock after takeback %+v", msg.Data.Clock)
+			}
+		}
+
+		// The taken back move does not count towards the period
+		g, err = e.GetGame(gameID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n := g.Clock.MovesToGo(chess.White); n != 1 {
+			t.Errorf("white has %d moves to go after takeback, want 1", n)
+		}
+		g.mu.Unlock()
+
+		expect(t, white, "make_move", &MessageData{GameID: gameID, Source: "g1", Target: "f3"}, "move_made", white, black)
+		g, err = e.GetGame(gameID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := g.Clock.Remaining(chess.White, time.Now()); got <= time.Minute {
+			t.Errorf("white has %s after replaying the second move, want more than a minute", got)
+		}
+		g.mu.Unlock()
+	})
+}
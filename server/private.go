@@ -0,0 +1,73 @@
+package server
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"log"
+)
+
+// Invite codes are short enough to be typed, ambiguous characters like 0 and
+// O are left out
+const (
+	inviteCodeLength   = 8
+	inviteCodeAlphabet = "23456789ABCDEFGHJKLMNPQRSTUVWXYZ"
+)
+
+// NewInviteCode returns a random invite code
+func NewInviteCode() (string, error) {
+	b := make([]byte, inviteCodeLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	for i := range b {
+		// The alphabet has 32 characters so every one is equally likely
+		b[i] = inviteCodeAlphabet[int(b[i])%len(inviteCodeAlphabet)]
+	}
+	return string(b), nil
+}
+
+// Admit checks a player may open a game, private games need the invite code
+// unless the player already has a seat. An empty player ID is anonymous and
+// never matches an empty seat.
+func (g *Game) Admit(playerID, inviteCode string) error {
+	if !g.Private || (playerID != "" && (g.WhiteID == playerID || g.BlackID == playerID)) {
+		return nil
+	}
+	if subtle.ConstantTimeCompare([]byte(inviteCode), []byte(g.InviteCode)) != 1 {
+		return ErrInvalidInviteCode
+	}
+	return nil
+}
+
+// NewPrivateGame creates a game only players with the invite code can open,
+// the player who creates it takes a seat right away. When invitedID is set
+// only that player can take the other seat. The game is returned locked.
+func (e *Engine) NewPrivateGame(c *Client, orientation string, tc *TimeControl, rated bool, invitedID string) (*Game, error) {
+	if orientation == "" {
+		orientation = randomOrientation()
+	}
+	if orientation != OrientationWhite && orientation != OrientationBlack {
+		return nil, ErrInvalidOrientation
+	}
+
+	code, err := NewInviteCode()
+	if err != nil {
+		return nil, err
+	}
+
+	g, err := e.newGame(InitialPosition, tc)
+	if err != nil {
+		return nil, err
+	}
+	g.Rated = rated && tc.Category() != ""
+	if err := g.Join(c, orientation); err != nil {
+		g.mu.Unlock()
+		return nil, err
+	}
+	g.Private, g.InviteCode, g.InvitedID = true, code, invitedID
+	g.save()
+
+	log.Printf("Player %s created private game %s", c.PlayerID, g.ID)
+
+	return g, nil
+}
@@ -0,0 +1,122 @@
+package server
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestInviteCode(t *testing.T) {
+	code, err := NewInviteCode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(code) != inviteCodeLength {
+		t.Errorf("invite code %s has %d characters, want %d", code, len(code), inviteCodeLength)
+	}
+	for _, r := range code {
+		if !strings.ContainsRune(inviteCodeAlphabet, r) {
+			t.Errorf("invite code %s has character %c outside of the alphabet", code, r)
+		}
+	}
+}
+
+func TestPrivateGame(t *testing.T) {
+	e := NewEngine(NewMemoryStore())
+	alice := &Client{PlayerID: "alice", send: make(chan []byte, 16)}
+	bob := &Client{PlayerID: "bob", send: make(chan []byte, 16)}
+	eve := &Client{PlayerID: "eve", send: make(chan []byte, 16)}
+
+	g, err := e.NewPrivateGame(alice, OrientationWhite, nil, false, "bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.White != alice || !g.Private || g.InviteCode == "" {
+		t.Fatalf("private game %+v", g)
+	}
+
+	if err := g.Admit("eve", ""); err != ErrInvalidInviteCode {
+		t.Errorf("opening a private game without the code returned %v", err)
+	}
+	if err := g.Admit("eve", g.InviteCode); err != nil {
+		t.Errorf("opening a private game with the code returned %v", err)
+	}
+	if err := g.Admit("alice", ""); err != nil {
+		t.Errorf("seated player could not open the game: %v", err)
+	}
+	if err := g.Admit("", ""); err != ErrInvalidInviteCode {
+		t.Errorf("anonymous player opened a game with an empty seat: %v", err)
+	}
+
+	if err := g.Join(eve, OrientationWhite); err != ErrSeatTaken {
+		t.Errorf("taking another player's seat returned %v", err)
+	}
+	if err := g.Join(eve, OrientationBlack); err != ErrNotInvited {
+		t.Errorf("taking the seat of the invited player returned %v", err)
+	}
+
+	// Rejected joins leave spectators watching
+	g.Spectators[bob] = true
+	if err := g.Join(bob, "purple"); err != ErrInvalidOrientation {
+		t.Errorf("taking a seat of an invalid color returned %v", err)
+	}
+	if !g.Spectators[bob] {
+		t.Error("invalid join removed the spectator")
+	}
+	aliceAgain := &Client{PlayerID: "alice", send: make(chan []byte, 16)}
+	if err := g.Join(aliceAgain, OrientationBlack); err != ErrBothSeats {
+		t.Errorf("taking the other seat of own game returned %v", err)
+	}
+	if g.Black != nil || g.BlackID != "" {
+		t.Errorf("alice took both seats: %+v", g)
+	}
+
+	if err := g.Join(bob, OrientationBlack); err != nil {
+		t.Fatal(err)
+	}
+	if g.Spectators[bob] {
+		t.Error("player is still watching after taking a seat")
+	}
+
+	// The invite code survives a restart
+	restored, err := RestoreGame(g.Record())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !restored.Private || restored.InviteCode != g.InviteCode || restored.InvitedID != "bob" {
+		t.Errorf("restored private game %+v", restored)
+	}
+}
+
+func TestPrivateGamePGN(t *testing.T) {
+	store := NewMemoryStore()
+	e := NewEngine(store)
+	alice := &Client{PlayerID: "alice", send: make(chan []byte, 16)}
+
+	g, err := e.NewPrivateGame(alice, OrientationWhite, nil, false, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gameID, code := g.ID, g.InviteCode
+	g.mu.Unlock()
+
+	check := func() {
+		if _, err := e.PGN(gameID, "eve", ""); err == nil {
+			t.Error("private game was exported without the invite code")
+		} else if _, ok := err.(*GameNotFoundError); !ok {
+			t.Errorf("exporting a private game without the invite code returned %v", err)
+		}
+		if _, err := e.PGN(gameID, "eve", code); err != nil {
+			t.Errorf("exporting a private game with the invite code returned %v", err)
+		}
+		if _, err := e.PGN(gameID, "alice", ""); err != nil {
+			t.Errorf("exporting a private game as its player returned %v", err)
+		}
+	}
+
+	// Active games and games read from the store
+	check()
+	e.mu.Lock()
+	delete(e.games, gameID)
+	e.mu.Unlock()
+	check()
+}
@@ -0,0 +1,157 @@
+package server
+
+import (
+	"log"
+	"time"
+
+	"github.com/RichardKnop/chess-engine/rating"
+)
+
+// Rating categories of time controls, players have a separate rating in each
+const (
+	CategoryBullet    = "bullet"
+	CategoryBlitz     = "blitz"
+	CategoryRapid     = "rapid"
+	CategoryClassical = "classical"
+)
+
+// Category returns the rating category of a time control based on the
+// expected duration of a game of 40 moves, untimed games are not rated
+func (tc *TimeControl) Category() string {
+	if tc == nil {
+		return ""
+	}
+
+	period := tc.Periods[0]
+	duration := period.Time + 40*(period.Increment+period.Delay)
+	switch {
+	case duration < 3*time.Minute:
+		return CategoryBullet
+	case duration < 8*time.Minute:
+		return CategoryBlitz
+	case duration < 25*time.Minute:
+		return CategoryRapid
+	default:
+		return CategoryClassical
+	}
+}
+
+// PlayerRatings are ratings of a player in every category they have played
+// and the history of rating changes
+type PlayerRatings struct {
+	PlayerID string                   `json:"player_id"`
+	Ratings  map[string]rating.Rating `json:"ratings"`
+	History  []*RatingChange          `json:"history"`
+}
+
+// RatingChange is a change of rating after a game
+type RatingChange struct {
+	GameID     string        `json:"game_id"`
+	Category   string        `json:"category"`
+	OpponentID string        `json:"opponent_id"`
+	Score      float64       `json:"score"`
+	Before     rating.Rating `json:"before"`
+	After      rating.Rating `json:"after"`
+	Time       time.Time     `json:"time"`
+}
+
+// RatingStore persists ratings of players
+type RatingStore interface {
+	// LoadRatings returns ratings of a player, players who have not played
+	// a rated game yet have no ratings
+	LoadRatings(playerID string) (*PlayerRatings, error)
+	// UpdateRatings changes ratings of both players of a game, concurrent
+	// updates of the same players are serialized so none is lost
+	UpdateRatings(whiteID, blackID string, update func(white, black *PlayerRatings)) error
+}
+
+// NewPlayerRatings creates ratings of a player who has not played yet
+func NewPlayerRatings(playerID string) *PlayerRatings {
+	return &PlayerRatings{
+		PlayerID: playerID,
+		Ratings:  make(map[string]rating.Rating),
+		History:  make([]*RatingChange, 0),
+	}
+}
+
+// Rating returns rating in a category, a new player's rating if there is none
+func (p *PlayerRatings) Rating(category string) rating.Rating {
+	if r, ok := p.Ratings[category]; ok {
+		return r
+	}
+	return rating.New()
+}
+
+// clone returns a deep copy so stored ratings are not shared with callers
+func (p *PlayerRatings) clone() *PlayerRatings {
+	c := NewPlayerRatings(p.PlayerID)
+	for category, r := range p.Ratings {
+		c.Ratings[category] = r
+	}
+	c.History = append(c.History, p.History...)
+	return c
+}
+
+// rated returns true if ratings have already been updated after a game
+func (p *PlayerRatings) rated(gameID string) bool {
+	for _, change := range p.History {
+		if change.GameID == gameID {
+			return true
+		}
+	}
+	return false
+}
+
+// apply updates the rating after a game
+func (p *PlayerRatings) apply(gameID, category, opponentID string, opponent rating.Rating, score float64, now time.Time) {
+	before := p.Rating(category)
+	after := before.Update([]rating.Result{{Opponent: opponent, Score: score}})
+
+	p.Ratings[category] = after
+	p.History = append(p.History, &RatingChange{
+		GameID:     gameID,
+		Category:   category,
+		OpponentID: opponentID,
+		Score:      score,
+		Before:     before,
+		After:      after,
+		Time:       now,
+	})
+}
+
+// rate updates ratings of both players once a rated game is over, games
+// aborted before both players moved and games against oneself are not rated
+func (g *Game) rate() {
+	if !g.Rated || g.store == nil || len(g.Moves) < 2 || g.WhiteID == g.BlackID {
+		return
+	}
+
+	var score float64
+	switch g.Result {
+	case ResultWhiteWins:
+		score = rating.Win
+	case ResultBlackWins:
+		score = rating.Loss
+	case ResultDraw:
+		score = rating.Draw
+	default:
+		return
+	}
+
+	category, now := g.TimeControl.Category(), time.Now()
+	err := g.store.UpdateRatings(g.WhiteID, g.BlackID, func(white, black *PlayerRatings) {
+		if white.rated(g.ID) || black.rated(g.ID) {
+			return
+		}
+		// Both players are rated against the opponent's rating before the game
+		whiteRating, blackRating := white.Rating(category), black.Rating(category)
+		white.apply(g.ID, category, g.BlackID, blackRating, score, now)
+		black.apply(g.ID, category, g.WhiteID, whiteRating, 1-score, now)
+	})
+	if err != nil {
+		log.Printf("Error updating ratings after game %s: %v", g.ID, err)
+		return
+	}
+
+	log.Printf("Updated %s ratings of players of game %s", category, g.ID)
+}
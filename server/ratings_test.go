@@ -0,0 +1,82 @@
+package server
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/RichardKnop/chess-engine/rating"
+)
+
+func TestCategory(t *testing.T) {
+	categories := map[string]string{
+		"":                   "",
+		"60+0":               CategoryBullet,
+		"120+1":              CategoryBullet,
+		"180+2":              CategoryBlitz,
+		"300+3":              CategoryBlitz,
+		"600d5":              CategoryRapid,
+		"900+10":             CategoryRapid,
+		"40/5400+30:1800+30": CategoryClassical,
+	}
+	for s, expected := range categories {
+		tc, err := ParseTimeControl(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if category := tc.Category(); category != expected {
+			t.Errorf("%q is %q, want %q", s, category, expected)
+		}
+	}
+}
+
+func TestRateGame(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ratings")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fileStore, err := NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, store := range map[string]Store{"memory": NewMemoryStore(), "file": fileStore} {
+		tc, _ := ParseTimeControl("300+3")
+		g, err := NewGame("6ba7b814-9dad-11d1-80b4-00c04fd430c8", InitialPosition, tc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		g.store, g.Rated = store, true
+		g.WhiteID = "6ba7b815-9dad-11d1-80b4-00c04fd430c8"
+		g.BlackID = "6ba7b816-9dad-11d1-80b4-00c04fd430c8"
+		g.Moves = append(g.Moves, &Move{Source: "e2", Target: "e4"}, &Move{Source: "e7", Target: "e5"})
+		g.Result, g.Reason = ResultWhiteWins, ReasonResignation
+
+		// Finishing twice does not rate the game twice
+		g.finish()
+		g.finish()
+
+		white, err := store.LoadRatings(g.WhiteID)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		black, err := store.LoadRatings(g.BlackID)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		if len(white.History) != 1 || len(black.History) != 1 {
+			t.Fatalf("%s: rating history %d and %d changes, want 1", name, len(white.History), len(black.History))
+		}
+		if w, b := white.Rating(CategoryBlitz), black.Rating(CategoryBlitz); w.Rating <= rating.DefaultRating || b.Rating >= rating.DefaultRating {
+			t.Errorf("%s: winner rated %.0f, loser %.0f", name, w.Rating, b.Rating)
+		}
+		if white.Rating(CategoryBullet) != rating.New() {
+			t.Errorf("%s: bullet rating changed by a blitz game", name)
+		}
+		if change := black.History[0]; change.OpponentID != g.WhiteID || change.Score != rating.Loss || change.Before != rating.New() {
+			t.Errorf("%s: rating change %+v", name, change)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package server
+
+import (
+	"log"
+	"time"
+
+	"github.com/RichardKnop/chess-engine/chess"
+)
+
+// reconnectGracePeriod is how long the seat of a disconnected player is
+// reserved before the game is lost by abandonment
+var reconnectGracePeriod = time.Minute
+
+// disconnected reserves the seat of a player who lost connection and lets
+// the opponent know
+func (g *Game) disconnected(color chess.Color, playerID string) error {
+	if timer := g.abandonTimers[color]; timer != nil {
+		timer.Stop()
+	}
+	var timer *time.Timer
+	timer = time.AfterFunc(reconnectGracePeriod, func() {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+
+		// The player may have reconnected and dropped again meanwhile
+		if g.abandonTimers[color] == timer {
+			g.abandon(color)
+		}
+		// Both players may be gone, nobody leaves the game afterwards
+		if g.isIdle() && g.unload != nil {
+			go g.unload()
+		}
+	})
+	g.abandonTimers[color] = timer
+
+	log.Printf("Reserving %s seat of game %s for player %s for %s", color, g.ID, playerID, reconnectGracePeriod)
+
+	return g.notifyOffer("player_disconnected", playerID)
+}
+
+// reconnected cancels abandonment of a game when a player comes back to
+// the reserved seat
+func (g *Game) reconnected(c *Client) error {
+	color := chess.White
+	if g.Black == c {
+		color = chess.Black
+	}
+
+	timer := g.abandonTimers[color]
+	if timer == nil {
+		return nil
+	}
+	timer.Stop()
+	g.abandonTimers[color] = nil
+
+	log.Printf("Player %s reconnected to game %s", c.PlayerID, g.ID)
+
+	return g.notifyOffer("player_reconnected", c.PlayerID)
+}
+
+// awaitingReconnect returns true while a seat is reserved for a player
+func (g *Game) awaitingReconnect() bool {
+	return g.abandonTimers[chess.White] != nil || g.abandonTimers[chess.Black] != nil
+}
+
+// isIdle returns true when nobody is in the game and no seat is reserved, the
+// game can then be unloaded from memory
+func (g *Game) isIdle() bool {
+	return len(g.GetPlayers()) == 0 && len(g.Spectators) == 0 && !g.awaitingReconnect()
+}
+
+// abandon ends the game lost by a player who did not reconnect in time
+func (g *Game) abandon(color chess.Color) {
+	if g.IsOver() || g.abandonTimers[color] == nil {
+		return
+	}
+	g.abandonTimers[color] = nil
+
+	g.Result, g.Reason = ResultWhiteWins, ReasonAbandonment
+	if color == chess.White {
+		g.Result = ResultBlackWins
+	}
+	g.finish()
+
+	if err := g.NotifyGameOver(); err != nil {
+		log.Printf("Error notifying players of game %s: %v", g.ID, err)
+	}
+}
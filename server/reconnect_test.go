@@ -0,0 +1,158 @@
+package server
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// receive returns the next message sent to a client of a type
+func receive(t *testing.T, c *Client, msgType string) *Message {
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case data := <-c.send:
+			msg := new(Message)
+			if err := json.Unmarshal(data, msg); err != nil {
+				t.Fatal(err)
+			}
+			if msg.Type == msgType {
+				return msg
+			}
+		case <-timeout:
+			t.Fatalf("%s message not received", msgType)
+		}
+	}
+}
+
+func TestReconnect(t *testing.T) {
+	defer func(d time.Duration) { reconnectGracePeriod = d }(reconnectGracePeriod)
+	reconnectGracePeriod = 50 * time.Millisecond
+
+	g, err := NewGame("game", InitialPosition, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	alice := &Client{PlayerID: "alice", send: make(chan []byte, 16)}
+	bob := &Client{PlayerID: "bob", send: make(chan []byte, 16)}
+	if err := g.Join(alice, OrientationWhite); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Join(bob, OrientationBlack); err != nil {
+		t.Fatal(err)
+	}
+	g.Started = true
+
+	// Leaving arms a timer which locks the game like handlers do
+	g.mu.Lock()
+	err = g.Leave(alice)
+	g.mu.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg := receive(t, bob, "player_disconnected"); msg.Data.PlayerID != "alice" {
+		t.Errorf("disconnected player %s", msg.Data.PlayerID)
+	}
+
+	mallory := &Client{PlayerID: "mallory", send: make(chan []byte, 16)}
+	if err := g.Join(mallory, OrientationWhite); err != ErrSeatReserved {
+		t.Errorf("joining a reserved seat returned %v", err)
+	}
+
+	// A refreshed page connects as a new client with the same player ID
+	alice = &Client{PlayerID: "alice", send: make(chan []byte, 16)}
+	if err := g.Join(alice, OrientationWhite); err != nil {
+		t.Fatal(err)
+	}
+	receive(t, bob, "player_reconnected")
+	if g.awaitingReconnect() {
+		t.Error("seat still reserved after reconnecting")
+	}
+
+	g.mu.Lock()
+	err = g.Leave(bob)
+	g.mu.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := receive(t, alice, "game_over")
+	if msg.Data.Result != ResultWhiteWins || msg.Data.Reason != ReasonAbandonment {
+		t.Errorf("game over %s (%s), want white to win by abandonment", msg.Data.Result, msg.Data.Reason)
+	}
+}
+
+func TestUnloadFinishedGame(t *testing.T) {
+	defer func(d time.Duration) { reconnectGracePeriod = d }(reconnectGracePeriod)
+	reconnectGracePeriod = 50 * time.Millisecond
+
+	e := NewEngine(NewMemoryStore())
+	go e.hub.Run()
+
+	// startGame returns a game between two connected players
+	startGame := func() (*Game, *Client, *Client) {
+		g, err := e.newGame(InitialPosition, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer g.mu.Unlock()
+
+		alice := &Client{PlayerID: "alice", send: make(chan []byte, 16), engine: e}
+		bob := &Client{PlayerID: "bob", send: make(chan []byte, 16), engine: e}
+		if err := g.Join(alice, OrientationWhite); err != nil {
+			t.Fatal(err)
+		}
+		if err := g.Join(bob, OrientationBlack); err != nil {
+			t.Fatal(err)
+		}
+		g.Started = true
+		return g, alice, bob
+	}
+	loaded := func(g *Game) bool {
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		return e.games[g.ID] != nil
+	}
+	awaitUnloaded := func(g *Game, what string) {
+		deadline := time.Now().Add(time.Second)
+		for loaded(g) {
+			if time.Now().After(deadline) {
+				t.Fatalf("game ended by %s was not unloaded", what)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	// Resigning while the opponent's seat is reserved
+	g, alice, bob := startGame()
+	e.ClientDisconnected(alice)
+	g.mu.Lock()
+	err := g.Resign("bob")
+	g.mu.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.ClientDisconnected(bob)
+	awaitUnloaded(g, "resignation")
+
+	// Abandonment after the opponent has left too
+	g, alice, bob = startGame()
+	e.ClientDisconnected(alice)
+	e.ClientDisconnected(bob)
+	if !loaded(g) {
+		t.Fatal("game unloaded while seats are reserved")
+	}
+	awaitUnloaded(g, "abandonment")
+	if r, err := e.store.Load(g.ID); err != nil || r.Reason != ReasonAbandonment {
+		t.Errorf("stored game %+v, %v", r, err)
+	}
+
+	// Abandonment while the opponent is still connected
+	g, alice, bob = startGame()
+	e.ClientDisconnected(alice)
+	receive(t, bob, "game_over")
+	if !loaded(g) {
+		t.Fatal("game unloaded while a player is connected")
+	}
+	e.ClientDisconnected(bob)
+	awaitUnloaded(g, "abandonment")
+}
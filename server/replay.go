@@ -0,0 +1,88 @@
+package server
+
+import (
+	"github.com/RichardKnop/chess-engine/chess"
+	"github.com/RichardKnop/chess-engine/fen"
+	"github.com/RichardKnop/chess-engine/pgn"
+)
+
+// NewImportedGame creates a game from a parsed PGN game, the game can only be
+// replayed and starts at the initial position
+func NewImportedGame(gameID string, pg *pgn.Game) (*Game, error) {
+	position := InitialPosition
+	if pg.Position != nil {
+		position = fen.Encode(pg.Position)
+	}
+
+	g, err := NewGame(gameID, position, nil)
+	if err != nil {
+		return nil, err
+	}
+	g.Imported = true
+	g.Tags = pg.Tags
+
+	for _, m := range pg.Moves {
+		piece := g.Position.PieceAt(m.From)
+		p, err := g.Position.MakeMove(m)
+		if err != nil {
+			return nil, NewIllegalMoveError(m.From.String(), m.To.String())
+		}
+
+		move := &Move{
+			Source: m.From.String(),
+			Target: m.To.String(),
+			Piece:  piece.Code(),
+		}
+		if m.Promotion != chess.NoPieceType {
+			move.Promotion = string(m.Promotion.Char())
+		}
+
+		g.Position = p
+		g.History = append(g.History, p)
+		g.Moves = append(g.Moves, move)
+	}
+
+	g.Result, g.Reason = "", ""
+	if pg.Result != pgn.ResultUnknown {
+		g.Result = pg.Result
+	}
+
+	return g, nil
+}
+
+// Step moves through an imported game by a number of moves, negative numbers
+// go back. The replay stops at the first and the last position.
+func (g *Game) Step(plies int) error {
+	if !g.Imported {
+		return ErrNotImported
+	}
+
+	g.ReplayPly += plies
+	if g.ReplayPly < 0 {
+		g.ReplayPly = 0
+	}
+	if g.ReplayPly > len(g.Moves) {
+		g.ReplayPly = len(g.Moves)
+	}
+
+	return g.NotifyReplayPosition()
+}
+
+// NotifyReplayPosition notifies players about the position shown in a replay
+func (g *Game) NotifyReplayPosition() error {
+	msg := &Message{
+		Type: "replay_position",
+		Data: &MessageData{
+			GameID:   g.ID,
+			Position: fen.Encode(g.History[g.ReplayPly]),
+			Ply:      g.ReplayPly,
+		},
+	}
+	if g.ReplayPly > 0 {
+		// The last move played can be highlighted
+		move := g.Moves[g.ReplayPly-1]
+		msg.Data.Source, msg.Data.Target = move.Source, move.Target
+		msg.Data.Piece, msg.Data.Promotion = move.Piece, move.Promotion
+	}
+	return g.notifyPlayers(msg)
+}
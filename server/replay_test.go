@@ -0,0 +1,67 @@
+package server
+
+import (
+	"io/ioutil"
+	"testing"
+)
+
+func TestImportGame(t *testing.T) {
+	data, err := ioutil.ReadFile("../pgn/testdata/classics.pgn")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := NewMemoryStore()
+	e := NewEngine(store)
+	g, err := e.ImportGame(string(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the first game is imported, the Opera game
+	if len(g.Moves) != 33 || g.Result != ResultWhiteWins || g.Tags["White"] != "Paul Morphy" {
+		t.Fatalf("imported game %+v", g)
+	}
+	if err := g.MakeMove("alice", "e2", "e4", ""); err != ErrImportedGame {
+		t.Errorf("move in imported game returned %v", err)
+	}
+
+	steps := []struct {
+		plies    int
+		expected int
+	}{
+		{1, 1},
+		{1, 2},
+		{-1, 1},
+		{-5, 0},
+		{100, 33},
+	}
+	for _, s := range steps {
+		if err := g.Step(s.plies); err != nil {
+			t.Fatal(err)
+		}
+		if g.ReplayPly != s.expected {
+			t.Errorf("step %d shows ply %d, want %d", s.plies, g.ReplayPly, s.expected)
+		}
+	}
+
+	// Imported games survive a restart
+	r, err := store.Load(g.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored, err := RestoreGame(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !restored.Imported || restored.FEN() != g.FEN() || restored.Tags["ECO"] != "C41" {
+		t.Errorf("restored game %+v differs from %+v", restored, g)
+	}
+
+	if _, err := e.ImportGame("[Event \"?\"]\n\n1. e4 e5 2. Ke3 *"); err == nil {
+		t.Error("import of an illegal move did not fail")
+	}
+	if _, err := e.ImportGame(""); err != ErrNoGameInPGN {
+		t.Errorf("import of empty PGN returned %v", err)
+	}
+}
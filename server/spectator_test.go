@@ -0,0 +1,70 @@
+package server
+
+import (
+	"testing"
+)
+
+func TestSpectators(t *testing.T) {
+	e := NewEngine(NewMemoryStore())
+	g, err := e.newGame(InitialPosition, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	alice := &Client{PlayerID: "alice", send: make(chan []byte, 16), engine: e}
+	bob := &Client{PlayerID: "bob", send: make(chan []byte, 16), engine: e}
+	eve := &Client{PlayerID: "eve", send: make(chan []byte, 16), engine: e}
+	if err := g.Join(alice, OrientationWhite); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Join(bob, OrientationBlack); err != nil {
+		t.Fatal(err)
+	}
+	g.mu.Unlock()
+
+	watch := &Message{Type: "watch_game", Data: &MessageData{GameID: g.ID}}
+	if err := eve.handleMessage(watch); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []*Client{alice, bob, eve} {
+		if msg := receive(t, c, "state_update"); msg.Data.Spectators != 1 {
+			t.Errorf("state update counts %d spectators, want 1", msg.Data.Spectators)
+		}
+	}
+	if err := alice.handleMessage(&Message{Type: "watch_game", Data: &MessageData{GameID: g.ID}}); err != ErrAlreadyPlaying {
+		t.Errorf("player watching own game returned %v", err)
+	}
+
+	// Claiming the ID of the player on the move does not help
+	move := &Message{Type: "make_move", Data: &MessageData{GameID: g.ID, PlayerID: "alice", Source: "e2", Target: "e4"}}
+	if err := eve.handleMessage(move); err != ErrSpectator {
+		t.Errorf("spectator move returned %v", err)
+	}
+	if eve.PlayerID != "eve" {
+		t.Errorf("player ID changed to %s by a message", eve.PlayerID)
+	}
+	for _, msgType := range []string{"resign", "offer_draw", "request_takeback"} {
+		msg := &Message{Type: msgType, Data: &MessageData{GameID: g.ID}}
+		if err := eve.handleMessage(msg); err != ErrSpectator {
+			t.Errorf("spectator %s returned %v", msgType, err)
+		}
+	}
+	if len(g.Moves) != 0 || g.IsOver() {
+		t.Fatalf("spectator changed the game: %+v", g)
+	}
+
+	move.Data.PlayerID = ""
+	if err := alice.handleMessage(move); err != nil {
+		t.Fatal(err)
+	}
+	if msg := receive(t, eve, "move_made"); msg.Data.Target != "e4" {
+		t.Errorf("spectator received move %s-%s", msg.Data.Source, msg.Data.Target)
+	}
+
+	if err := g.Leave(eve); err != nil {
+		t.Fatal(err)
+	}
+	if msg := receive(t, bob, "state_update"); msg.Data.Spectators != 0 {
+		t.Errorf("state update counts %d spectators after leaving", msg.Data.Spectators)
+	}
+}
@@ -0,0 +1,456 @@
+package server
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/satori/go.uuid"
+)
+
+// Subdirectories of FileStore with accounts and ratings
+const (
+	accountsDir = "accounts"
+	ratingsDir  = "ratings"
+)
+
+// GameStore persists games so they survive disconnects and server restarts
+type GameStore interface {
+	// Save inserts or replaces a game
+	Save(r *GameRecord) error
+	// Load returns GameNotFoundError if the game does not exist
+	Load(gameID string) (*GameRecord, error)
+}
+
+// Store persists games, accounts and ratings
+type Store interface {
+	GameStore
+	AccountStore
+	RatingStore
+}
+
+// GameRecord is everything needed to restore a game, the position is
+// derived by replaying moves from the initial position
+type GameRecord struct {
+	ID              string  `json:"id"`
+	InitialPosition string  `json:"initial_position"`
+	TimeControl     string  `json:"time_control,omitempty"`
+	WhiteID         string  `json:"white_id,omitempty"`
+	BlackID         string  `json:"black_id,omitempty"`
+	WhiteName       string  `json:"white_name,omitempty"`
+	BlackName       string  `json:"black_name,omitempty"`
+	Rated           bool    `json:"rated,omitempty"`
+	Private         bool    `json:"private,omitempty"`
+	InviteCode      string  `json:"invite_code,omitempty"`
+	InvitedID       string  `json:"invited_id,omitempty"`
+	ComputerColor   string  `json:"computer_color,omitempty"`
+	ComputerLevel   int     `json:"computer_level,omitempty"`
+	Moves           []*Move `json:"moves"`
+	Result          string  `json:"result,omitempty"`
+	Reason          string  `json:"reason,omitempty"`
+	// Imported games keep their PGN tags
+	Imported bool              `json:"imported,omitempty"`
+	Tags     map[string]string `json:"tags,omitempty"`
+	// Remaining clock times in milliseconds
+	WhiteTime int64     `json:"white_time,omitempty"`
+	BlackTime int64     `json:"black_time,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// MemoryStore keeps games and accounts in memory, they are lost when the
+// server stops
+type MemoryStore struct {
+	mu       sync.RWMutex
+	records  map[string]GameRecord
+	accounts map[string]Account
+	// Account IDs by username
+	usernames map[string]string
+	ratings   map[string]*PlayerRatings
+}
+
+// NewMemoryStore creates a new instance of MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		records:   make(map[string]GameRecord),
+		accounts:  make(map[string]Account),
+		usernames: make(map[string]string),
+		ratings:   make(map[string]*PlayerRatings),
+	}
+}
+
+// Save inserts or replaces a game
+func (s *MemoryStore) Save(r *GameRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Copy moves so later changes by the caller are not visible
+	record := *r
+	record.Moves = append([]*Move(nil), r.Moves...)
+	s.records[r.ID] = record
+
+	return nil
+}
+
+// Load returns a game by its ID
+func (s *MemoryStore) Load(gameID string) (*GameRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	record, ok := s.records[gameID]
+	if !ok {
+		return nil, NewGameNotFoundError(gameID)
+	}
+	record.Moves = append([]*Move(nil), record.Moves...)
+
+	return &record, nil
+}
+
+// CreateAccount inserts a new account
+func (s *MemoryStore) CreateAccount(a *Account) error {
+	return s.saveAccount(a, true)
+}
+
+// SaveAccount inserts or replaces an account
+func (s *MemoryStore) SaveAccount(a *Account) error {
+	return s.saveAccount(a, false)
+}
+
+func (s *MemoryStore) saveAccount(a *Account, insert bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.accounts[a.ID]; ok && insert {
+		return ErrAccountExists
+	}
+	if id, ok := s.usernames[a.Username]; ok && id != a.ID {
+		return ErrUsernameTaken
+	}
+	s.accounts[a.ID] = *a
+	s.usernames[a.Username] = a.ID
+
+	return nil
+}
+
+// LoadAccount returns an account by its ID
+func (s *MemoryStore) LoadAccount(id string) (*Account, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	a, ok := s.accounts[id]
+	if !ok {
+		return nil, NewAccountNotFoundError(id)
+	}
+	return &a, nil
+}
+
+// FindAccount returns an account by its username
+func (s *MemoryStore) FindAccount(username string) (*Account, error) {
+	s.mu.RLock()
+	id, ok := s.usernames[username]
+	s.mu.RUnlock()
+
+	if !ok {
+		return nil, NewAccountNotFoundError(username)
+	}
+	return s.LoadAccount(id)
+}
+
+// LoadRatings returns ratings of a player
+func (s *MemoryStore) LoadRatings(playerID string) (*PlayerRatings, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if p, ok := s.ratings[playerID]; ok {
+		return p.clone(), nil
+	}
+	return NewPlayerRatings(playerID), nil
+}
+
+// UpdateRatings changes ratings of two players
+func (s *MemoryStore) UpdateRatings(whiteID, blackID string, update func(white, black *PlayerRatings)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	white, black := NewPlayerRatings(whiteID), NewPlayerRatings(blackID)
+	if p, ok := s.ratings[whiteID]; ok {
+		white = p.clone()
+	}
+	if p, ok := s.ratings[blackID]; ok {
+		black = p.clone()
+	}
+
+	update(white, black)
+	s.ratings[whiteID], s.ratings[blackID] = white, black
+
+	return nil
+}
+
+// FileStore keeps every game in a JSON file inside a directory, accounts and
+// ratings are kept the same way in subdirectories
+type FileStore struct {
+	dir string
+	// Serializes writes of the same file
+	mu sync.Mutex
+	// Account IDs by username, read from the files when first needed
+	usernames map[string]string
+}
+
+// NewFileStore creates a new instance of FileStore, the directory is created
+// if it does not exist
+func NewFileStore(dir string) (*FileStore, error) {
+	for _, subdir := range []string{accountsDir, ratingsDir} {
+		if err := os.MkdirAll(filepath.Join(dir, subdir), 0755); err != nil {
+			return nil, err
+		}
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+// Save inserts or replaces a game, the file is replaced atomically so a crash
+// never leaves a half written game behind
+func (s *FileStore) Save(r *GameRecord) error {
+	path, err := s.path(r.ID)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.write(path, r)
+}
+
+// write replaces a file with JSON of a value atomically, so a crash never
+// leaves a half written file behind
+func (s *FileStore) write(path string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// CreateAccount inserts a new account
+func (s *FileStore) CreateAccount(a *Account) error {
+	return s.saveAccount(a, true)
+}
+
+// SaveAccount inserts or replaces an account
+func (s *FileStore) SaveAccount(a *Account) error {
+	return s.saveAccount(a, false)
+}
+
+func (s *FileStore) saveAccount(a *Account, insert bool) error {
+	if _, err := uuid.FromString(a.ID); err != nil {
+		return NewAccountNotFoundError(a.ID)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if insert {
+		if _, err := os.Stat(s.accountPath(a.ID)); err == nil {
+			return ErrAccountExists
+		} else if !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if err := s.loadUsernames(); err != nil {
+		return err
+	}
+	if id, ok := s.usernames[a.Username]; ok && id != a.ID {
+		return ErrUsernameTaken
+	}
+
+	if err := s.write(s.accountPath(a.ID), a); err != nil {
+		return err
+	}
+	s.usernames[a.Username] = a.ID
+
+	return nil
+}
+
+// LoadAccount returns an account by its ID
+func (s *FileStore) LoadAccount(id string) (*Account, error) {
+	if _, err := uuid.FromString(id); err != nil {
+		return nil, NewAccountNotFoundError(id)
+	}
+
+	data, err := ioutil.ReadFile(s.accountPath(id))
+	if os.IsNotExist(err) {
+		return nil, NewAccountNotFoundError(id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	a := new(Account)
+	if err := json.Unmarshal(data, a); err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// FindAccount returns an account by its username
+func (s *FileStore) FindAccount(username string) (*Account, error) {
+	s.mu.Lock()
+	err := s.loadUsernames()
+	id, ok := s.usernames[username]
+	s.mu.Unlock()
+
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, NewAccountNotFoundError(username)
+	}
+	return s.LoadAccount(id)
+}
+
+// loadUsernames reads all accounts once to index them by username, the
+// mutex must be held
+func (s *FileStore) loadUsernames() error {
+	if s.usernames != nil {
+		return nil
+	}
+
+	paths, err := filepath.Glob(filepath.Join(s.dir, accountsDir, "*.json"))
+	if err != nil {
+		return err
+	}
+
+	usernames := make(map[string]string, len(paths))
+	for _, path := range paths {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		a := new(Account)
+		if err := json.Unmarshal(data, a); err != nil {
+			return err
+		}
+		usernames[a.Username] = a.ID
+	}
+	s.usernames = usernames
+
+	return nil
+}
+
+// LoadRatings returns ratings of a player
+func (s *FileStore) LoadRatings(playerID string) (*PlayerRatings, error) {
+	path, err := s.ratingsPath(playerID)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return NewPlayerRatings(playerID), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	p := NewPlayerRatings(playerID)
+	if err := json.Unmarshal(data, p); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// UpdateRatings changes ratings of two players, the mutex keeps concurrent
+// updates from overwriting each other
+func (s *FileStore) UpdateRatings(whiteID, blackID string, update func(white, black *PlayerRatings)) error {
+	whitePath, err := s.ratingsPath(whiteID)
+	if err != nil {
+		return err
+	}
+	blackPath, err := s.ratingsPath(blackID)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	white, err := s.LoadRatings(whiteID)
+	if err != nil {
+		return err
+	}
+	black, err := s.LoadRatings(blackID)
+	if err != nil {
+		return err
+	}
+
+	update(white, black)
+
+	if err := s.write(whitePath, white); err != nil {
+		return err
+	}
+	return s.write(blackPath, black)
+}
+
+// ratingsPath returns the ratings file of a player, player IDs are UUIDs
+func (s *FileStore) ratingsPath(playerID string) (string, error) {
+	if _, err := uuid.FromString(playerID); err != nil {
+		return "", NewPlayerNotFoundError(playerID)
+	}
+	return filepath.Join(s.dir, ratingsDir, playerID+".json"), nil
+}
+
+func (s *FileStore) accountPath(id string) string {
+	return filepath.Join(s.dir, accountsDir, id+".json")
+}
+
+// Load returns a game by its ID
+func (s *FileStore) Load(gameID string) (*GameRecord, error) {
+	path, err := s.path(gameID)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, NewGameNotFoundError(gameID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	record := new(GameRecord)
+	if err := json.Unmarshal(data, record); err != nil {
+		return nil, err
+	}
+
+	return record, nil
+}
+
+// path returns the file of a game, only UUIDs are accepted as game IDs so
+// they cannot point outside of the directory
+func (s *FileStore) path(gameID string) (string, error) {
+	if _, err := uuid.FromString(gameID); err != nil {
+		return "", NewGameNotFoundError(gameID)
+	}
+	return filepath.Join(s.dir, gameID+".json"), nil
+}
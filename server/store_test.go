@@ -0,0 +1,114 @@
+package server
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RichardKnop/chess-engine/chess"
+)
+
+func testRecord() *GameRecord {
+	return &GameRecord{
+		ID:              "6ba7b810-9dad-11d1-80b4-00c04fd430c8",
+		InitialPosition: InitialPosition,
+		TimeControl:     "300+3",
+		WhiteID:         "alice",
+		BlackID:         "bob",
+		Moves: []*Move{
+			{PlayerID: "alice", Source: "f2", Target: "f3", Piece: "wP", Clock: 300000},
+			{PlayerID: "bob", Source: "e7", Target: "e5", Piece: "bP", Clock: 299000},
+			{PlayerID: "alice", Source: "g2", Target: "g4", Piece: "wP", Clock: 290000},
+			{PlayerID: "bob", Source: "d8", Target: "h4", Piece: "bQ", Clock: 301000},
+		},
+		Result:    ResultBlackWins,
+		Reason:    ReasonCheckmate,
+		WhiteTime: 290000,
+		BlackTime: 301000,
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+	}
+}
+
+func TestGameStores(t *testing.T) {
+	dir, err := ioutil.TempDir("", "games")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fileStore, err := NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stores := map[string]GameStore{
+		"memory": NewMemoryStore(),
+		"file":   fileStore,
+	}
+	for name, store := range stores {
+		r := testRecord()
+		if err := store.Save(r); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		loaded, err := store.Load(r.ID)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if len(loaded.Moves) != 4 || *loaded.Moves[3] != *r.Moves[3] || loaded.Result != r.Result || !loaded.CreatedAt.Equal(r.CreatedAt) {
+			t.Errorf("%s: loaded %+v, saved %+v", name, loaded, r)
+		}
+
+		if _, err := store.Load("6ba7b811-9dad-11d1-80b4-00c04fd430c8"); err == nil {
+			t.Errorf("%s: loading a missing game did not fail", name)
+		}
+	}
+
+	if _, err := fileStore.Load("../../etc/passwd"); err == nil {
+		t.Error("file store accepted a path as game ID")
+	}
+}
+
+func TestRestoreGame(t *testing.T) {
+	r := testRecord()
+	g, err := RestoreGame(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "rnb1kbnr/pppp1ppp/8/4p3/6Pq/5P2/PPPPP2P/RNBQKBNR w KQkq - 1 3"
+	if g.FEN() != expected {
+		t.Errorf("restored position %s, want %s", g.FEN(), expected)
+	}
+	if !g.IsOver() || g.WhiteID != "alice" || g.BlackID != "bob" {
+		t.Errorf("restored game %+v", g)
+	}
+	if got := g.Clock.Remaining(chess.White, time.Now()); got != 290*time.Second {
+		t.Errorf("restored white clock %s", got)
+	}
+
+	record := g.Record()
+	record.UpdatedAt = time.Time{}
+	if record.InitialPosition != r.InitialPosition || record.TimeControl != r.TimeControl || len(record.Moves) != len(r.Moves) || record.WhiteTime != r.WhiteTime {
+		t.Errorf("record of restored game %+v differs from %+v", record, r)
+	}
+}
+
+func TestGamePGN(t *testing.T) {
+	g, err := RestoreGame(testRecord())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := g.PGN()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{`[White "alice"]`, `[Result "0-1"]`, `[TimeControl "300+3"]`, "2. g4 {[%clk 0:04:50]}", "Qh4# {[%clk 0:05:01]} 0-1\n"} {
+		if !strings.Contains(data, expected) {
+			t.Errorf("%q not found in\n%s", expected, data)
+		}
+	}
+}
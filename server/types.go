@@ -1,14 +1,51 @@
 package server
 
+import (
+	"github.com/RichardKnop/chess-engine/fen"
+)
+
 const (
 	// OrientationBlack means black is on the play facing white
 	OrientationBlack = "black"
 	// OrientationWhite means white is on the play facing black
 	OrientationWhite = "white"
 
-	// InitialPosition is a FEM representation of initial board state
+	// InitialPosition is a FEN representation of initial board state
 	// See https://en.wikipedia.org/wiki/Forsyth%E2%80%93Edwards_Notation
-	InitialPosition = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR"
+	InitialPosition = fen.Initial
+
+	// pgnSite is the Site tag of exported games
+	pgnSite = "chess-engine"
+
+	// ResultWhiteWins is a result of a game won by white
+	ResultWhiteWins = "1-0"
+	// ResultBlackWins is a result of a game won by black
+	ResultBlackWins = "0-1"
+	// ResultDraw is a result of a drawn game
+	ResultDraw = "1/2-1/2"
+
+	// ReasonCheckmate means the side to move was checkmated
+	ReasonCheckmate = "checkmate"
+	// ReasonStalemate means the side to move had no legal moves
+	ReasonStalemate = "stalemate"
+	// ReasonThreefoldRepetition means the same position occurred three times
+	ReasonThreefoldRepetition = "threefold_repetition"
+	// ReasonFiftyMoveRule means fifty moves were played by each side without
+	// a capture or a pawn move
+	ReasonFiftyMoveRule = "fifty_move_rule"
+	// ReasonInsufficientMaterial means neither side can checkmate
+	ReasonInsufficientMaterial = "insufficient_material"
+	// ReasonResignation means one of the players resigned
+	ReasonResignation = "resignation"
+	// ReasonAgreement means players agreed to a draw
+	ReasonAgreement = "agreement"
+	// ReasonAbandonment means a player disconnected and did not come back
+	ReasonAbandonment = "abandonment"
+	// ReasonTimeout means the side to move ran out of time
+	ReasonTimeout = "timeout"
+	// ReasonTimeoutVsInsufficientMaterial means the side to move ran out of
+	// time but the opponent could not checkmate
+	ReasonTimeoutVsInsufficientMaterial = "timeout_vs_insufficient_material"
 )
 
 // Message is a generic message send via websockets
@@ -19,13 +56,31 @@ type Message struct {
 
 // MessageData ...
 type MessageData struct {
-	Position    string `json:"position"`
-	GameID      string `json:"game_id"`
-	Orientation string `json:"orientation,omitempty"`
-	PlayerID    string `json:"player_id,omitempty"`
-	Source      string `json:"source,omitempty"`
-	Target      string `json:"target,omitempty"`
-	Piece       string `json:"piece,omitempty"`
-	OldPosition string `json:"old_position,omitempty"`
-	NewPosition string `json:"new_position,omitempty"`
+	Position    string         `json:"position"`
+	GameID      string         `json:"game_id"`
+	Orientation string         `json:"orientation,omitempty"`
+	PlayerID    string         `json:"player_id,omitempty"`
+	Source      string         `json:"source,omitempty"`
+	Target      string         `json:"target,omitempty"`
+	Piece       string         `json:"piece,omitempty"`
+	Promotion   string         `json:"promotion,omitempty"`
+	Level       int            `json:"level,omitempty"`
+	TimeControl string         `json:"time_control,omitempty"`
+	Clock       *ClockState    `json:"clock,omitempty"`
+	Result      string         `json:"result,omitempty"`
+	Reason      string         `json:"reason,omitempty"`
+	PGN         string         `json:"pgn,omitempty"`
+	Ply         int            `json:"ply,omitempty"`
+	Spectators  int            `json:"spectators,omitempty"`
+	Rated       bool           `json:"rated,omitempty"`
+	RatingRange int            `json:"rating_range,omitempty"`
+	Channel     string         `json:"channel,omitempty"`
+	Text        string         `json:"text,omitempty"`
+	Chat        []*ChatMessage `json:"chat,omitempty"`
+	SeekID      string         `json:"seek_id,omitempty"`
+	Seeks       []*Seek        `json:"seeks,omitempty"`
+	ChallengeID string         `json:"challenge_id,omitempty"`
+	Challenge   *Challenge     `json:"challenge,omitempty"`
+	InviteCode  string         `json:"invite_code,omitempty"`
+	Error       string         `json:"error,omitempty"`
 }
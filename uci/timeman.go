@@ -0,0 +1,90 @@
+package uci
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/RichardKnop/chess-engine/chess"
+	"github.com/RichardKnop/chess-engine/search"
+)
+
+// defaultMoveTime limits a search when go sets no limit at all, otherwise it
+// would only end at the maximum depth
+const defaultMoveTime = 5 * time.Second
+
+// goParams are arguments of the go command
+type goParams struct {
+	// Clock is set when wtime or btime is given
+	clock     bool
+	time      [2]time.Duration
+	inc       [2]time.Duration
+	movesToGo int
+	moveTime  time.Duration
+	depth     int
+	nodes     uint64
+	infinite  bool
+	ponder    bool
+}
+
+// parseGoParams parses arguments of the go command, unknown ones are ignored
+func parseGoParams(args []string) goParams {
+	var params goParams
+	for i := 0; i < len(args); i++ {
+		// Most arguments are followed by a number
+		var value int64
+		if i+1 < len(args) {
+			value, _ = strconv.ParseInt(args[i+1], 10, 64)
+		}
+		ms := time.Duration(value) * time.Millisecond
+
+		switch args[i] {
+		case "wtime":
+			params.time[chess.White], params.clock = ms, true
+		case "btime":
+			params.time[chess.Black], params.clock = ms, true
+		case "winc":
+			params.inc[chess.White] = ms
+		case "binc":
+			params.inc[chess.Black] = ms
+		case "movestogo":
+			params.movesToGo = int(value)
+		case "movetime":
+			params.moveTime = ms
+		case "depth":
+			params.depth = int(value)
+		case "nodes":
+			params.nodes = uint64(value)
+		case "infinite":
+			params.infinite = true
+			continue
+		case "ponder":
+			params.ponder = true
+			continue
+		default:
+			continue
+		}
+		i++
+	}
+	return params
+}
+
+// limits converts go arguments into search limits for the side to move
+func (p goParams) limits(turn chess.Color) search.Limits {
+	limits := search.Limits{
+		Depth:    p.depth,
+		Nodes:    p.nodes,
+		MoveTime: p.moveTime,
+		Infinite: p.infinite,
+	}
+
+	switch {
+	case p.moveTime > 0 || p.infinite:
+	case p.clock:
+		// No time left still gets the minimal budget
+		limits.MoveTime = search.AllocateTime(p.time[turn], p.inc[turn], p.movesToGo)
+	case p.depth == 0 && p.nodes == 0:
+		limits.MoveTime = defaultMoveTime
+	}
+
+	return limits
+}
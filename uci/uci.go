@@ -0,0 +1,307 @@
+// Package uci implements the Universal Chess Interface so the engine can be
+// used from chess GUIs and tournament managers such as cutechess-cli
+// See http://wbec-ridderkerk.nl/html/UCIProtocol.html
+package uci
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/RichardKnop/chess-engine/chess"
+	"github.com/RichardKnop/chess-engine/fen"
+	"github.com/RichardKnop/chess-engine/search"
+)
+
+const (
+	// EngineName is reported in response to the uci command
+	EngineName = "chess-engine"
+	// EngineAuthor is reported in response to the uci command
+	EngineAuthor = "Richard Knop"
+
+	maxHashSize = 1024
+)
+
+// Engine reads UCI commands and writes responses
+type Engine struct {
+	in  io.Reader
+	out io.Writer
+	// Guards out, responses are written by both command and search goroutines
+	mu sync.Mutex
+
+	searcher *search.Searcher
+	position *chess.Position
+	ponder   bool
+
+	// Closed when the running search has printed its best move
+	searchDone chan struct{}
+	// Closing it stops the running search and no other one
+	searchStop chan struct{}
+	// Pondering search waiting for ponderhit or stop
+	pondering *ponderState
+}
+
+// ponderState is limits to apply once the opponent plays the expected move
+type ponderState struct {
+	sync.Mutex
+	hit    bool
+	limits search.Limits
+	// Closing it ends pondering
+	stop chan struct{}
+}
+
+// New creates a new instance of Engine
+func New(in io.Reader, out io.Writer) *Engine {
+	return &Engine{
+		in:       in,
+		out:      out,
+		searcher: search.New(),
+		position: chess.NewPosition(),
+	}
+}
+
+// Run processes commands until quit is received or the input is closed
+func (e *Engine) Run() error {
+	scanner := bufio.NewScanner(e.in)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch fields[0] {
+		case "uci":
+			e.uci()
+		case "isready":
+			e.send("readyok")
+		case "setoption":
+			e.setOption(fields[1:])
+		case "ucinewgame":
+			e.stopSearch()
+			e.searcher.ClearHash()
+			e.position = chess.NewPosition()
+		case "position":
+			e.stopSearch()
+			if err := e.setPosition(fields[1:]); err != nil {
+				e.send("info string %v", err)
+			}
+		case "go":
+			e.stopSearch()
+			e.goSearch(fields[1:])
+		case "stop":
+			e.stopSearch()
+		case "ponderhit":
+			e.ponderHit()
+		case "quit":
+			e.stopSearch()
+			return nil
+		default:
+			e.send("info string Unknown command: %s", fields[0])
+		}
+	}
+
+	e.stopSearch()
+	return scanner.Err()
+}
+
+// send writes a single response line
+func (e *Engine) send(format string, args ...interface{}) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	fmt.Fprintf(e.out, format+"\n", args...)
+}
+
+func (e *Engine) uci() {
+	e.send("id name %s", EngineName)
+	e.send("id author %s", EngineAuthor)
+	e.send("option name Hash type spin default %d min 1 max %d", search.DefaultHashSize, maxHashSize)
+	e.send("option name Clear Hash type button")
+	e.send("option name Ponder type check default false")
+	e.send("uciok")
+}
+
+// setOption handles "setoption name <id> [value <x>]"
+func (e *Engine) setOption(args []string) {
+	var name, value []string
+	var current *[]string
+	for _, arg := range args {
+		switch arg {
+		case "name":
+			current = &name
+		case "value":
+			current = &value
+		default:
+			if current != nil {
+				*current = append(*current, arg)
+			}
+		}
+	}
+
+	switch strings.ToLower(strings.Join(name, " ")) {
+	case "hash":
+		size, err := strconv.Atoi(strings.Join(value, ""))
+		if err != nil || size < 1 || size > maxHashSize {
+			e.send("info string Invalid hash size: %s", strings.Join(value, " "))
+			return
+		}
+		e.stopSearch()
+		e.searcher.SetHashSize(size)
+	case "clear hash":
+		e.stopSearch()
+		e.searcher.ClearHash()
+	case "ponder":
+		e.ponder = strings.Join(value, "") == "true"
+	default:
+		e.send("info string Unknown option: %s", strings.Join(name, " "))
+	}
+}
+
+// setPosition handles "position [startpos | fen <fen>] [moves <move>...]"
+func (e *Engine) setPosition(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("Missing position")
+	}
+
+	var p *chess.Position
+	var rest []string
+	switch args[0] {
+	case "startpos":
+		p, rest = chess.NewPosition(), args[1:]
+	case "fen":
+		end := len(args)
+		for i, arg := range args {
+			if arg == "moves" {
+				end = i
+				break
+			}
+		}
+		var err error
+		if p, err = fen.Parse(strings.Join(args[1:end], " ")); err != nil {
+			return err
+		}
+		rest = args[end:]
+	default:
+		return fmt.Errorf("Invalid position: %s", args[0])
+	}
+
+	if len(rest) > 0 && rest[0] == "moves" {
+		for _, s := range rest[1:] {
+			m, err := chess.ParseMove(s)
+			if err != nil {
+				return err
+			}
+			if p, err = p.MakeMove(m); err != nil {
+				return fmt.Errorf("Illegal move: %s", s)
+			}
+		}
+	}
+
+	e.position = p
+	return nil
+}
+
+// goSearch handles the go command and starts searching in the background
+func (e *Engine) goSearch(args []string) {
+	params := parseGoParams(args)
+	limits := params.limits(e.position.Turn())
+
+	done, stop := make(chan struct{}), make(chan struct{})
+	e.searchDone, e.searchStop = done, stop
+	limits.Stop = stop
+
+	var ponder *ponderState
+	if params.ponder {
+		// Search without limits until the opponent makes the expected move
+		ponder = &ponderState{limits: limits, stop: make(chan struct{})}
+		limits = search.Limits{Infinite: true, Stop: ponder.stop}
+		e.pondering = ponder
+	}
+
+	position, reportPonder := e.position, e.ponder
+	e.searcher.OnIteration = e.info
+
+	go func() {
+		defer close(done)
+
+		result := e.searcher.Search(position, limits)
+
+		if ponder != nil {
+			ponder.Lock()
+			hit := ponder.hit
+			ponder.Unlock()
+			if hit {
+				// The remaining time limits apply to a fresh search which
+				// reuses the transposition table filled while pondering
+				result = e.searcher.Search(position, ponder.limits)
+			}
+		}
+
+		if result.BestMove != chess.NoMove && len(result.PV) > 1 && reportPonder {
+			e.send("bestmove %s ponder %s", result.BestMove, result.PV[1])
+		} else {
+			e.send("bestmove %s", result.BestMove)
+		}
+	}()
+}
+
+// ponderHit switches a pondering search into a normal one
+func (e *Engine) ponderHit() {
+	ponder := e.pondering
+	if ponder == nil {
+		return
+	}
+	e.pondering = nil
+
+	ponder.Lock()
+	ponder.hit = true
+	ponder.Unlock()
+	close(ponder.stop)
+}
+
+// stopSearch stops a running search and waits for its best move to be sent
+func (e *Engine) stopSearch() {
+	if e.searchDone == nil {
+		return
+	}
+	if ponder := e.pondering; ponder != nil {
+		// Stopping while pondering means the opponent played another move
+		ponder.Lock()
+		ponder.hit = false
+		ponder.Unlock()
+		close(ponder.stop)
+		e.pondering = nil
+	}
+
+	// The stop belongs to this search only, closing it after the search
+	// has ended does not affect the next one
+	close(e.searchStop)
+	<-e.searchDone
+	e.searchDone, e.searchStop = nil, nil
+}
+
+// info reports a completed iteration
+func (e *Engine) info(r search.Result) {
+	score := fmt.Sprintf("cp %d", r.Score)
+	if r.IsMate() {
+		score = fmt.Sprintf("mate %d", r.MateIn())
+	}
+
+	pv := make([]string, len(r.PV))
+	for i, m := range r.PV {
+		pv[i] = m.String()
+	}
+
+	e.send(
+		"info depth %d score %s nodes %d nps %d time %d hashfull %d pv %s",
+		r.Depth,
+		score,
+		r.Nodes,
+		r.NPS(),
+		r.Time.Nanoseconds()/1e6,
+		e.searcher.Hashfull(),
+		strings.Join(pv, " "),
+	)
+}
@@ -0,0 +1,75 @@
+package uci
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRun(t *testing.T) {
+	in := strings.NewReader(strings.Join([]string{
+		"uci",
+		"setoption name Hash value 1",
+		"isready",
+		"ucinewgame",
+		"position startpos moves e2e4 e7e5 g1f3",
+		"go depth 4",
+		"quit",
+	}, "\n"))
+	out := new(bytes.Buffer)
+
+	if err := New(in, out).Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, expected := range []string{"id name chess-engine", "uciok", "readyok", "info depth 1 ", "bestmove "} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("Output does not contain %q:\n%s", expected, out.String())
+		}
+	}
+}
+
+func TestSetPosition(t *testing.T) {
+	e := New(nil, new(bytes.Buffer))
+
+	if err := e.setPosition(strings.Fields("fen 6k1/5ppp/8/8/8/8/5PPP/3R2K1 w - - 0 1 moves d1d8")); err != nil {
+		t.Fatal(err)
+	}
+	if !e.position.InCheck() {
+		t.Error("Expected black to be in check after d1d8")
+	}
+
+	if err := e.setPosition(strings.Fields("startpos moves e2e5")); err == nil {
+		t.Error("Expected an illegal move to be rejected")
+	}
+}
+
+func TestAllocateTime(t *testing.T) {
+	params := parseGoParams(strings.Fields("wtime 60000 btime 1000 winc 1000 binc 0"))
+
+	white := params.limits(0).MoveTime
+	if white < 2*time.Second || white > 3*time.Second {
+		t.Errorf("White has 60s + 1s, allocated %v", white)
+	}
+	black := params.limits(1).MoveTime
+	if black <= 0 || black > 500*time.Millisecond {
+		t.Errorf("Black has 1s left, allocated %v", black)
+	}
+
+	if limits := parseGoParams(strings.Fields("movetime 1500 depth 7")).limits(0); limits.MoveTime != 1500*time.Millisecond || limits.Depth != 7 {
+		t.Errorf("Unexpected limits: %+v", limits)
+	}
+
+	// A flag about to fall still gets a minimal budget
+	if limits := parseGoParams(strings.Fields("wtime 0 btime 60000")).limits(0); limits.MoveTime <= 0 || limits.MoveTime > 100*time.Millisecond {
+		t.Errorf("White has no time left, allocated %v", limits.MoveTime)
+	}
+	// Without any limit the search does not run until the maximum depth
+	if limits := parseGoParams(nil).limits(0); limits.MoveTime != defaultMoveTime {
+		t.Errorf("No limits given, allocated %v", limits.MoveTime)
+	}
+	if limits := parseGoParams(strings.Fields("infinite")).limits(0); limits.MoveTime != 0 {
+		t.Errorf("Infinite search allocated %v", limits.MoveTime)
+	}
+}